@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	mongodriver "go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/ucpr/mongo-streamer/internal/config"
+	"github.com/ucpr/mongo-streamer/internal/deadletter"
+	"github.com/ucpr/mongo-streamer/internal/mongo"
+	"github.com/ucpr/mongo-streamer/internal/pubsub"
+)
+
+var errDeadLetterDisabled = errors.New("dead letter queue is disabled")
+
+// NewDeadLetter creates a dead letter queue selected by the configuration.
+// It returns nil when the dead letter queue is disabled.
+func NewDeadLetter(ctx context.Context, cfg *config.DeadLetter, pcfg *config.PubSub, mcfg *config.MongoDB, cli *mongo.Client) (deadletter.Sink, error) {
+	switch cfg.Type {
+	case config.DeadLetterTypeNone:
+		return nil, nil
+	case config.DeadLetterTypeFile:
+		return deadletter.NewFile(cfg.FilePath)
+	case config.DeadLetterTypePubSub:
+		pub, err := pubsub.NewPublisher(ctx, &config.PubSub{
+			ProjectID: pcfg.ProjectID,
+			TopicID:   cfg.PubSubTopicID,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return deadletter.NewPubSub(pub), nil
+	case config.DeadLetterTypeMongoDB:
+		return deadletter.NewMongo(deadLetterCollection(cfg, mcfg, cli)), nil
+	default:
+		return nil, fmt.Errorf("unsupported dead letter type: %s", cfg.Type)
+	}
+}
+
+// NewDeadLetterSource creates a source to replay the dead letter queue selected by the configuration.
+func NewDeadLetterSource(ctx context.Context, cfg *config.DeadLetter, pcfg *config.PubSub, mcfg *config.MongoDB, cli *mongo.Client) (deadletter.Source, error) {
+	switch cfg.Type {
+	case config.DeadLetterTypeNone:
+		return nil, errDeadLetterDisabled
+	case config.DeadLetterTypeFile:
+		return deadletter.NewFile(cfg.FilePath)
+	case config.DeadLetterTypePubSub:
+		return deadletter.NewPubSubSource(ctx, pcfg.ProjectID, cfg.PubSubSubscriptionID, cfg.ReplayIdleTimeout)
+	case config.DeadLetterTypeMongoDB:
+		return deadletter.NewMongo(deadLetterCollection(cfg, mcfg, cli)), nil
+	default:
+		return nil, fmt.Errorf("unsupported dead letter type: %s", cfg.Type)
+	}
+}
+
+func deadLetterCollection(cfg *config.DeadLetter, mcfg *config.MongoDB, cli *mongo.Client) *mongodriver.Collection {
+	return cli.Database(metadataDatabase(cfg.MongoDBDatabase, mcfg)).Collection(cfg.MongoDBCollection)
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// The environment variables set by the command line flags.
+const (
+	envStartAtOperationTime = "MONGO_DB_START_AT_OPERATION_TIME"
+	envStartAfter           = "MONGO_DB_START_AFTER"
+	envStartOverride        = "MONGO_DB_START_OVERRIDE"
+)
+
+// parseFlags parses the command line flags of the streamer. The flags override the
+// environment variables, so that they are injected like the other settings.
+//
+// -start-at-operation-time and -start-after replay the change streams from the
+// position once: the saved resume tokens are discarded, and the streams resume from
+// the new tokens after a restart without the flags.
+func parseFlags(args []string, output io.Writer) error {
+	fs := flag.NewFlagSet("mongo-streamer", flag.ContinueOnError)
+	fs.SetOutput(output)
+	startAt := fs.String("start-at-operation-time", "",
+		`start the change streams at the cluster time "<seconds>.<increment>" or the RFC 3339 time, discarding the saved resume tokens`)
+	startAfter := fs.String("start-after", "",
+		"start the change streams after the resume token in Extended JSON or its _data, discarding the saved resume tokens")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unknown argument: %s", fs.Arg(0))
+	}
+	if *startAt != "" && *startAfter != "" {
+		return errors.New("-start-at-operation-time and -start-after are exclusive")
+	}
+
+	if *startAt == "" && *startAfter == "" {
+		return nil
+	}
+	for k, v := range map[string]string{
+		envStartAtOperationTime: *startAt,
+		envStartAfter:           *startAfter,
+		envStartOverride:        "true",
+	} {
+		if err := os.Setenv(k, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
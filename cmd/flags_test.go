@@ -0,0 +1,82 @@
+package main
+
+import (
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+//nolint:paralleltest
+func TestParseFlags(t *testing.T) {
+	patterns := []struct {
+		name    string
+		args    []string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name: "no flags",
+			args: []string{},
+			want: map[string]string{
+				envStartAtOperationTime: "1705555648",
+				envStartAfter:           "",
+				envStartOverride:        "",
+			},
+		},
+		{
+			name: "start at operation time",
+			args: []string{"-start-at-operation-time", "2024-01-18T05:27:28Z"},
+			want: map[string]string{
+				envStartAtOperationTime: "2024-01-18T05:27:28Z",
+				envStartAfter:           "",
+				envStartOverride:        "true",
+			},
+		},
+		{
+			name: "start after",
+			args: []string{"-start-after=82"},
+			want: map[string]string{
+				// the position of the environment variable is replaced
+				envStartAtOperationTime: "",
+				envStartAfter:           "82",
+				envStartOverride:        "true",
+			},
+		},
+		{
+			name:    "both",
+			args:    []string{"-start-at-operation-time", "1705555648", "-start-after", "82"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown flag",
+			args:    []string{"-unknown"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown argument",
+			args:    []string{"replay"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range patterns {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(envStartAtOperationTime, "1705555648")
+			t.Setenv(envStartAfter, "")
+			t.Setenv(envStartOverride, "")
+
+			err := parseFlags(tt.args, io.Discard)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			for k, v := range tt.want {
+				assert.Equal(t, v, os.Getenv(k), k)
+			}
+		})
+	}
+}
@@ -3,7 +3,9 @@ package main
 import (
 	"context"
 	"errors"
+	"flag"
 	"net/http"
+	"os"
 	"os/signal"
 	"syscall"
 	"time"
@@ -16,6 +18,9 @@ const (
 	gracefulShutdownTimeout = 5 * time.Second
 )
 
+// cmdReplayDeadLetter is the subcommand to replay the dead letter queue.
+const cmdReplayDeadLetter = "replay-dead-letter"
+
 func main() {
 	log.Info("Initialize mongo-streamer",
 		log.Fstring("version", stamp.BuildVersion),
@@ -25,14 +30,40 @@ func main() {
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT, syscall.SIGKILL)
 	defer stop()
 
-	streamer, err := injectStreamer(ctx)
+	if len(os.Args) > 1 && os.Args[1] == cmdReplayDeadLetter {
+		if err := replayDeadLetter(ctx); err != nil {
+			log.Error("Failed to replay dead letter queue", log.Ferror(err))
+			stop()
+			os.Exit(1)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == cmdResnapshot {
+		if err := resnapshot(ctx, os.Args[2:]); err != nil {
+			log.Error("Failed to re-snapshot", log.Ferror(err))
+			stop()
+			os.Exit(1)
+		}
+		return
+	}
+	if err := parseFlags(os.Args[1:], os.Stderr); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+		log.Error("Failed to parse flags", log.Ferror(err))
+		stop()
+		os.Exit(2)
+	}
+
+	streamers, err := injectStreamers(ctx)
 	if err != nil {
-		log.Panic("Failed to inject streamer", log.Ferror(err))
+		log.Panic("Failed to inject streamers", log.Ferror(err))
 	}
 	srv, err := injectServer(ctx)
 	if err != nil {
 		log.Panic("Failed to inject server", log.Ferror(err))
 	}
+	srv.HandleResnapshots(streamers.Resnapshots())
 
 	go func() {
 		if err := srv.Serve(); err != nil && !errors.Is(err, http.ErrServerClosed) {
@@ -40,19 +71,50 @@ func main() {
 		}
 	}()
 
+	streamErr := make(chan error, 1)
 	go func() {
-		streamer.Stream(ctx)
+		streamErr <- streamers.Stream(ctx)
 	}()
 
-	<-ctx.Done()
+	var failed bool
+	select {
+	case <-ctx.Done():
+	case err := <-streamErr:
+		if err != nil {
+			log.Error("Change streams stopped with error", log.Ferror(err))
+			failed = true
+		}
+		stop()
+	}
 	tctx, cancel := context.WithTimeout(context.Background(), gracefulShutdownTimeout)
 	defer cancel()
 	if err := srv.Shutdown(tctx); err != nil {
 		log.Error("Failed to shutdown http server", log.Ferror(err))
 	}
-	if err := streamer.Close(tctx); err != nil {
-		log.Error("Failed to close change stream", log.Ferror(err))
+	if err := streamers.Close(tctx); err != nil {
+		log.Error("Failed to close change streams", log.Ferror(err))
 	}
 
+	if failed {
+		os.Exit(1)
+	}
 	log.Info("Successfully graceful shutdown")
 }
+
+// replayDeadLetter publishes the dead-lettered change events to the sink.
+func replayDeadLetter(ctx context.Context) error {
+	replayer, err := injectReplayer(ctx)
+	if err != nil {
+		return err
+	}
+
+	n, rerr := replayer.Replay(ctx)
+	log.Info("Replayed dead letter queue", log.Fint("replayed", n))
+
+	tctx, cancel := context.WithTimeout(context.Background(), gracefulShutdownTimeout)
+	defer cancel()
+	if err := replayer.Close(tctx); err != nil {
+		log.Error("Failed to close replayer", log.Ferror(err))
+	}
+	return rerr
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"github.com/ucpr/mongo-streamer/internal/config"
+	"github.com/ucpr/mongo-streamer/internal/mongo"
+)
+
+// defaultMetadataDatabase is the database of the checkpoint and dead letter
+// collections when the whole deployment is watched.
+const defaultMetadataDatabase = "mongo_streamer"
+
+// NewNamespaceFilter creates a filter of the namespaces to stream. The checkpoint
+// and dead letter collections are always excluded so that writing them does not
+// produce change events in a database or deployment level change stream.
+func NewNamespaceFilter(mcfg *config.MongoDB, scfg *config.Storage, dcfg *config.DeadLetter) (*mongo.NamespaceFilter, error) {
+	exclude := append([]string{}, mcfg.ExcludeNamespaces...)
+	for _, ns := range metadataNamespaces(mcfg, scfg, dcfg) {
+		exclude = append(exclude, ns.String())
+	}
+	return mongo.NewNamespaceFilter(mcfg.IncludeNamespaces, exclude)
+}
+
+// metadataNamespaces returns the checkpoint and dead letter collections stored in MongoDB.
+func metadataNamespaces(mcfg *config.MongoDB, scfg *config.Storage, dcfg *config.DeadLetter) []mongo.Namespace {
+	var namespaces []mongo.Namespace
+	if scfg.Type == config.StorageTypeMongoDB {
+		namespaces = append(namespaces, mongo.Namespace{
+			Database:   metadataDatabase(scfg.MongoDBDatabase, mcfg),
+			Collection: scfg.MongoDBCollection,
+		})
+	}
+	if dcfg.Type == config.DeadLetterTypeMongoDB {
+		namespaces = append(namespaces, mongo.Namespace{
+			Database:   metadataDatabase(dcfg.MongoDBDatabase, mcfg),
+			Collection: dcfg.MongoDBCollection,
+		})
+	}
+	return namespaces
+}
+
+// watchedNamespace returns the name of the watched collection, database, or
+// deployment, which identifies the stream by default.
+func watchedNamespace(mcfg *config.MongoDB) string {
+	switch {
+	case mcfg.Database == "":
+		return "cluster"
+	case mcfg.Collection == "":
+		return mcfg.Database
+	default:
+		return mcfg.Database + "." + mcfg.Collection
+	}
+}
+
+// metadataDatabase returns db, or the watched database if db is empty.
+func metadataDatabase(db string, mcfg *config.MongoDB) string {
+	if db != "" {
+		return db
+	}
+	if mcfg.Database != "" {
+		return mcfg.Database
+	}
+	return defaultMetadataDatabase
+}
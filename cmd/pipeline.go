@@ -0,0 +1,41 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	mongodriver "go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/ucpr/mongo-streamer/internal/config"
+	"github.com/ucpr/mongo-streamer/internal/mongo"
+)
+
+// NewAggregationPipeline parses the aggregation pipeline of the change stream
+// from the configuration value or the file. The events of the checkpoint and dead
+// letter collections are dropped on the server before the configured stages, as
+// each of them would otherwise produce another write in a database or deployment
+// level change stream.
+func NewAggregationPipeline(mcfg *config.MongoDB, scfg *config.Storage, dcfg *config.DeadLetter) (mongodriver.Pipeline, error) {
+	if mcfg.Pipeline != "" && mcfg.PipelineFile != "" {
+		return nil, errors.New("pipeline and pipeline file are exclusive")
+	}
+
+	s := mcfg.Pipeline
+	if mcfg.PipelineFile != "" {
+		b, err := os.ReadFile(mcfg.PipelineFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read pipeline file: %w", err)
+		}
+		s = string(b)
+	}
+	pipeline, err := mongo.ParsePipeline(s)
+	if err != nil {
+		return nil, err
+	}
+
+	if stage := mongo.ExcludeNamespacesStage(metadataNamespaces(mcfg, scfg, dcfg)); stage != nil {
+		pipeline = append(mongodriver.Pipeline{stage}, pipeline...)
+	}
+	return pipeline, nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ucpr/mongo-streamer/internal/config"
+	"github.com/ucpr/mongo-streamer/internal/mongo"
+)
+
+func TestNewAggregationPipeline(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "pipeline.json")
+	require.NoError(t, os.WriteFile(path, []byte(`[{"$match": {"operationType": "insert"}}]`), 0o600))
+
+	patterns := []struct {
+		name    string
+		cfg     *config.MongoDB
+		storage *config.Storage
+		wantLen int
+		wantErr bool
+	}{
+		{
+			name: "no pipeline",
+			cfg:  &config.MongoDB{},
+		},
+		{
+			name:    "inline pipeline",
+			cfg:     &config.MongoDB{Pipeline: `[{"$match": {"operationType": "insert"}}, {"$project": {"fullDocument.secret": 0}}]`},
+			wantLen: 2,
+		},
+		{
+			name:    "pipeline file",
+			cfg:     &config.MongoDB{PipelineFile: path},
+			wantLen: 1,
+		},
+		{
+			// the checkpoint collection is dropped before the configured stages
+			name:    "checkpoint collection",
+			cfg:     &config.MongoDB{Database: "db", PipelineFile: path},
+			storage: &config.Storage{Type: config.StorageTypeMongoDB, MongoDBCollection: "resume_tokens"},
+			wantLen: 2,
+		},
+		{
+			name:    "both inline and file",
+			cfg:     &config.MongoDB{Pipeline: "[]", PipelineFile: path},
+			wantErr: true,
+		},
+		{
+			name:    "missing file",
+			cfg:     &config.MongoDB{PipelineFile: filepath.Join(t.TempDir(), "missing.json")},
+			wantErr: true,
+		},
+		{
+			name:    "invalid stage",
+			cfg:     &config.MongoDB{Pipeline: `[{"$out": "col"}]`},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range patterns {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			storage := tt.storage
+			if storage == nil {
+				storage = &config.Storage{Type: config.StorageTypeFile}
+			}
+			got, err := NewAggregationPipeline(tt.cfg, storage, &config.DeadLetter{})
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Len(t, got, tt.wantLen)
+			if tt.storage != nil {
+				assert.Equal(t, mongo.ExcludeNamespacesStage([]mongo.Namespace{{Database: "db", Collection: "resume_tokens"}}), got[0])
+			}
+		})
+	}
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ucpr/mongo-streamer/internal/config"
+	"github.com/ucpr/mongo-streamer/internal/kafka"
+	"github.com/ucpr/mongo-streamer/internal/pubsub"
+)
+
+// NewPublisher creates a publisher for the sink selected by the configuration.
+func NewPublisher(ctx context.Context, scfg *config.Sink, pcfg *config.PubSub, kcfg *config.Kafka) (pubsub.Publisher, error) {
+	switch scfg.Type {
+	case config.SinkTypePubSub:
+		return pubsub.NewPublisher(ctx, pcfg)
+	case config.SinkTypeKafka:
+		return kafka.NewPublisher(ctx, kcfg)
+	default:
+		return nil, fmt.Errorf("unsupported sink type: %s", scfg.Type)
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/ucpr/mongo-streamer/internal/app"
+	"github.com/ucpr/mongo-streamer/internal/config"
+	"github.com/ucpr/mongo-streamer/internal/deadletter"
+	"github.com/ucpr/mongo-streamer/internal/model"
+	"github.com/ucpr/mongo-streamer/internal/mongo"
+	"github.com/ucpr/mongo-streamer/internal/pubsub"
+	"github.com/ucpr/mongo-streamer/pkg/log"
+)
+
+// Replayer replays dead-lettered change events through the event handler of their stream.
+type Replayer struct {
+	cli *mongo.Client
+	src deadletter.Source
+	// handlers are the event handlers by stream name
+	handlers map[string]*app.Handler
+	pubs     []pubsub.Publisher
+}
+
+func NewReplayer(ctx context.Context, cli *mongo.Client, src deadletter.Source, cfg *config.Streams, d *StreamDefaults, streams []config.Stream) (*Replayer, error) {
+	return newReplayer(ctx, cli, src, cfg, d, streams, func(ss streamSettings) (pubsub.Publisher, error) {
+		return NewPublisher(ctx, &ss.sink, &ss.pubSub, &ss.kafka)
+	})
+}
+
+// newReplayer creates a Replayer whose publishers are created by newPublisher.
+// The handlers are created as the handlers of the streamer, so that replayed
+// messages are encoded the same as live ones.
+func newReplayer(ctx context.Context, cli *mongo.Client, src deadletter.Source, cfg *config.Streams, d *StreamDefaults, streams []config.Stream, newPublisher func(ss streamSettings) (pubsub.Publisher, error)) (*Replayer, error) {
+	r := &Replayer{
+		cli:      cli,
+		src:      src,
+		handlers: make(map[string]*app.Handler, len(streams)),
+	}
+	closePubs := func() {
+		for _, pub := range r.pubs {
+			pub.Close()
+		}
+	}
+	for _, stream := range streams {
+		ss := d.settings(stream, cfg)
+		opts, err := NewHandlerOptions(ctx, d.SchemaRegistry, ss)
+		if err != nil {
+			closePubs()
+			return nil, fmt.Errorf("failed to create handler options of stream %q: %w", stream.Name, err)
+		}
+		pub, err := newPublisher(ss)
+		if err != nil {
+			closePubs()
+			return nil, fmt.Errorf("failed to create publisher of stream %q: %w", stream.Name, err)
+		}
+		r.pubs = append(r.pubs, pub)
+		h, err := app.NewHandler(pub, &ss.pubSub, opts...)
+		if err != nil {
+			closePubs()
+			return nil, fmt.Errorf("failed to create handler of stream %q: %w", stream.Name, err)
+		}
+		r.handlers[stream.Name] = h
+	}
+	return r, nil
+}
+
+// Replay publishes the dead-lettered events and removes the published ones from
+// the dead letter queue. It returns the number of replayed events.
+func (r *Replayer) Replay(ctx context.Context) (int, error) {
+	return r.src.Replay(ctx, func(ctx context.Context, entry deadletter.Entry) error {
+		h, err := r.handler(entry)
+		if err != nil {
+			return err
+		}
+
+		var event model.ChangeEvent
+		if err := bson.Unmarshal(entry.Event, &event); err != nil {
+			return fmt.Errorf("failed to decode change event: %w", err)
+		}
+		if err := h.EventHandler(ctx, event); err != nil {
+			return err
+		}
+		log.Info("Replayed dead-lettered change event", log.Fstring("stream", entry.Stream), log.Fstring("token", entry.Token))
+		return nil
+	})
+}
+
+// handler returns the event handler of the stream of the entry. Entries without
+// a stream are replayed to the only stream, if there is exactly one.
+func (r *Replayer) handler(entry deadletter.Entry) (*app.Handler, error) {
+	if entry.Stream == "" && len(r.handlers) == 1 {
+		for _, h := range r.handlers {
+			return h, nil
+		}
+	}
+	h, ok := r.handlers[entry.Stream]
+	if !ok {
+		return nil, fmt.Errorf("unknown stream %q", entry.Stream)
+	}
+	return h, nil
+}
+
+// Close closes the resources.
+func (r *Replayer) Close(ctx context.Context) error {
+	var errs []error
+	if err := r.src.Close(ctx); err != nil {
+		errs = append(errs, err)
+	}
+	for _, pub := range r.pubs {
+		if err := pub.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := r.cli.Disconnect(ctx); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.uber.org/mock/gomock"
+
+	"github.com/ucpr/mongo-streamer/internal/app"
+	"github.com/ucpr/mongo-streamer/internal/config"
+	"github.com/ucpr/mongo-streamer/internal/deadletter"
+	"github.com/ucpr/mongo-streamer/internal/pubsub"
+	"github.com/ucpr/mongo-streamer/internal/pubsub/mock"
+	"github.com/ucpr/mongo-streamer/internal/schemaregistry"
+)
+
+func TestReplayer_Replay_SchemaRegistry(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/compatibility/") {
+			_, _ = w.Write([]byte(`{"is_compatible":true}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"id":7}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	src, err := deadletter.NewFile(filepath.Join(t.TempDir(), "dead_letter.jsonl"))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		src.Close(ctx)
+	})
+	event, err := bson.Marshal(bson.D{
+		{Key: "_id", Value: bson.D{{Key: "_data", Value: "token"}}},
+		{Key: "operationType", Value: "insert"},
+		{Key: "ns", Value: bson.D{{Key: "db", Value: "db"}, {Key: "coll", Value: "col"}}},
+		{Key: "documentKey", Value: bson.D{{Key: "_id", Value: 1}}},
+		{Key: "fullDocument", Value: bson.D{{Key: "_id", Value: 1}}},
+	})
+	require.NoError(t, err)
+	require.NoError(t, src.Put(ctx, deadletter.Entry{Stream: "db.col", Event: event, Token: "token"}))
+
+	ctrl := gomock.NewController(t)
+	mp := mock.NewMockPublisher(ctrl)
+	mpr := mock.NewMockPublishResult(ctrl)
+	// the replayed message is framed with the schema id as the live messages are
+	mp.EXPECT().AsyncPublish(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, msg pubsub.Message) pubsub.PublishResult {
+		assert.Equal(t, schemaregistry.Frame(7, nil), msg.Data[:5])
+		assert.Equal(t, "7", msg.Attributes[app.SchemaIDAttribute])
+		return mpr
+	}).Times(1)
+	mpr.EXPECT().Get(gomock.Any()).Return("id", nil).Times(1)
+
+	d := testStreamDefaults()
+	d.PubSub.PublishFormat = config.PubSubPublishFormatAvro
+	d.SchemaRegistry = &config.SchemaRegistry{URL: srv.URL}
+	streams, err := NewStreamConfigs(&config.Streams{}, d)
+	require.NoError(t, err)
+	r, err := newReplayer(ctx, nil, src, &config.Streams{}, d, streams, func(streamSettings) (pubsub.Publisher, error) {
+		return mp, nil
+	})
+	require.NoError(t, err)
+
+	n, err := r.Replay(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	ihttp "github.com/ucpr/mongo-streamer/internal/http"
+	"github.com/ucpr/mongo-streamer/internal/mongo"
+	"github.com/ucpr/mongo-streamer/pkg/log"
+)
+
+// cmdResnapshot is the subcommand to re-send documents through a running streamer.
+const cmdResnapshot = "resnapshot"
+
+// resnapshotRequestTimeout is the timeout of a request to the admin endpoint.
+const resnapshotRequestTimeout = 30 * time.Second
+
+// resnapshotOptions are the options of the resnapshot subcommand.
+type resnapshotOptions struct {
+	addr     string
+	token    string
+	interval time.Duration
+	req      ihttp.ResnapshotRequest
+}
+
+// parseResnapshotFlags parses the command line flags of the resnapshot subcommand.
+func parseResnapshotFlags(args []string, output io.Writer) (resnapshotOptions, error) {
+	fs := flag.NewFlagSet(cmdResnapshot, flag.ContinueOnError)
+	fs.SetOutput(output)
+	var (
+		opts   resnapshotOptions
+		filter string
+	)
+	fs.StringVar(&opts.addr, "addr", "http://localhost:8080", "base URL of the admin endpoints of the running streamer")
+	fs.StringVar(&opts.token, "token", os.Getenv("ADMIN_TOKEN"), "bearer token of the admin endpoints, defaults to ADMIN_TOKEN")
+	fs.DurationVar(&opts.interval, "interval", time.Second, "interval to poll the progress")
+	fs.StringVar(&opts.req.Stream, "stream", "", "name of the stream to send the documents to (required)")
+	fs.StringVar(&opts.req.Database, "database", "", "database of the documents, defaults to the database of the stream")
+	fs.StringVar(&opts.req.Collection, "collection", "", "collection of the documents, defaults to the collection of the stream")
+	fs.StringVar(&filter, "filter", "", "query filter of the documents in Extended JSON, all documents are sent if it is empty")
+	if err := fs.Parse(args); err != nil {
+		return resnapshotOptions{}, err
+	}
+	if fs.NArg() > 0 {
+		return resnapshotOptions{}, fmt.Errorf("unknown argument: %s", fs.Arg(0))
+	}
+	if opts.req.Stream == "" {
+		return resnapshotOptions{}, errors.New("-stream is required")
+	}
+	if opts.interval <= 0 {
+		return resnapshotOptions{}, errors.New("-interval must be positive")
+	}
+	if filter != "" {
+		// the filter is validated by the streamer
+		opts.req.Filter = json.RawMessage(filter)
+	}
+	return opts, nil
+}
+
+// resnapshot starts a re-snapshot on the running streamer and reports its progress
+// until it finishes.
+func resnapshot(ctx context.Context, args []string) error {
+	opts, err := parseResnapshotFlags(args, os.Stderr)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	c := &resnapshotClient{
+		cli:   &http.Client{Timeout: resnapshotRequestTimeout},
+		addr:  strings.TrimSuffix(opts.addr, "/"),
+		token: opts.token,
+	}
+
+	status, err := c.start(ctx, opts.req)
+	if err != nil {
+		return err
+	}
+	log.Info("Started re-snapshot",
+		log.Fstring("id", status.ID),
+		log.Fstring("stream", status.Stream),
+		log.Fstring("namespace", status.Namespace),
+		log.Fint("total", int(status.Total)),
+	)
+
+	ticker := time.NewTicker(opts.interval)
+	defer ticker.Stop()
+	for status.State == mongo.ResnapshotRunning {
+		select {
+		case <-ctx.Done():
+			// the re-snapshot keeps running on the streamer
+			return ctx.Err()
+		case <-ticker.C:
+		}
+		if status, err = c.status(ctx, status.ID); err != nil {
+			return err
+		}
+		log.Info("Re-snapshot progress",
+			log.Fstring("id", status.ID),
+			log.Fstring("state", string(status.State)),
+			log.Fint("sent", int(status.Sent)),
+			log.Fint("total", int(status.Total)),
+		)
+	}
+	if status.State == mongo.ResnapshotFailed {
+		return fmt.Errorf("re-snapshot %s failed: %s", status.ID, status.Error)
+	}
+	log.Info("Finished re-snapshot", log.Fstring("id", status.ID), log.Fint("sent", int(status.Sent)))
+	return nil
+}
+
+// resnapshotClient is a client of the re-snapshot admin endpoints.
+type resnapshotClient struct {
+	cli   *http.Client
+	addr  string
+	token string
+}
+
+func (c *resnapshotClient) start(ctx context.Context, req ihttp.ResnapshotRequest) (mongo.ResnapshotStatus, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return mongo.ResnapshotStatus{}, err
+	}
+	return c.do(ctx, http.MethodPost, "/admin/resnapshots", bytes.NewReader(body), http.StatusAccepted)
+}
+
+func (c *resnapshotClient) status(ctx context.Context, id string) (mongo.ResnapshotStatus, error) {
+	return c.do(ctx, http.MethodGet, "/admin/resnapshots/"+id, nil, http.StatusOK)
+}
+
+func (c *resnapshotClient) do(ctx context.Context, method, path string, body io.Reader, want int) (mongo.ResnapshotStatus, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.addr+path, body)
+	if err != nil {
+		return mongo.ResnapshotStatus{}, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := c.cli.Do(req)
+	if err != nil {
+		return mongo.ResnapshotStatus{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != want {
+		var e struct {
+			Error string `json:"error"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&e); err != nil || e.Error == "" {
+			e.Error = http.StatusText(resp.StatusCode)
+		}
+		return mongo.ResnapshotStatus{}, fmt.Errorf("%s %s: %d %s", method, path, resp.StatusCode, e.Error)
+	}
+	var status mongo.ResnapshotStatus
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return mongo.ResnapshotStatus{}, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return status, nil
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ihttp "github.com/ucpr/mongo-streamer/internal/http"
+	"github.com/ucpr/mongo-streamer/internal/mongo"
+)
+
+func TestParseResnapshotFlags(t *testing.T) {
+	t.Parallel()
+
+	patterns := []struct {
+		name    string
+		args    []string
+		want    ihttp.ResnapshotRequest
+		wantErr bool
+	}{
+		{
+			name: "all flags",
+			args: []string{"-stream", "users", "-database", "app", "-collection", "users", "-filter", `{"status": "active"}`},
+			want: ihttp.ResnapshotRequest{
+				Stream:     "users",
+				Database:   "app",
+				Collection: "users",
+				Filter:     json.RawMessage(`{"status": "active"}`),
+			},
+		},
+		{
+			name: "stream only",
+			args: []string{"-stream=users"},
+			want: ihttp.ResnapshotRequest{Stream: "users"},
+		},
+		{
+			name:    "no stream",
+			args:    []string{"-collection", "users"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid interval",
+			args:    []string{"-stream", "users", "-interval", "0s"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown argument",
+			args:    []string{"-stream", "users", "users"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range patterns {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := parseResnapshotFlags(tt.args, io.Discard)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got.req)
+		})
+	}
+}
+
+func TestResnapshot(t *testing.T) {
+	t.Parallel()
+
+	patterns := []struct {
+		name       string
+		startCode  int
+		finalState mongo.ResnapshotState
+		wantErr    bool
+	}{
+		{
+			name:       "succeeded",
+			startCode:  http.StatusAccepted,
+			finalState: mongo.ResnapshotSucceeded,
+		},
+		{
+			name:       "failed",
+			startCode:  http.StatusAccepted,
+			finalState: mongo.ResnapshotFailed,
+			wantErr:    true,
+		},
+		{
+			name:      "conflict",
+			startCode: http.StatusConflict,
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range patterns {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var polls atomic.Int32
+			mux := http.NewServeMux()
+			mux.HandleFunc("POST /admin/resnapshots", func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+				var req ihttp.ResnapshotRequest
+				assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+				assert.Equal(t, "users", req.Stream)
+
+				w.WriteHeader(tt.startCode)
+				if tt.startCode != http.StatusAccepted {
+					json.NewEncoder(w).Encode(map[string]string{"error": "running"})
+					return
+				}
+				json.NewEncoder(w).Encode(mongo.ResnapshotStatus{ID: "1", State: mongo.ResnapshotRunning, Total: 2})
+			})
+			mux.HandleFunc("GET /admin/resnapshots/1", func(w http.ResponseWriter, r *http.Request) {
+				// the re-snapshot finishes at the second poll
+				status := mongo.ResnapshotStatus{ID: "1", State: mongo.ResnapshotRunning, Total: 2, Sent: 1}
+				if polls.Add(1) > 1 {
+					status.State = tt.finalState
+					status.Sent = 2
+				}
+				json.NewEncoder(w).Encode(status)
+			})
+			srv := httptest.NewServer(mux)
+			t.Cleanup(srv.Close)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			err := resnapshot(ctx, []string{"-addr", srv.URL, "-token", "secret", "-interval", "10ms", "-stream", "users"})
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, int32(2), polls.Load())
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+
+	"github.com/ucpr/mongo-streamer/internal/app"
+	"github.com/ucpr/mongo-streamer/internal/config"
+	"github.com/ucpr/mongo-streamer/internal/model"
+	"github.com/ucpr/mongo-streamer/internal/schemaregistry"
+	"github.com/ucpr/mongo-streamer/pkg/log"
+)
+
+// NewHandlerOptions registers the Avro schema with the schema registry when the stream
+// publishes Avro messages and a registry is configured, so that the messages are framed
+// with the schema id. It fails if the schema is incompatible with the registered one.
+func NewHandlerOptions(ctx context.Context, cfg *config.SchemaRegistry, ss streamSettings) ([]app.HandlerOption, error) {
+	if cfg.URL == "" || ss.pubSub.PublishFormat != config.PubSubPublishFormatAvro {
+		return nil, nil
+	}
+
+	subject := schemaSubject(cfg, ss)
+	id, err := schemaregistry.NewClient(cfg).Register(ctx, subject, model.AvroSchema())
+	if err != nil {
+		return nil, err
+	}
+	log.Info("Registered avro schema", log.Fstring("subject", subject), log.Fint("id", id))
+
+	return []app.HandlerOption{app.WithSchemaID(id)}, nil
+}
+
+// schemaSubject returns the subject of the schema, "<topic>-value" by default.
+func schemaSubject(cfg *config.SchemaRegistry, ss streamSettings) string {
+	if cfg.Subject != "" {
+		return cfg.Subject
+	}
+	topic := ss.pubSub.TopicID
+	if ss.sink.Type == config.SinkTypeKafka {
+		topic = ss.kafka.Topic
+	}
+	return topic + "-value"
+}
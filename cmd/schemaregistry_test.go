@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ucpr/mongo-streamer/internal/config"
+	"github.com/ucpr/mongo-streamer/internal/schemaregistry"
+)
+
+func TestNewHandlerOptions(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.Contains(r.URL.Path, "/incompatible-value/"):
+			_, _ = w.Write([]byte(`{"is_compatible":false,"messages":["incompatible"]}`))
+		case strings.HasPrefix(r.URL.Path, "/compatibility/"):
+			_, _ = w.Write([]byte(`{"is_compatible":true}`))
+		default:
+			_, _ = w.Write([]byte(`{"id":7}`))
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	patterns := []struct {
+		name   string
+		cfg    *config.SchemaRegistry
+		format string
+		topic  string
+		want   int
+		err    error
+	}{
+		{
+			name:   "registry is not configured",
+			cfg:    &config.SchemaRegistry{},
+			format: config.PubSubPublishFormatAvro,
+			topic:  "topic",
+			want:   0,
+		},
+		{
+			name:   "not avro format",
+			cfg:    &config.SchemaRegistry{URL: srv.URL},
+			format: config.PubSubPublishFormatJSON,
+			topic:  "topic",
+			want:   0,
+		},
+		{
+			name:   "register avro schema",
+			cfg:    &config.SchemaRegistry{URL: srv.URL},
+			format: config.PubSubPublishFormatAvro,
+			topic:  "topic",
+			want:   1,
+		},
+		{
+			name:   "incompatible schema",
+			cfg:    &config.SchemaRegistry{URL: srv.URL},
+			format: config.PubSubPublishFormatAvro,
+			topic:  "incompatible",
+			err:    schemaregistry.ErrIncompatibleSchema,
+		},
+	}
+
+	for _, tt := range patterns {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ss := testStreamDefaults().settings(config.Stream{Name: "stream", Topic: tt.topic, PublishFormat: tt.format}, &config.Streams{})
+			got, err := NewHandlerOptions(ctx, tt.cfg, ss)
+			assert.ErrorIs(t, err, tt.err)
+			assert.Len(t, got, tt.want)
+		})
+	}
+}
+
+func TestSchemaSubject(t *testing.T) {
+	t.Parallel()
+
+	patterns := []struct {
+		name string
+		cfg  *config.SchemaRegistry
+		sink string
+		want string
+	}{
+		{
+			name: "subject is set",
+			cfg:  &config.SchemaRegistry{Subject: "subject"},
+			sink: config.SinkTypePubSub,
+			want: "subject",
+		},
+		{
+			name: "pubsub topic",
+			cfg:  &config.SchemaRegistry{},
+			sink: config.SinkTypePubSub,
+			want: "pubsub-topic-value",
+		},
+		{
+			name: "kafka topic",
+			cfg:  &config.SchemaRegistry{},
+			sink: config.SinkTypeKafka,
+			want: "kafka-topic-value",
+		},
+	}
+
+	for _, tt := range patterns {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ss := streamSettings{
+				sink:   config.Sink{Type: tt.sink},
+				pubSub: config.PubSub{TopicID: "pubsub-topic"},
+				kafka:  config.Kafka{Topic: "kafka-topic"},
+			}
+			assert.Equal(t, tt.want, schemaSubject(tt.cfg, ss))
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/ucpr/mongo-streamer/internal/config"
+	"github.com/ucpr/mongo-streamer/internal/mongo"
+	"github.com/ucpr/mongo-streamer/internal/persistent"
+)
+
+// NewStorage creates a resume token storage selected by the configuration.
+func NewStorage(ctx context.Context, cfg *config.Storage, mcfg *config.MongoDB, cli *mongo.Client) (persistent.Storage, error) {
+	streamID := cfg.StreamID
+	if streamID == "" {
+		streamID = watchedNamespace(mcfg)
+	}
+
+	switch cfg.Type {
+	case config.StorageTypeLog:
+		return persistent.NewLogWriter(), nil
+	case config.StorageTypeFile:
+		return persistent.NewFileWriter(cfg.FilePath)
+	case config.StorageTypeMongoDB:
+		col := cli.Database(metadataDatabase(cfg.MongoDBDatabase, mcfg)).Collection(cfg.MongoDBCollection)
+		return persistent.NewMongoWriter(col, streamID)
+	case config.StorageTypeRedis:
+		rcli := redis.NewClient(&redis.Options{
+			Addr:     cfg.RedisAddr,
+			Password: cfg.RedisPassword,
+			DB:       cfg.RedisDB,
+		})
+		if err := rcli.Ping(ctx).Err(); err != nil {
+			rcli.Close()
+			return nil, fmt.Errorf("failed to ping Redis: %w", err)
+		}
+		return persistent.NewRedisWriter(rcli, cfg.RedisKeyPrefix+":"+streamID, cfg.RedisTTL), nil
+	default:
+		return nil, fmt.Errorf("unsupported storage type: %s", cfg.Type)
+	}
+}
+
+// NewSnapshotStorage creates the storage of the snapshot progress of the stream,
+// next to its resume token in the storage selected by the configuration.
+func NewSnapshotStorage(ctx context.Context, cfg *config.Storage, mcfg *config.MongoDB, cli *mongo.Client) (persistent.Storage, error) {
+	scfg := *cfg
+	if scfg.StreamID == "" {
+		scfg.StreamID = watchedNamespace(mcfg)
+	}
+	scfg.StreamID += ".snapshot"
+	scfg.FilePath += ".snapshot"
+	return NewStorage(ctx, &scfg, mcfg, cli)
+}
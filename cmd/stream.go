@@ -0,0 +1,122 @@
+package main
+
+import (
+	"github.com/ucpr/mongo-streamer/internal/config"
+)
+
+// StreamDefaults are the settings of the environment variables that streams inherit.
+type StreamDefaults struct {
+	MongoDB    *config.MongoDB
+	Sink       *config.Sink
+	PubSub     *config.PubSub
+	Kafka      *config.Kafka
+	Storage    *config.Storage
+	Retry      *config.Retry
+	Reconnect  *config.Reconnect
+	Delivery   *config.Delivery
+	DeadLetter *config.DeadLetter
+	// SchemaRegistry is shared by the streams, each stream registers the schema
+	// under the subject of its topic unless the subject is set.
+	SchemaRegistry *config.SchemaRegistry
+}
+
+// streamSettings are the settings of a stream merged with the defaults.
+type streamSettings struct {
+	mongoDB config.MongoDB
+	sink    config.Sink
+	pubSub  config.PubSub
+	kafka   config.Kafka
+	storage config.Storage
+}
+
+// NewStreamConfigs returns the streams declared in the streams config file,
+// or a single stream configured by the environment variables if no file is set.
+func NewStreamConfigs(cfg *config.Streams, d *StreamDefaults) ([]config.Stream, error) {
+	if cfg.ConfigFile != "" {
+		return config.LoadStreams(cfg.ConfigFile)
+	}
+
+	name := watchedNamespace(d.MongoDB)
+	key := d.Storage.StreamID
+	if key == "" {
+		key = name
+	}
+	return []config.Stream{
+		{
+			Name:              name,
+			Database:          d.MongoDB.Database,
+			Collection:        d.MongoDB.Collection,
+			IncludeNamespaces: d.MongoDB.IncludeNamespaces,
+			ExcludeNamespaces: d.MongoDB.ExcludeNamespaces,
+			Pipeline:          d.MongoDB.Pipeline,
+			PipelineFile:      d.MongoDB.PipelineFile,
+			CheckpointKey:     key,
+		},
+	}, nil
+}
+
+// settings merges the stream with the defaults. When the streams are declared in
+// a config file, the resume token file of each stream is suffixed with its checkpoint
+// id so that the streams do not share a file.
+func (d *StreamDefaults) settings(s config.Stream, cfg *config.Streams) streamSettings {
+	ss := streamSettings{
+		mongoDB: *d.MongoDB,
+		sink:    *d.Sink,
+		pubSub:  *d.PubSub,
+		kafka:   *d.Kafka,
+		storage: *d.Storage,
+	}
+
+	ss.mongoDB.Database = s.Database
+	ss.mongoDB.Collection = s.Collection
+	ss.mongoDB.IncludeNamespaces = s.IncludeNamespaces
+	ss.mongoDB.ExcludeNamespaces = s.ExcludeNamespaces
+	ss.mongoDB.Pipeline = s.Pipeline
+	ss.mongoDB.PipelineFile = s.PipelineFile
+	if s.FullDocument != "" {
+		ss.mongoDB.FullDocument = s.FullDocument
+	}
+	if s.FullDocumentBeforeChange != "" {
+		ss.mongoDB.FullDocumentBeforeChange = s.FullDocumentBeforeChange
+	}
+	if s.HistoryLostPolicy != "" {
+		ss.mongoDB.HistoryLostPolicy = s.HistoryLostPolicy
+	}
+	if s.SnapshotMode != "" {
+		ss.mongoDB.SnapshotMode = s.SnapshotMode
+	}
+	// the start positions are exclusive, so the stream replaces both unless the
+	// start position is overridden for all streams, e.g. by the command line flags
+	if !ss.mongoDB.StartOverride && (s.StartAtOperationTime != "" || s.StartAfter != "") {
+		ss.mongoDB.StartAtOperationTime = s.StartAtOperationTime
+		ss.mongoDB.StartAfter = s.StartAfter
+	}
+	if s.Sink != "" {
+		ss.sink.Type = s.Sink
+	}
+	if s.PublishFormat != "" {
+		ss.pubSub.PublishFormat = s.PublishFormat
+	}
+	if len(s.Attributes) > 0 {
+		attrs := make(map[string]string, len(d.PubSub.Attributes)+len(s.Attributes))
+		for k, v := range d.PubSub.Attributes {
+			attrs[k] = v
+		}
+		for k, v := range s.Attributes {
+			attrs[k] = v
+		}
+		ss.pubSub.Attributes = attrs
+	}
+	if s.CloudEvents != "" {
+		ss.pubSub.CloudEvents = s.CloudEvents
+	}
+	if s.Topic != "" {
+		ss.pubSub.TopicID = s.Topic
+		ss.kafka.Topic = s.Topic
+	}
+	ss.storage.StreamID = s.CheckpointID()
+	if cfg.ConfigFile != "" {
+		ss.storage.FilePath += "." + s.CheckpointID()
+	}
+	return ss
+}
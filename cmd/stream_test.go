@@ -0,0 +1,111 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ucpr/mongo-streamer/internal/config"
+)
+
+func testStreamDefaults() *StreamDefaults {
+	return &StreamDefaults{
+		MongoDB:  &config.MongoDB{URI: "mongodb://localhost:27017", Database: "db", Collection: "col", FullDocument: "updateLookup", HistoryLostPolicy: config.HistoryLostPolicyFail, StartAtOperationTime: "1705555648"},
+		Sink:     &config.Sink{Type: config.SinkTypePubSub},
+		PubSub:   &config.PubSub{ProjectID: "project", TopicID: "topic", PublishFormat: config.PubSubPublishFormatJSON},
+		Kafka:    &config.Kafka{Topic: "topic"},
+		Storage:  &config.Storage{Type: config.StorageTypeFile, FilePath: "data/resume_token"},
+		Retry:    &config.Retry{},
+		Delivery: &config.Delivery{},
+	}
+}
+
+func TestNewStreamConfigs(t *testing.T) {
+	t.Parallel()
+
+	got, err := NewStreamConfigs(&config.Streams{}, testStreamDefaults())
+	assert.NoError(t, err)
+	assert.Equal(t, []config.Stream{
+		{
+			Name:          "db.col",
+			Database:      "db",
+			Collection:    "col",
+			CheckpointKey: "db.col",
+		},
+	}, got)
+}
+
+func TestStreamDefaults_settings(t *testing.T) {
+	t.Parallel()
+
+	patterns := []struct {
+		name   string
+		cfg    *config.Streams
+		stream config.Stream
+		check  func(t *testing.T, ss streamSettings)
+	}{
+		{
+			name:   "inherit defaults",
+			cfg:    &config.Streams{},
+			stream: config.Stream{Name: "db.col", Database: "db", Collection: "col", CheckpointKey: "db.col"},
+			check: func(t *testing.T, ss streamSettings) {
+				t.Helper()
+				assert.Equal(t, config.SinkTypePubSub, ss.sink.Type)
+				assert.Equal(t, "topic", ss.pubSub.TopicID)
+				assert.Equal(t, config.PubSubPublishFormatJSON, ss.pubSub.PublishFormat)
+				assert.Equal(t, "db.col", ss.storage.StreamID)
+				assert.Equal(t, "data/resume_token", ss.storage.FilePath)
+				assert.Equal(t, "updateLookup", ss.mongoDB.FullDocument)
+				assert.Equal(t, config.HistoryLostPolicyFail, ss.mongoDB.HistoryLostPolicy)
+				assert.Equal(t, "1705555648", ss.mongoDB.StartAtOperationTime)
+			},
+		},
+		{
+			name: "override by stream",
+			cfg:  &config.Streams{ConfigFile: "streams.yaml"},
+			stream: config.Stream{
+				Name:              "users",
+				Database:          "app",
+				Collection:        "users",
+				Sink:              config.SinkTypeKafka,
+				Topic:             "users",
+				PublishFormat:     config.PubSubPublishFormatAvro,
+				CloudEvents:       config.CloudEventsModeBinary,
+				FullDocument:      "required",
+				HistoryLostPolicy: config.HistoryLostPolicyResnapshot,
+				StartAfter:        "82",
+				SnapshotMode:      config.SnapshotModeInitial,
+			},
+			check: func(t *testing.T, ss streamSettings) {
+				t.Helper()
+				assert.Equal(t, "app", ss.mongoDB.Database)
+				assert.Equal(t, "users", ss.mongoDB.Collection)
+				assert.Equal(t, config.SinkTypeKafka, ss.sink.Type)
+				assert.Equal(t, "users", ss.kafka.Topic)
+				assert.Equal(t, "required", ss.mongoDB.FullDocument)
+				assert.Equal(t, config.HistoryLostPolicyResnapshot, ss.mongoDB.HistoryLostPolicy)
+				// the start position of the stream replaces the default
+				assert.Equal(t, "82", ss.mongoDB.StartAfter)
+				assert.Empty(t, ss.mongoDB.StartAtOperationTime)
+				assert.Equal(t, config.SnapshotModeInitial, ss.mongoDB.SnapshotMode)
+				assert.Equal(t, config.PubSubPublishFormatAvro, ss.pubSub.PublishFormat)
+				assert.Equal(t, config.CloudEventsModeBinary, ss.pubSub.CloudEvents)
+				assert.Equal(t, "users", ss.storage.StreamID)
+				// each stream has its own resume token file
+				assert.Equal(t, "data/resume_token.users", ss.storage.FilePath)
+			},
+		},
+	}
+
+	for _, tt := range patterns {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			d := testStreamDefaults()
+			tt.check(t, d.settings(tt.stream, tt.cfg))
+			// the defaults are not modified
+			assert.Equal(t, testStreamDefaults(), d)
+		})
+	}
+}
@@ -2,63 +2,277 @@ package main
 
 import (
 	"context"
-	"time"
+	"errors"
+	"fmt"
+	"sync"
 
 	"github.com/ucpr/mongo-streamer/internal/app"
 	"github.com/ucpr/mongo-streamer/internal/config"
+	"github.com/ucpr/mongo-streamer/internal/deadletter"
 	"github.com/ucpr/mongo-streamer/internal/mongo"
 	"github.com/ucpr/mongo-streamer/internal/persistent"
+	"github.com/ucpr/mongo-streamer/internal/pubsub"
+	"github.com/ucpr/mongo-streamer/pkg/backoff"
 	"github.com/ucpr/mongo-streamer/pkg/log"
 )
 
-type Streamer struct {
+// Streamers runs the streams sharing a MongoDB client and a dead letter queue.
+type Streamers struct {
 	cli *mongo.Client
-	cs  *mongo.ChangeStream
-	st  persistent.StorageBuffer
+	// dl is nil when the dead letter queue is disabled
+	dl        deadletter.Sink
+	streamers []*Streamer
+	// resnapshots re-sends the documents of the streams on demand
+	resnapshots *mongo.Resnapshotter
+}
+
+func NewStreamers(ctx context.Context, cli *mongo.Client, cfg *config.Streams, d *StreamDefaults, streams []config.Stream, dl deadletter.Sink) (*Streamers, error) {
+	s := &Streamers{
+		cli:         cli,
+		dl:          dl,
+		resnapshots: mongo.NewResnapshotter(),
+	}
+	for _, stream := range streams {
+		st, err := NewStreamer(ctx, cli, stream, d.settings(stream, cfg), d, dl)
+		if err != nil {
+			// close the streams created so far as they will never run
+			for _, st := range s.streamers {
+				st.close(ctx)
+			}
+			return nil, fmt.Errorf("failed to create stream %q: %w", stream.Name, err)
+		}
+		s.streamers = append(s.streamers, st)
+		s.resnapshots.Register(st.cs)
+	}
+	return s, nil
+}
+
+// Resnapshots returns the Resnapshotter of the streams.
+func (s *Streamers) Resnapshots() *mongo.Resnapshotter {
+	return s.resnapshots
+}
+
+// Stream runs the streams until ctx is done or all streams stopped. A stream that
+// fails stops without affecting the other streams, and the errors of the failed
+// streams are returned.
+func (s *Streamers) Stream(ctx context.Context) error {
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	for _, st := range s.streamers {
+		st := st
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := st.Stream(ctx); err != nil {
+				log.Error("Stream stopped with error", log.Fstring("stream", st.name), log.Ferror(err))
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("stream %q: %w", st.name, err))
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
 }
 
-func NewStreamer(ctx context.Context, cli *mongo.Client, mcfg *config.MongoDB, h *app.Handler) (*Streamer, error) {
-	stLog := persistent.NewLogWriter()
-	st, err := persistent.NewBuffer(10, 5*time.Second, stLog)
+// Close stops the re-snapshots and closes the streams, then the shared resources.
+func (s *Streamers) Close(ctx context.Context) error {
+	var errs []error
+	// the re-snapshots publish through the publishers of the streams
+	if err := s.resnapshots.Close(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("re-snapshots: %w", err))
+	}
+	for _, st := range s.streamers {
+		if err := st.Close(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("stream %q: %w", st.name, err))
+		}
+	}
+	if s.dl != nil {
+		if err := s.dl.Close(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := s.cli.Disconnect(ctx); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// Streamer streams the change events of a stream to its sink.
+type Streamer struct {
+	name string
+	cs   *mongo.ChangeStream
+	st   persistent.StorageBuffer
+	// snapshots stores the snapshot progress, nil if the stream is not snapshotted
+	snapshots persistent.StorageBuffer
+	pub       pubsub.Publisher
+	// done is closed when the change stream loop exits
+	done chan struct{}
+}
+
+func NewStreamer(ctx context.Context, cli *mongo.Client, stream config.Stream, ss streamSettings, d *StreamDefaults, dl deadletter.Sink) (*Streamer, error) {
+	pipeline, err := NewAggregationPipeline(&ss.mongoDB, &ss.storage, d.DeadLetter)
 	if err != nil {
 		return nil, err
 	}
+	fullDocument, err := mongo.ParseFullDocument(ss.mongoDB.FullDocument)
+	if err != nil {
+		return nil, err
+	}
+	beforeChange, err := mongo.ParseFullDocumentBeforeChange(ss.mongoDB.FullDocumentBeforeChange)
+	if err != nil {
+		return nil, err
+	}
+	historyLost, err := mongo.ParseHistoryLostPolicy(ss.mongoDB.HistoryLostPolicy)
+	if err != nil {
+		return nil, err
+	}
+	start, err := mongo.ParseStartPosition(ss.mongoDB.StartAtOperationTime, ss.mongoDB.StartAfter, ss.mongoDB.StartOverride)
+	if err != nil {
+		return nil, err
+	}
+	snapshot, err := mongo.ParseSnapshotMode(ss.mongoDB.SnapshotMode)
+	if err != nil {
+		return nil, err
+	}
+	storage, err := NewStorage(ctx, &ss.storage, &ss.mongoDB, cli)
+	if err != nil {
+		return nil, err
+	}
+	st, err := persistent.NewBuffer(ss.storage.BufferSize, ss.storage.FlushInterval, storage)
+	if err != nil {
+		return nil, err
+	}
+	// the snapshot progress is checkpointed every chunk of documents
+	var snapshots persistent.StorageBuffer
+	if snapshot == mongo.SnapshotInitial || historyLost == mongo.HistoryLostResnapshot {
+		storage, err := NewSnapshotStorage(ctx, &ss.storage, &ss.mongoDB, cli)
+		if err != nil {
+			st.Close(ctx)
+			return nil, err
+		}
+		if snapshots, err = persistent.NewBuffer(ss.mongoDB.SnapshotChunkSize, ss.storage.FlushInterval, storage); err != nil {
+			st.Close(ctx)
+			return nil, err
+		}
+	}
+	closeStorages := func() {
+		st.Close(ctx)
+		if snapshots != nil {
+			snapshots.Close(ctx)
+		}
+	}
+	nf, err := NewNamespaceFilter(&ss.mongoDB, &ss.storage, d.DeadLetter)
+	if err != nil {
+		closeStorages()
+		return nil, err
+	}
+	opts, err := NewHandlerOptions(ctx, d.SchemaRegistry, ss)
+	if err != nil {
+		closeStorages()
+		return nil, err
+	}
+	pub, err := NewPublisher(ctx, &ss.sink, &ss.pubSub, &ss.kafka)
+	if err != nil {
+		closeStorages()
+		return nil, err
+	}
+	h, err := app.NewHandler(pub, &ss.pubSub, opts...)
+	if err != nil {
+		pub.Close()
+		closeStorages()
+		return nil, err
+	}
+
 	cs, err := mongo.NewChangeStream(ctx, mongo.ChangeStreamParams{
-		Client:     cli,
-		Handler:    h.EventHandler,
-		Storage:    st,
-		Database:   mcfg.Database,
-		Collection: mcfg.Collection,
+		Name:        stream.Name,
+		Client:      cli,
+		Handler:     h.AsyncEventHandler,
+		OrderingKey: h.OrderingKey,
+		Storage:     st,
+		Retry: mongo.RetryPolicy{
+			MaxAttempts: d.Retry.MaxAttempts,
+			Backoff: backoff.Backoff{
+				Initial:    d.Retry.InitialInterval,
+				Max:        d.Retry.MaxInterval,
+				Multiplier: d.Retry.Multiplier,
+			},
+		},
+		Reconnect: mongo.ReconnectPolicy{
+			MaxAttempts: d.Reconnect.MaxAttempts,
+			Backoff: backoff.Backoff{
+				Initial:    d.Reconnect.InitialInterval,
+				Max:        d.Reconnect.MaxInterval,
+				Multiplier: d.Reconnect.Multiplier,
+				Jitter:     d.Reconnect.Jitter,
+			},
+		},
+		HistoryLost:              historyLost,
+		Start:                    start,
+		Snapshot:                 snapshot,
+		SnapshotStorage:          snapshots,
+		MaxInFlight:              d.Delivery.MaxInFlight,
+		DeadLetter:               dl,
+		Namespaces:               nf,
+		Pipeline:                 pipeline,
+		FullDocument:             fullDocument,
+		FullDocumentBeforeChange: beforeChange,
+		Database:                 ss.mongoDB.Database,
+		Collection:               ss.mongoDB.Collection,
 	})
 	if err != nil {
+		pub.Close()
+		closeStorages()
 		return nil, err
 	}
 
 	return &Streamer{
-		cli: cli,
-		cs:  cs,
-		st:  st,
+		name:      stream.Name,
+		cs:        cs,
+		st:        st,
+		snapshots: snapshots,
+		pub:       pub,
+		done:      make(chan struct{}),
 	}, nil
 }
 
-func (s *Streamer) Stream(ctx context.Context) {
+// Stream watches the change stream until ctx is done or the stream fails.
+func (s *Streamer) Stream(ctx context.Context) error {
+	defer close(s.done)
 	go func() {
 		s.st.Watch(ctx)
 	}()
 
-	log.Info("Start change stream watcher")
-	s.cs.Run(ctx)
+	log.Info("Start change stream watcher", log.Fstring("stream", s.name))
+	return s.cs.Run(ctx)
 }
 
+// Close waits for the change stream loop to exit, then flushes the last
+// acknowledged resume token and closes the resources.
 func (s *Streamer) Close(ctx context.Context) error {
+	select {
+	case <-s.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return s.close(ctx)
+}
+
+// close closes the resources of the stream.
+func (s *Streamer) close(ctx context.Context) error {
 	if err := s.cs.Close(ctx); err != nil {
 		return err
 	}
 	if err := s.st.Close(ctx); err != nil {
 		return err
 	}
-	if err := s.cli.Disconnect(ctx); err != nil {
-		return err
+	if s.snapshots != nil {
+		if err := s.snapshots.Close(ctx); err != nil {
+			return err
+		}
 	}
-	return nil
+	return s.pub.Close()
 }
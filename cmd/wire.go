@@ -7,20 +7,31 @@ import (
 
 	"github.com/google/wire"
 
-	"github.com/ucpr/mongo-streamer/internal/app"
 	"github.com/ucpr/mongo-streamer/internal/config"
 	"github.com/ucpr/mongo-streamer/internal/http"
 	"github.com/ucpr/mongo-streamer/internal/mongo"
-	"github.com/ucpr/mongo-streamer/internal/pubsub"
 )
 
-func injectStreamer(ctx context.Context) (*Streamer, error) {
+func injectStreamers(ctx context.Context) (*Streamers, error) {
 	wire.Build(
 		config.Set,
 		mongo.Set,
-		pubsub.Set,
-		app.NewHandler,
-		NewStreamer,
+		wire.Struct(new(StreamDefaults), "*"),
+		NewStreamConfigs,
+		NewDeadLetter,
+		NewStreamers,
+	)
+	return nil, nil
+}
+
+func injectReplayer(ctx context.Context) (*Replayer, error) {
+	wire.Build(
+		config.Set,
+		mongo.Set,
+		wire.Struct(new(StreamDefaults), "*"),
+		NewStreamConfigs,
+		NewDeadLetterSource,
+		NewReplayer,
 	)
 	return nil, nil
 }
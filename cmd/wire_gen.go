@@ -8,16 +8,14 @@ package main
 
 import (
 	"context"
-	"github.com/ucpr/mongo-streamer/internal/app"
 	"github.com/ucpr/mongo-streamer/internal/config"
 	"github.com/ucpr/mongo-streamer/internal/http"
 	"github.com/ucpr/mongo-streamer/internal/mongo"
-	"github.com/ucpr/mongo-streamer/internal/pubsub"
 )
 
 // Injectors from wire.go:
 
-func injectStreamer(ctx context.Context) (*Streamer, error) {
+func injectStreamers(ctx context.Context) (*Streamers, error) {
 	mongoDB, err := config.NewMongoDB(ctx)
 	if err != nil {
 		return nil, err
@@ -26,20 +24,147 @@ func injectStreamer(ctx context.Context) (*Streamer, error) {
 	if err != nil {
 		return nil, err
 	}
+	streams, err := config.NewStreams(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sink, err := config.NewSink(ctx)
+	if err != nil {
+		return nil, err
+	}
 	pubSub, err := config.NewPubSub(ctx)
 	if err != nil {
 		return nil, err
 	}
-	pubSubPublisher, err := pubsub.NewPublisher(ctx, pubSub)
+	kafka, err := config.NewKafka(ctx, sink, streams)
+	if err != nil {
+		return nil, err
+	}
+	storage, err := config.NewStorage(ctx)
+	if err != nil {
+		return nil, err
+	}
+	retry, err := config.NewRetry(ctx)
+	if err != nil {
+		return nil, err
+	}
+	reconnect, err := config.NewReconnect(ctx)
 	if err != nil {
 		return nil, err
 	}
-	handler := app.NewHandler(pubSubPublisher, pubSub)
-	streamer, err := NewStreamer(ctx, client, mongoDB, handler)
+	delivery, err := config.NewDelivery(ctx)
 	if err != nil {
 		return nil, err
 	}
-	return streamer, nil
+	deadLetter, err := config.NewDeadLetter(ctx)
+	if err != nil {
+		return nil, err
+	}
+	schemaRegistry, err := config.NewSchemaRegistry(ctx)
+	if err != nil {
+		return nil, err
+	}
+	streamDefaults := &StreamDefaults{
+		MongoDB:        mongoDB,
+		Sink:           sink,
+		PubSub:         pubSub,
+		Kafka:          kafka,
+		Storage:        storage,
+		Retry:          retry,
+		Reconnect:      reconnect,
+		Delivery:       delivery,
+		DeadLetter:     deadLetter,
+		SchemaRegistry: schemaRegistry,
+	}
+	v, err := NewStreamConfigs(streams, streamDefaults)
+	if err != nil {
+		return nil, err
+	}
+	deadletterSink, err := NewDeadLetter(ctx, deadLetter, pubSub, mongoDB, client)
+	if err != nil {
+		return nil, err
+	}
+	streamers, err := NewStreamers(ctx, client, streams, streamDefaults, v, deadletterSink)
+	if err != nil {
+		return nil, err
+	}
+	return streamers, nil
+}
+
+func injectReplayer(ctx context.Context) (*Replayer, error) {
+	mongoDB, err := config.NewMongoDB(ctx)
+	if err != nil {
+		return nil, err
+	}
+	client, err := mongo.NewClient(ctx, mongoDB)
+	if err != nil {
+		return nil, err
+	}
+	deadLetter, err := config.NewDeadLetter(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pubSub, err := config.NewPubSub(ctx)
+	if err != nil {
+		return nil, err
+	}
+	source, err := NewDeadLetterSource(ctx, deadLetter, pubSub, mongoDB, client)
+	if err != nil {
+		return nil, err
+	}
+	streams, err := config.NewStreams(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sink, err := config.NewSink(ctx)
+	if err != nil {
+		return nil, err
+	}
+	kafka, err := config.NewKafka(ctx, sink, streams)
+	if err != nil {
+		return nil, err
+	}
+	storage, err := config.NewStorage(ctx)
+	if err != nil {
+		return nil, err
+	}
+	retry, err := config.NewRetry(ctx)
+	if err != nil {
+		return nil, err
+	}
+	reconnect, err := config.NewReconnect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	delivery, err := config.NewDelivery(ctx)
+	if err != nil {
+		return nil, err
+	}
+	schemaRegistry, err := config.NewSchemaRegistry(ctx)
+	if err != nil {
+		return nil, err
+	}
+	streamDefaults := &StreamDefaults{
+		MongoDB:        mongoDB,
+		Sink:           sink,
+		PubSub:         pubSub,
+		Kafka:          kafka,
+		Storage:        storage,
+		Retry:          retry,
+		Reconnect:      reconnect,
+		Delivery:       delivery,
+		DeadLetter:     deadLetter,
+		SchemaRegistry: schemaRegistry,
+	}
+	v, err := NewStreamConfigs(streams, streamDefaults)
+	if err != nil {
+		return nil, err
+	}
+	replayer, err := NewReplayer(ctx, client, source, streams, streamDefaults, v)
+	if err != nil {
+		return nil, err
+	}
+	return replayer, nil
 }
 
 func injectServer(ctx context.Context) (*http.Server, error) {
@@ -47,6 +172,10 @@ func injectServer(ctx context.Context) (*http.Server, error) {
 	if err != nil {
 		return nil, err
 	}
-	server := http.NewServer(metrics)
+	admin, err := config.NewAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	server := http.NewServer(metrics, admin)
 	return server, nil
 }
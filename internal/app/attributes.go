@@ -0,0 +1,111 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/ucpr/mongo-streamer/internal/config"
+	"github.com/ucpr/mongo-streamer/internal/model"
+)
+
+var ErrInvalidAttribute = errors.New("handler: invalid attribute")
+
+// Attributes of the change event metadata, which can be used in the subscription filters.
+const (
+	OperationTypeAttribute = "operation_type"
+	DatabaseAttribute      = "database"
+	CollectionAttribute    = "collection"
+	DocumentKeyAttribute   = "document_key"
+	// ClusterTimeAttribute is the cluster time of the event as "<seconds>.<increment>".
+	ClusterTimeAttribute   = "cluster_time"
+	SchemaVersionAttribute = "schema_version"
+	PublishFormatAttribute = "publish_format"
+	// SchemaIDAttribute is set when the schema is registered with the schema registry.
+	SchemaIDAttribute = "schema_id"
+)
+
+const (
+	// reservedAttributePrefix is the prefix of the attribute keys reserved by Pub/Sub.
+	reservedAttributePrefix = "goog"
+	// maxAttributeKeySize and maxAttributeValueSize are the limits of Pub/Sub attributes.
+	maxAttributeKeySize   = 256
+	maxAttributeValueSize = 1024
+)
+
+// attributeBuilder builds the message attributes of change events.
+type attributeBuilder struct {
+	// static are the attributes shared by every event.
+	static map[string]string
+}
+
+// newAttributeBuilder returns the builder of the attributes of the publish config.
+func newAttributeBuilder(pcfg *config.PubSub, schemaID int) (*attributeBuilder, error) {
+	static := make(map[string]string, len(pcfg.Attributes)+3)
+	for k, v := range pcfg.Attributes {
+		if err := validateAttribute(k, v); err != nil {
+			return nil, err
+		}
+		static[k] = v
+	}
+	static[SchemaVersionAttribute] = model.SchemaVersion
+	static[PublishFormatAttribute] = pcfg.PublishFormat
+	if schemaID != 0 {
+		static[SchemaIDAttribute] = strconv.Itoa(schemaID)
+	}
+	return &attributeBuilder{static: static}, nil
+}
+
+// validateAttribute returns an error if Pub/Sub rejects the attribute.
+func validateAttribute(key, value string) error {
+	switch {
+	case key == "":
+		return fmt.Errorf("%w: empty key", ErrInvalidAttribute)
+	case strings.HasPrefix(key, reservedAttributePrefix):
+		return fmt.Errorf("%w %q: the prefix %q is reserved", ErrInvalidAttribute, key, reservedAttributePrefix)
+	case len(key) > maxAttributeKeySize:
+		return fmt.Errorf("%w %q: key exceeds %d bytes", ErrInvalidAttribute, key, maxAttributeKeySize)
+	case len(value) > maxAttributeValueSize:
+		return fmt.Errorf("%w %q: value exceeds %d bytes", ErrInvalidAttribute, key, maxAttributeValueSize)
+	}
+	return nil
+}
+
+// apply adds the attributes of the event to attrs, which may hold the attributes of
+// the CloudEvents envelope. The static attributes do not override the attributes of the
+// envelope or the metadata of the event, and the document key is omitted when it exceeds the size limit.
+func (b *attributeBuilder) apply(attrs map[string]string, event model.ChangeEvent) map[string]string {
+	if attrs == nil {
+		attrs = make(map[string]string, len(b.static)+5)
+	}
+	for k, v := range b.static {
+		if _, ok := attrs[k]; !ok {
+			attrs[k] = v
+		}
+	}
+
+	attrs[OperationTypeAttribute] = event.OperationType
+	if event.Namespace.DB != "" {
+		attrs[DatabaseAttribute] = event.Namespace.DB
+	}
+	if event.Namespace.Coll != "" {
+		attrs[CollectionAttribute] = event.Namespace.Coll
+	}
+	if len(event.DocumentKey) > 0 {
+		if key := event.DocumentKey.String(); len(key) <= maxAttributeValueSize {
+			attrs[DocumentKeyAttribute] = key
+		}
+	}
+	if ts := event.ClusterTime; ts.T != 0 || ts.I != 0 {
+		attrs[ClusterTimeAttribute] = formatClusterTime(ts)
+	}
+	return attrs
+}
+
+// formatClusterTime returns the cluster time as "<seconds>.<increment>".
+func formatClusterTime(ts primitive.Timestamp) string {
+	return strconv.FormatUint(uint64(ts.T), 10) + "." + strconv.FormatUint(uint64(ts.I), 10)
+}
@@ -0,0 +1,152 @@
+package app
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+
+	"github.com/ucpr/mongo-streamer/internal/config"
+	"github.com/ucpr/mongo-streamer/internal/model"
+)
+
+func TestAttributeBuilder(t *testing.T) {
+	t.Parallel()
+
+	event := model.ChangeEvent{
+		OperationType: "insert",
+		ClusterTime:   primitive.Timestamp{T: 1700000000, I: 3},
+		Namespace:     model.Namespace{DB: "shop", Coll: "orders"},
+		DocumentKey:   bson.Raw(bsoncore.NewDocumentBuilder().AppendInt32("_id", 1).Build()),
+	}
+
+	patterns := []struct {
+		name     string
+		cfg      *config.PubSub
+		schemaID int
+		attrs    map[string]string
+		event    model.ChangeEvent
+		want     map[string]string
+	}{
+		{
+			name:  "metadata",
+			cfg:   &config.PubSub{PublishFormat: config.PubSubPublishFormatJSON},
+			event: event,
+			want: map[string]string{
+				OperationTypeAttribute: "insert",
+				DatabaseAttribute:      "shop",
+				CollectionAttribute:    "orders",
+				DocumentKeyAttribute:   `{"_id": {"$numberInt":"1"}}`,
+				ClusterTimeAttribute:   "1700000000.3",
+				SchemaVersionAttribute: model.SchemaVersion,
+				PublishFormatAttribute: "json",
+			},
+		},
+		{
+			name: "static attributes",
+			cfg: &config.PubSub{
+				PublishFormat: config.PubSubPublishFormatAvro,
+				Attributes:    map[string]string{"team": "search", OperationTypeAttribute: "static"},
+			},
+			schemaID: 7,
+			event:    event,
+			want: map[string]string{
+				"team":                 "search",
+				OperationTypeAttribute: "insert",
+				DatabaseAttribute:      "shop",
+				CollectionAttribute:    "orders",
+				DocumentKeyAttribute:   `{"_id": {"$numberInt":"1"}}`,
+				ClusterTimeAttribute:   "1700000000.3",
+				SchemaVersionAttribute: model.SchemaVersion,
+				PublishFormatAttribute: "avro",
+				SchemaIDAttribute:      "7",
+			},
+		},
+		{
+			name: "cloudevents attributes",
+			cfg: &config.PubSub{
+				PublishFormat: config.PubSubPublishFormatJSON,
+				Attributes:    map[string]string{contentTypeAttribute: "text/plain"},
+			},
+			attrs: map[string]string{contentTypeAttribute: cloudEventsJSONContentType},
+			event: model.ChangeEvent{
+				OperationType: "dropDatabase",
+				Namespace:     model.Namespace{DB: "shop"},
+			},
+			want: map[string]string{
+				contentTypeAttribute:   cloudEventsJSONContentType,
+				OperationTypeAttribute: "dropDatabase",
+				DatabaseAttribute:      "shop",
+				SchemaVersionAttribute: model.SchemaVersion,
+				PublishFormatAttribute: "json",
+			},
+		},
+		{
+			name: "large document key",
+			cfg:  &config.PubSub{PublishFormat: config.PubSubPublishFormatJSON},
+			event: model.ChangeEvent{
+				OperationType: "delete",
+				Namespace:     model.Namespace{DB: "shop", Coll: "orders"},
+				DocumentKey:   bson.Raw(bsoncore.NewDocumentBuilder().AppendString("_id", strings.Repeat("a", 1024)).Build()),
+			},
+			want: map[string]string{
+				OperationTypeAttribute: "delete",
+				DatabaseAttribute:      "shop",
+				CollectionAttribute:    "orders",
+				SchemaVersionAttribute: model.SchemaVersion,
+				PublishFormatAttribute: "json",
+			},
+		},
+	}
+
+	for _, tt := range patterns {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			b, err := newAttributeBuilder(tt.cfg, tt.schemaID)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, b.apply(tt.attrs, tt.event))
+		})
+	}
+}
+
+func TestNewAttributeBuilder_Invalid(t *testing.T) {
+	t.Parallel()
+
+	patterns := []struct {
+		name  string
+		attrs map[string]string
+	}{
+		{
+			name:  "empty key",
+			attrs: map[string]string{"": "value"},
+		},
+		{
+			name:  "reserved prefix",
+			attrs: map[string]string{"googclient_id": "value"},
+		},
+		{
+			name:  "long key",
+			attrs: map[string]string{strings.Repeat("k", 257): "value"},
+		},
+		{
+			name:  "long value",
+			attrs: map[string]string{"key": strings.Repeat("v", 1025)},
+		},
+	}
+
+	for _, tt := range patterns {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := newAttributeBuilder(&config.PubSub{Attributes: tt.attrs}, 0)
+			assert.ErrorIs(t, err, ErrInvalidAttribute)
+		})
+	}
+}
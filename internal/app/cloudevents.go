@@ -0,0 +1,148 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/ucpr/mongo-streamer/internal/config"
+	"github.com/ucpr/mongo-streamer/internal/model"
+	"github.com/ucpr/mongo-streamer/internal/pubsub"
+)
+
+var ErrInvalidCloudEventsMode = errors.New("handler: invalid cloudevents mode")
+
+const (
+	// cloudEventsSpecVersion is the version of the CloudEvents specification.
+	cloudEventsSpecVersion = "1.0"
+	// cloudEventsTypePrefix is prepended to the operation type to build the event type.
+	cloudEventsTypePrefix = "com.mongodb.changestream."
+	// cloudEventsAttributePrefix is the prefix of the context attributes in binary mode,
+	// following the Google Cloud Pub/Sub protocol binding.
+	cloudEventsAttributePrefix = "ce-"
+	// contentTypeAttribute carries the content type of the data, following the
+	// Google Cloud Pub/Sub protocol binding.
+	contentTypeAttribute = "Content-Type"
+	// cloudEventsJSONContentType is the content type of a structured mode message.
+	cloudEventsJSONContentType = "application/cloudevents+json"
+)
+
+// cloudEvent is a CloudEvents 1.0 envelope in the JSON event format.
+type cloudEvent struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Time            string          `json:"time,omitempty"`
+	DataContentType string          `json:"datacontenttype,omitempty"`
+	Data            json.RawMessage `json:"data,omitempty"`
+	DataBase64      []byte          `json:"data_base64,omitempty"`
+}
+
+// newCloudEvent builds the envelope of the change event with the encoded data.
+func newCloudEvent(event model.ChangeEvent, data []byte, contentType string) cloudEvent {
+	ce := cloudEvent{
+		SpecVersion:     cloudEventsSpecVersion,
+		ID:              cloudEventID(event),
+		Source:          cloudEventSource(event),
+		Type:            cloudEventsTypePrefix + event.OperationType,
+		DataContentType: contentType,
+	}
+	if t := cloudEventTime(event); !t.IsZero() {
+		ce.Time = t.Format(time.RFC3339Nano)
+	}
+	if contentType == "application/json" {
+		ce.Data = data
+	} else {
+		ce.DataBase64 = data
+	}
+	return ce
+}
+
+// attributes returns the context attributes of the envelope as message attributes.
+func (c cloudEvent) attributes() map[string]string {
+	attrs := map[string]string{
+		cloudEventsAttributePrefix + "specversion": c.SpecVersion,
+		cloudEventsAttributePrefix + "id":          c.ID,
+		cloudEventsAttributePrefix + "source":      c.Source,
+		cloudEventsAttributePrefix + "type":        c.Type,
+		contentTypeAttribute:                       c.DataContentType,
+	}
+	if c.Time != "" {
+		attrs[cloudEventsAttributePrefix+"time"] = c.Time
+	}
+	return attrs
+}
+
+// validateCloudEventsMode returns ErrInvalidCloudEventsMode for an unsupported mode.
+func validateCloudEventsMode(mode string) error {
+	switch mode {
+	case config.CloudEventsModeNone, "", config.CloudEventsModeStructured, config.CloudEventsModeBinary:
+		return nil
+	default:
+		return ErrInvalidCloudEventsMode
+	}
+}
+
+// cloudEventMessage wraps the encoded change event in the CloudEvents mode.
+func cloudEventMessage(mode string, event model.ChangeEvent, data []byte, contentType string) (pubsub.Message, error) {
+	switch mode {
+	case config.CloudEventsModeNone, "":
+		return pubsub.Message{Data: data}, nil
+	case config.CloudEventsModeStructured:
+		ce := newCloudEvent(event, data, contentType)
+		b, err := json.Marshal(ce)
+		if err != nil {
+			return pubsub.Message{}, err
+		}
+		return pubsub.Message{
+			Data:       b,
+			Attributes: map[string]string{contentTypeAttribute: cloudEventsJSONContentType},
+		}, nil
+	case config.CloudEventsModeBinary:
+		ce := newCloudEvent(event, data, contentType)
+		return pubsub.Message{
+			Data:       data,
+			Attributes: ce.attributes(),
+		}, nil
+	default:
+		return pubsub.Message{}, ErrInvalidCloudEventsMode
+	}
+}
+
+// cloudEventID returns the resume token data, which is unique for each event. Snapshot
+// events without a resume token are identified by the document and the time of the snapshot.
+func cloudEventID(event model.ChangeEvent) string {
+	if len(event.ID) == 0 {
+		return cloudEventSource(event) + "/" + event.DocumentKey.String() + "@" + formatClusterTime(event.ClusterTime)
+	}
+	if data, ok := event.ID.Lookup("_data").StringValueOK(); ok {
+		return data
+	}
+	return event.ID.String()
+}
+
+// cloudEventSource returns the namespace of the event as a URI-reference,
+// e.g. /db/coll, /db or / for events of the deployment.
+func cloudEventSource(event model.ChangeEvent) string {
+	source := "/"
+	if event.Namespace.DB != "" {
+		source += event.Namespace.DB
+		if event.Namespace.Coll != "" {
+			source += "/" + event.Namespace.Coll
+		}
+	}
+	return source
+}
+
+// cloudEventTime returns the wall time of the event, or the cluster time on
+// servers that do not report the wall time.
+func cloudEventTime(event model.ChangeEvent) time.Time {
+	if event.WallTime != nil {
+		return event.WallTime.UTC()
+	}
+	if event.ClusterTime.T == 0 {
+		return time.Time{}
+	}
+	return time.Unix(int64(event.ClusterTime.T), 0).UTC()
+}
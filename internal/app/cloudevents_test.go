@@ -0,0 +1,152 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+
+	"github.com/ucpr/mongo-streamer/internal/config"
+	"github.com/ucpr/mongo-streamer/internal/model"
+	"github.com/ucpr/mongo-streamer/internal/pubsub"
+)
+
+func TestCloudEventMessage(t *testing.T) {
+	t.Parallel()
+
+	wallTime := time.Date(2024, time.January, 18, 5, 27, 28, 123000000, time.UTC)
+	event := model.ChangeEvent{
+		ID:            testResumeToken,
+		OperationType: "insert",
+		ClusterTime:   primitive.Timestamp{T: 1705555648, I: 1},
+		WallTime:      &wallTime,
+		Namespace:     model.Namespace{DB: "test", Coll: "tweets"},
+	}
+
+	patterns := []struct {
+		name        string
+		mode        string
+		event       model.ChangeEvent
+		data        []byte
+		contentType string
+		want        pubsub.Message
+		err         error
+	}{
+		{
+			name:        "none",
+			mode:        config.CloudEventsModeNone,
+			event:       event,
+			data:        []byte(`{"a":1}`),
+			contentType: "application/json",
+			want:        pubsub.Message{Data: []byte(`{"a":1}`)},
+		},
+		{
+			name:        "structured with json data",
+			mode:        config.CloudEventsModeStructured,
+			event:       event,
+			data:        []byte(`{"a":1}`),
+			contentType: "application/json",
+			want: pubsub.Message{
+				Data:       []byte(`{"specversion":"1.0","id":"id","source":"/test/tweets","type":"com.mongodb.changestream.insert","time":"2024-01-18T05:27:28.123Z","datacontenttype":"application/json","data":{"a":1}}`),
+				Attributes: map[string]string{"Content-Type": "application/cloudevents+json"},
+			},
+		},
+		{
+			name:        "structured with avro data",
+			mode:        config.CloudEventsModeStructured,
+			event:       event,
+			data:        []byte{0x01, 0x02},
+			contentType: "application/avro",
+			want: pubsub.Message{
+				Data:       []byte(`{"specversion":"1.0","id":"id","source":"/test/tweets","type":"com.mongodb.changestream.insert","time":"2024-01-18T05:27:28.123Z","datacontenttype":"application/avro","data_base64":"AQI="}`),
+				Attributes: map[string]string{"Content-Type": "application/cloudevents+json"},
+			},
+		},
+		{
+			name:        "binary",
+			mode:        config.CloudEventsModeBinary,
+			event:       event,
+			data:        []byte(`{"a":1}`),
+			contentType: "application/json",
+			want: pubsub.Message{
+				Data: []byte(`{"a":1}`),
+				Attributes: map[string]string{
+					"ce-specversion": "1.0",
+					"ce-id":          "id",
+					"ce-source":      "/test/tweets",
+					"ce-type":        "com.mongodb.changestream.insert",
+					"ce-time":        "2024-01-18T05:27:28.123Z",
+					"Content-Type":   "application/json",
+				},
+			},
+		},
+		{
+			name: "binary with cluster time of a database event",
+			mode: config.CloudEventsModeBinary,
+			event: model.ChangeEvent{
+				ID:            testResumeToken,
+				OperationType: "dropDatabase",
+				ClusterTime:   primitive.Timestamp{T: 1705555648, I: 1},
+				Namespace:     model.Namespace{DB: "test"},
+			},
+			data:        []byte(`{"a":1}`),
+			contentType: "application/json",
+			want: pubsub.Message{
+				Data: []byte(`{"a":1}`),
+				Attributes: map[string]string{
+					"ce-specversion": "1.0",
+					"ce-id":          "id",
+					"ce-source":      "/test",
+					"ce-type":        "com.mongodb.changestream.dropDatabase",
+					"ce-time":        "2024-01-18T05:27:28Z",
+					"Content-Type":   "application/json",
+				},
+			},
+		},
+		{
+			name: "binary snapshot event without resume token",
+			mode: config.CloudEventsModeBinary,
+			event: model.ChangeEvent{
+				OperationType: model.OperationTypeSnapshot,
+				ClusterTime:   primitive.Timestamp{T: 1705555648, I: 1},
+				Namespace:     model.Namespace{DB: "test", Coll: "tweets"},
+				DocumentKey:   bson.Raw(bsoncore.NewDocumentBuilder().AppendInt32("_id", 1).Build()),
+			},
+			data:        []byte(`{"a":1}`),
+			contentType: "application/json",
+			want: pubsub.Message{
+				Data: []byte(`{"a":1}`),
+				Attributes: map[string]string{
+					"ce-specversion": "1.0",
+					"ce-id":          `/test/tweets/{"_id": {"$numberInt":"1"}}@1705555648.1`,
+					"ce-source":      "/test/tweets",
+					"ce-type":        "com.mongodb.changestream.snapshot",
+					"ce-time":        "2024-01-18T05:27:28Z",
+					"Content-Type":   "application/json",
+				},
+			},
+		},
+		{
+			name:        "invalid mode",
+			mode:        "invalid_mode",
+			event:       event,
+			data:        []byte(`{"a":1}`),
+			contentType: "application/json",
+			err:         ErrInvalidCloudEventsMode,
+		},
+	}
+
+	for _, tt := range patterns {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := cloudEventMessage(tt.mode, tt.event, tt.data, tt.contentType)
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package app
+
+import (
+	"github.com/ucpr/mongo-streamer/internal/config"
+	"github.com/ucpr/mongo-streamer/internal/model"
+	"github.com/ucpr/mongo-streamer/internal/schemaregistry"
+)
+
+// NewEncoder returns the encoder of the publish format. Avro messages are framed
+// with the schema id when it is not 0.
+func NewEncoder(pcfg *config.PubSub, schemaID int) (model.Encoder, error) {
+	switch pcfg.PublishFormat {
+	case config.PubSubPublishFormatJSON:
+		return model.NewJSONEncoder(), nil
+	case config.PubSubPublishFormatAvro:
+		enc, err := model.NewAvroEncoder()
+		if err != nil {
+			return nil, err
+		}
+		if schemaID == 0 {
+			return enc, nil
+		}
+		return &schemaEncoder{Encoder: enc, schemaID: schemaID}, nil
+	case config.PubSubPublishFormatCanonicalExtJSON:
+		return model.NewExtJSONEncoder(true), nil
+	case config.PubSubPublishFormatRelaxedExtJSON:
+		return model.NewExtJSONEncoder(false), nil
+	case config.PubSubPublishFormatDebezium:
+		return model.NewDebeziumEncoder(pcfg.DebeziumServerName), nil
+	case config.PubSubPublishFormatProtobuf:
+		return model.NewProtobufEncoder(pcfg.ProtobufDocuments == config.ProtobufDocumentsStruct), nil
+	default:
+		return nil, ErrInvalidPublishFormat
+	}
+}
+
+// schemaEncoder frames the encoded events with the schema id in the Confluent wire format.
+type schemaEncoder struct {
+	model.Encoder
+	schemaID int
+}
+
+func (e *schemaEncoder) Encode(c model.ChangeEvent) ([]byte, error) {
+	b, err := e.Encoder.Encode(c)
+	if err != nil {
+		return nil, err
+	}
+	return schemaregistry.Frame(e.schemaID, b), nil
+}
@@ -0,0 +1,96 @@
+package app
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+
+	"github.com/ucpr/mongo-streamer/internal/config"
+	"github.com/ucpr/mongo-streamer/internal/model"
+)
+
+var (
+	ErrInvalidOrderingKey   = errors.New("handler: invalid ordering key")
+	ErrMissingOrderingField = errors.New("handler: ordering key field is required")
+)
+
+// maxOrderingKeySize is the maximum size of an ordering key of Pub/Sub,
+// longer keys are replaced with their hash.
+const maxOrderingKeySize = 1024
+
+// orderingKeyFunc returns the ordering key of the change event, empty if the event is not ordered.
+type orderingKeyFunc func(event model.ChangeEvent) string
+
+// newOrderingKeyFunc returns the function that derives ordering keys from change events.
+func newOrderingKeyFunc(pcfg *config.PubSub) (orderingKeyFunc, error) {
+	switch pcfg.OrderingKey {
+	case config.OrderingKeyNone, "":
+		return func(model.ChangeEvent) string { return "" }, nil
+	case config.OrderingKeyDocumentKey:
+		return documentOrderingKey, nil
+	case config.OrderingKeyNamespace:
+		return namespaceOrderingKey, nil
+	case config.OrderingKeyField:
+		if pcfg.OrderingKeyField == "" {
+			return nil, ErrMissingOrderingField
+		}
+		return fieldOrderingKey(strings.Split(pcfg.OrderingKeyField, ".")), nil
+	default:
+		return nil, ErrInvalidOrderingKey
+	}
+}
+
+// documentOrderingKey orders the events of the same document. The namespace is part of
+// the key so that documents with the same _id in other collections are not serialized.
+// Events without a document key, e.g. drop, are ordered with the namespace.
+func documentOrderingKey(event model.ChangeEvent) string {
+	ns := namespaceOrderingKey(event)
+	if len(event.DocumentKey) == 0 {
+		return ns
+	}
+	return limitOrderingKey(ns + ":" + event.DocumentKey.String())
+}
+
+// namespaceOrderingKey orders the events of the same collection, or database for
+// database events.
+func namespaceOrderingKey(event model.ChangeEvent) string {
+	if event.Namespace.Coll == "" {
+		return limitOrderingKey(event.Namespace.DB)
+	}
+	return limitOrderingKey(event.Namespace.DB + "." + event.Namespace.Coll)
+}
+
+// fieldOrderingKey orders the events with the same value of the field. The field is
+// looked up in the full document and then in the pre-image, events without the field
+// are not ordered.
+func fieldOrderingKey(path []string) orderingKeyFunc {
+	return func(event model.ChangeEvent) string {
+		for _, doc := range []bson.Raw{event.FullDocument, event.FullDocumentBeforeChange} {
+			if len(doc) == 0 {
+				continue
+			}
+			v, err := doc.LookupErr(path...)
+			if err != nil || v.Type == bsontype.Null {
+				continue
+			}
+			if s, ok := v.StringValueOK(); ok {
+				return limitOrderingKey(s)
+			}
+			return limitOrderingKey(v.String())
+		}
+		return ""
+	}
+}
+
+// limitOrderingKey replaces a key longer than the limit of Pub/Sub with its hash.
+func limitOrderingKey(key string) string {
+	if len(key) <= maxOrderingKeySize {
+		return key
+	}
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,130 @@
+package app
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+
+	"github.com/ucpr/mongo-streamer/internal/config"
+	"github.com/ucpr/mongo-streamer/internal/model"
+)
+
+func TestOrderingKeyFunc(t *testing.T) {
+	t.Parallel()
+
+	doc := func(b *bsoncore.DocumentBuilder) bson.Raw {
+		return bson.Raw(b.Build())
+	}
+	event := model.ChangeEvent{
+		OperationType: "update",
+		Namespace:     model.Namespace{DB: "shop", Coll: "orders"},
+		DocumentKey:   doc(bsoncore.NewDocumentBuilder().AppendInt32("_id", 1)),
+		FullDocument: doc(bsoncore.NewDocumentBuilder().
+			AppendInt32("_id", 1).
+			AppendDocument("customer", bsoncore.NewDocumentBuilder().AppendString("id", "c-1").Build()).
+			AppendInt64("store", 42)),
+	}
+	deleted := model.ChangeEvent{
+		OperationType:            "delete",
+		Namespace:                model.Namespace{DB: "shop", Coll: "orders"},
+		DocumentKey:              doc(bsoncore.NewDocumentBuilder().AppendInt32("_id", 1)),
+		FullDocumentBeforeChange: doc(bsoncore.NewDocumentBuilder().AppendDocument("customer", bsoncore.NewDocumentBuilder().AppendString("id", "c-1").Build())),
+	}
+	dropDatabase := model.ChangeEvent{
+		OperationType: "dropDatabase",
+		Namespace:     model.Namespace{DB: "shop"},
+	}
+
+	patterns := []struct {
+		name  string
+		cfg   *config.PubSub
+		event model.ChangeEvent
+		want  string
+		err   error
+	}{
+		{
+			name:  "none",
+			cfg:   &config.PubSub{OrderingKey: config.OrderingKeyNone},
+			event: event,
+			want:  "",
+		},
+		{
+			name:  "document key",
+			cfg:   &config.PubSub{OrderingKey: config.OrderingKeyDocumentKey},
+			event: event,
+			want:  `shop.orders:{"_id": {"$numberInt":"1"}}`,
+		},
+		{
+			name:  "document key of an event without document key",
+			cfg:   &config.PubSub{OrderingKey: config.OrderingKeyDocumentKey},
+			event: dropDatabase,
+			want:  "shop",
+		},
+		{
+			name:  "namespace",
+			cfg:   &config.PubSub{OrderingKey: config.OrderingKeyNamespace},
+			event: event,
+			want:  "shop.orders",
+		},
+		{
+			name:  "string field",
+			cfg:   &config.PubSub{OrderingKey: config.OrderingKeyField, OrderingKeyField: "customer.id"},
+			event: event,
+			want:  "c-1",
+		},
+		{
+			name:  "non-string field",
+			cfg:   &config.PubSub{OrderingKey: config.OrderingKeyField, OrderingKeyField: "store"},
+			event: event,
+			want:  `{"$numberLong":"42"}`,
+		},
+		{
+			name:  "field of the pre-image",
+			cfg:   &config.PubSub{OrderingKey: config.OrderingKeyField, OrderingKeyField: "customer.id"},
+			event: deleted,
+			want:  "c-1",
+		},
+		{
+			name:  "missing field",
+			cfg:   &config.PubSub{OrderingKey: config.OrderingKeyField, OrderingKeyField: "customer.name"},
+			event: event,
+			want:  "",
+		},
+		{
+			name: "field is not set",
+			cfg:  &config.PubSub{OrderingKey: config.OrderingKeyField},
+			err:  ErrMissingOrderingField,
+		},
+		{
+			name: "invalid ordering key",
+			cfg:  &config.PubSub{OrderingKey: "invalid"},
+			err:  ErrInvalidOrderingKey,
+		},
+	}
+
+	for _, tt := range patterns {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			f, err := newOrderingKeyFunc(tt.cfg)
+			assert.ErrorIs(t, err, tt.err)
+			if tt.err != nil {
+				return
+			}
+			assert.Equal(t, tt.want, f(tt.event))
+		})
+	}
+}
+
+func TestLimitOrderingKey(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "key", limitOrderingKey("key"))
+	long := strings.Repeat("k", maxOrderingKeySize+1)
+	assert.Len(t, limitOrderingKey(long), 64)
+	assert.Equal(t, limitOrderingKey(long), limitOrderingKey(long))
+}
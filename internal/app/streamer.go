@@ -7,6 +7,7 @@ import (
 	"github.com/ucpr/mongo-streamer/internal/config"
 	"github.com/ucpr/mongo-streamer/internal/model"
 	"github.com/ucpr/mongo-streamer/internal/pubsub"
+	"github.com/ucpr/mongo-streamer/pkg/backoff"
 	"github.com/ucpr/mongo-streamer/pkg/log"
 )
 
@@ -15,24 +16,75 @@ var ErrInvalidPublishFormat = errors.New("handler: invalid publish format")
 type Handler struct {
 	pubsub pubsub.Publisher
 	pcfg   *config.PubSub
+	// schemaID is the id of the Avro schema in the schema registry, 0 if it is not registered.
+	schemaID    int
+	encoder     model.Encoder
+	orderingKey orderingKeyFunc
+	attributes  *attributeBuilder
 }
 
-func NewHandler(ps pubsub.Publisher, pcfg *config.PubSub) *Handler {
-	return &Handler{
+// HandlerOption is an option of Handler.
+type HandlerOption func(h *Handler)
+
+// WithSchemaID frames Avro messages with the id of the schema in the schema registry.
+func WithSchemaID(id int) HandlerOption {
+	return func(h *Handler) {
+		h.schemaID = id
+	}
+}
+
+// NewHandler creates a new handler. It returns an error when the configuration is
+// invalid, e.g. for an invalid publish format, so that the stream does not start.
+func NewHandler(ps pubsub.Publisher, pcfg *config.PubSub, opts ...HandlerOption) (*Handler, error) {
+	h := &Handler{
 		pubsub: ps,
 		pcfg:   pcfg,
 	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	if err := validateCloudEventsMode(pcfg.CloudEvents); err != nil {
+		return nil, err
+	}
+	var err error
+	if h.encoder, err = NewEncoder(pcfg, h.schemaID); err != nil {
+		return nil, err
+	}
+	if h.orderingKey, err = newOrderingKeyFunc(pcfg); err != nil {
+		return nil, err
+	}
+	if h.attributes, err = newAttributeBuilder(pcfg, h.schemaID); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// skippedResult is the result of an event that is not published, which is
+// acknowledged immediately so that its checkpoint is committed.
+type skippedResult struct {
+	ready chan struct{}
+}
+
+func newSkippedResult() *skippedResult {
+	r := &skippedResult{ready: make(chan struct{})}
+	close(r.ready)
+	return r
 }
 
+func (r *skippedResult) Ready() <-chan struct{} {
+	return r.ready
+}
+
+func (r *skippedResult) Get(context.Context) (string, error) {
+	return "", nil
+}
+
+// EventHandler publishes the change event and waits until it is acknowledged.
 func (e *Handler) EventHandler(ctx context.Context, event model.ChangeEvent) error {
-	data, err := e.marshalEventData(event)
+	res, err := e.AsyncEventHandler(ctx, event)
 	if err != nil {
 		return err
 	}
-
-	res := e.pubsub.AsyncPublish(ctx, pubsub.Message{
-		Data: data,
-	})
 	id, err := res.Get(ctx)
 	if err != nil {
 		return err
@@ -41,13 +93,37 @@ func (e *Handler) EventHandler(ctx context.Context, event model.ChangeEvent) err
 	return nil
 }
 
-func (e *Handler) marshalEventData(event model.ChangeEvent) ([]byte, error) {
-	switch e.pcfg.PublishFormat {
-	case config.PubSubPublishFormatJSON:
-		return event.JSON()
-	case config.PubSubPublishFormatAvro:
-		return event.Avro()
-	default:
-		return nil, ErrInvalidPublishFormat
+// OrderingKey returns the ordering key of the change event, empty if the events are not ordered.
+func (e *Handler) OrderingKey(event model.ChangeEvent) string {
+	return e.orderingKey(event)
+}
+
+// AsyncEventHandler publishes the change event without waiting for the acknowledgement.
+// The returned result is ready when the event is acknowledged by the sink.
+func (e *Handler) AsyncEventHandler(ctx context.Context, event model.ChangeEvent) (pubsub.PublishResult, error) {
+	data, err := e.encoder.Encode(event)
+	if errors.Is(err, model.ErrDebeziumUnsupportedOperation) {
+		// the connector does not emit the event either, e.g. for drop or rename
+		log.Debug("Skip change event unsupported by the publish format",
+			log.Fstring("operation_type", event.OperationType),
+			log.Fstring("format", e.pcfg.PublishFormat),
+		)
+		return newSkippedResult(), nil
+	}
+	if err != nil {
+		// the event cannot be marshaled however many times it is retried
+		return nil, backoff.Permanent(err)
+	}
+
+	msg, err := cloudEventMessage(e.pcfg.CloudEvents, event, data, e.encoder.ContentType())
+	if err != nil {
+		return nil, backoff.Permanent(err)
+	}
+	if len(event.DocumentKey) > 0 {
+		msg.Key = event.DocumentKey.String()
 	}
+	msg.OrderingKey = e.OrderingKey(event)
+	msg.Attributes = e.attributes.apply(msg.Attributes, event)
+	res := e.pubsub.AsyncPublish(ctx, msg)
+	return res, nil
 }
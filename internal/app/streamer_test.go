@@ -5,13 +5,20 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
 	"go.uber.org/mock/gomock"
 
 	"github.com/ucpr/mongo-streamer/internal/config"
 	"github.com/ucpr/mongo-streamer/internal/model"
+	"github.com/ucpr/mongo-streamer/internal/pubsub"
 	"github.com/ucpr/mongo-streamer/internal/pubsub/mock"
 )
 
+//nolint:gochecknoglobals
+var testResumeToken = bson.Raw(bsoncore.NewDocumentBuilder().AppendString("_data", "id").Build())
+
 func TestHandler_EventHandler(t *testing.T) {
 	t.Parallel()
 	ctx := context.Background()
@@ -36,7 +43,7 @@ func TestHandler_EventHandler(t *testing.T) {
 				mc.publisher.EXPECT().AsyncPublish(ctx, gomock.Any()).Return(mc.publishResult).Times(1)
 			},
 			event: model.ChangeEvent{
-				ID: "id",
+				ID: testResumeToken,
 			},
 			publishFormat: config.PubSubPublishFormatJSON,
 			err:           nil,
@@ -50,21 +57,80 @@ func TestHandler_EventHandler(t *testing.T) {
 				mc.publisher.EXPECT().AsyncPublish(ctx, gomock.Any()).Return(mc.publishResult).Times(1)
 			},
 			event: model.ChangeEvent{
-				ID: "id",
+				ID: testResumeToken,
 			},
 			publishFormat: config.PubSubPublishFormatAvro,
 			err:           nil,
 		},
 		{
-			name: "invalid publish format",
+			name: "success handle event with canonical extended json format",
+			injector: func(t *testing.T, mc *mc) {
+				t.Helper()
+
+				mc.publishResult.EXPECT().Get(ctx).Return("id", nil).Times(1)
+				mc.publisher.EXPECT().AsyncPublish(ctx, gomock.Any()).Return(mc.publishResult).Times(1)
+			},
+			event: model.ChangeEvent{
+				ID: testResumeToken,
+			},
+			publishFormat: config.PubSubPublishFormatCanonicalExtJSON,
+			err:           nil,
+		},
+		{
+			name: "success handle event with relaxed extended json format",
+			injector: func(t *testing.T, mc *mc) {
+				t.Helper()
+
+				mc.publishResult.EXPECT().Get(ctx).Return("id", nil).Times(1)
+				mc.publisher.EXPECT().AsyncPublish(ctx, gomock.Any()).Return(mc.publishResult).Times(1)
+			},
+			event: model.ChangeEvent{
+				ID: testResumeToken,
+			},
+			publishFormat: config.PubSubPublishFormatRelaxedExtJSON,
+			err:           nil,
+		},
+		{
+			name: "success handle event with debezium format",
+			injector: func(t *testing.T, mc *mc) {
+				t.Helper()
+
+				mc.publishResult.EXPECT().Get(ctx).Return("id", nil).Times(1)
+				mc.publisher.EXPECT().AsyncPublish(ctx, gomock.Any()).Return(mc.publishResult).Times(1)
+			},
+			event: model.ChangeEvent{
+				ID:            testResumeToken,
+				OperationType: "insert",
+			},
+			publishFormat: config.PubSubPublishFormatDebezium,
+			err:           nil,
+		},
+		{
+			name: "success handle event with protobuf format",
 			injector: func(t *testing.T, mc *mc) {
 				t.Helper()
+
+				mc.publishResult.EXPECT().Get(ctx).Return("id", nil).Times(1)
+				mc.publisher.EXPECT().AsyncPublish(ctx, gomock.Any()).Return(mc.publishResult).Times(1)
 			},
 			event: model.ChangeEvent{
-				ID: "id",
+				ID: testResumeToken,
 			},
-			publishFormat: "invalid_format",
-			err:           ErrInvalidPublishFormat,
+			publishFormat: config.PubSubPublishFormatProtobuf,
+			err:           nil,
+		},
+		{
+			name: "skip unsupported operation of debezium format",
+			injector: func(t *testing.T, mc *mc) {
+				t.Helper()
+				// the event is acknowledged without publishing
+			},
+			event: model.ChangeEvent{
+				ID:            testResumeToken,
+				OperationType: "drop",
+			},
+			publishFormat: config.PubSubPublishFormatDebezium,
+			err:           nil,
 		},
 		{
 			name: "failed to publish event",
@@ -75,7 +141,7 @@ func TestHandler_EventHandler(t *testing.T) {
 				mc.publisher.EXPECT().AsyncPublish(ctx, gomock.Any()).Return(mc.publishResult).Times(1)
 			},
 			event: model.ChangeEvent{
-				ID: "id",
+				ID: testResumeToken,
 			},
 			publishFormat: config.PubSubPublishFormatJSON,
 			err:           assert.AnError,
@@ -95,11 +161,172 @@ func TestHandler_EventHandler(t *testing.T) {
 				publishResult: mpr,
 			})
 
-			h := NewHandler(mp, &config.PubSub{
+			h, err := NewHandler(mp, &config.PubSub{
 				PublishFormat: tt.publishFormat,
 			})
-			err := h.EventHandler(ctx, tt.event)
-			assert.Equal(t, tt.err, err)
+			require.NoError(t, err)
+			err = h.EventHandler(ctx, tt.event)
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+}
+
+func TestHandler_AsyncEventHandler(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	mp := mock.NewMockPublisher(ctrl)
+	mpr := mock.NewMockPublishResult(ctrl)
+	// the result is returned without waiting for the acknowledgement
+	// and the document key is used as the partitioning key.
+	mp.EXPECT().AsyncPublish(ctx, gomock.Any()).DoAndReturn(func(_ context.Context, msg pubsub.Message) pubsub.PublishResult {
+		assert.Equal(t, `{"_id": "id"}`, msg.Key)
+		return mpr
+	}).Times(1)
+
+	h, err := NewHandler(mp, &config.PubSub{
+		PublishFormat: config.PubSubPublishFormatJSON,
+	})
+	require.NoError(t, err)
+	got, err := h.AsyncEventHandler(ctx, model.ChangeEvent{
+		ID:          testResumeToken,
+		DocumentKey: bson.Raw(bsoncore.NewDocumentBuilder().AppendString("_id", "id").Build()),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, mpr, got)
+}
+
+func TestHandler_AsyncEventHandler_SchemaID(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	mp := mock.NewMockPublisher(ctrl)
+	mpr := mock.NewMockPublishResult(ctrl)
+	// avro messages are framed with the magic byte and the schema id
+	mp.EXPECT().AsyncPublish(ctx, gomock.Any()).DoAndReturn(func(_ context.Context, msg pubsub.Message) pubsub.PublishResult {
+		assert.Equal(t, []byte{0x00, 0x00, 0x00, 0x00, 0x07}, msg.Data[:5])
+		return mpr
+	}).Times(1)
+
+	h, err := NewHandler(mp, &config.PubSub{
+		PublishFormat: config.PubSubPublishFormatAvro,
+	}, WithSchemaID(7))
+	require.NoError(t, err)
+	_, err = h.AsyncEventHandler(ctx, model.ChangeEvent{ID: testResumeToken})
+	assert.NoError(t, err)
+}
+
+func TestHandler_AsyncEventHandler_OrderingKey(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	mp := mock.NewMockPublisher(ctrl)
+	mpr := mock.NewMockPublishResult(ctrl)
+	mp.EXPECT().AsyncPublish(ctx, gomock.Any()).DoAndReturn(func(_ context.Context, msg pubsub.Message) pubsub.PublishResult {
+		assert.Equal(t, "test.tweets", msg.OrderingKey)
+		return mpr
+	}).Times(1)
+
+	h, err := NewHandler(mp, &config.PubSub{
+		PublishFormat: config.PubSubPublishFormatJSON,
+		OrderingKey:   config.OrderingKeyNamespace,
+	})
+	require.NoError(t, err)
+	_, err = h.AsyncEventHandler(ctx, model.ChangeEvent{
+		ID:        testResumeToken,
+		Namespace: model.Namespace{DB: "test", Coll: "tweets"},
+	})
+	assert.NoError(t, err)
+}
+
+func TestHandler_AsyncEventHandler_Attributes(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	mp := mock.NewMockPublisher(ctrl)
+	mpr := mock.NewMockPublishResult(ctrl)
+	// the metadata is merged with the attributes of the CloudEvents envelope
+	mp.EXPECT().AsyncPublish(ctx, gomock.Any()).DoAndReturn(func(_ context.Context, msg pubsub.Message) pubsub.PublishResult {
+		assert.Equal(t, "insert", msg.Attributes[OperationTypeAttribute])
+		assert.Equal(t, "test", msg.Attributes[DatabaseAttribute])
+		assert.Equal(t, "tweets", msg.Attributes[CollectionAttribute])
+		assert.Equal(t, "search", msg.Attributes["team"])
+		assert.Equal(t, "com.mongodb.changestream.insert", msg.Attributes["ce-type"])
+		return mpr
+	}).Times(1)
+
+	h, err := NewHandler(mp, &config.PubSub{
+		PublishFormat: config.PubSubPublishFormatJSON,
+		CloudEvents:   config.CloudEventsModeBinary,
+		Attributes:    map[string]string{"team": "search"},
+	})
+	require.NoError(t, err)
+	_, err = h.AsyncEventHandler(ctx, model.ChangeEvent{
+		ID:            testResumeToken,
+		OperationType: "insert",
+		Namespace:     model.Namespace{DB: "test", Coll: "tweets"},
+	})
+	assert.NoError(t, err)
+}
+
+func TestNewHandler(t *testing.T) {
+	t.Parallel()
+
+	patterns := []struct {
+		name string
+		pcfg *config.PubSub
+		err  error
+	}{
+		{
+			name: "valid",
+			pcfg: &config.PubSub{PublishFormat: config.PubSubPublishFormatJSON},
+		},
+		{
+			name: "invalid publish format",
+			pcfg: &config.PubSub{PublishFormat: "invalid_format"},
+			err:  ErrInvalidPublishFormat,
+		},
+		{
+			name: "invalid cloudevents mode",
+			pcfg: &config.PubSub{PublishFormat: config.PubSubPublishFormatJSON, CloudEvents: "invalid_mode"},
+			err:  ErrInvalidCloudEventsMode,
+		},
+		{
+			name: "invalid ordering key",
+			pcfg: &config.PubSub{PublishFormat: config.PubSubPublishFormatJSON, OrderingKey: "invalid"},
+			err:  ErrInvalidOrderingKey,
+		},
+		{
+			name: "ordering key field is missing",
+			pcfg: &config.PubSub{PublishFormat: config.PubSubPublishFormatJSON, OrderingKey: config.OrderingKeyField},
+			err:  ErrMissingOrderingField,
+		},
+		{
+			name: "reserved attribute",
+			pcfg: &config.PubSub{PublishFormat: config.PubSubPublishFormatJSON, Attributes: map[string]string{"goog_id": "1"}},
+			err:  ErrInvalidAttribute,
+		},
+	}
+
+	for _, tt := range patterns {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			// the misconfigured handler fails at startup, not at every event
+			h, err := NewHandler(mock.NewMockPublisher(ctrl), tt.pcfg)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				assert.Nil(t, h)
+				return
+			}
+			assert.NoError(t, err)
+			assert.NotNil(t, h)
 		})
 	}
 }
@@ -2,22 +2,55 @@ package config
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/google/wire"
 	envconfig "github.com/sethvargo/go-envconfig"
 )
 
+var (
+	// ErrMissingStorageFilePath is returned when the file storage is selected without a path.
+	ErrMissingStorageFilePath = errors.New("config: STORAGE_FILE_PATH is required when STORAGE_TYPE is file")
+	// ErrMissingKafkaBrokers is returned when the kafka sink is selected without brokers.
+	ErrMissingKafkaBrokers = errors.New("config: KAFKA_BROKERS is required when the sink is kafka")
+	// ErrMissingKafkaTopic is returned when the kafka sink is selected without a topic.
+	ErrMissingKafkaTopic = errors.New("config: KAFKA_TOPIC is required when the sink is kafka")
+	// ErrMissingAdminToken is returned when the admin endpoints are enabled without a token.
+	ErrMissingAdminToken = errors.New("config: ADMIN_TOKEN is required when ADMIN_ENABLED is true")
+)
+
 // Set is a Wire provider set that provides configuration.
 var Set = wire.NewSet(
 	NewMongoDB,
 	NewPubSub,
 	NewMetrics,
+	NewSink,
+	NewKafka,
+	NewStorage,
+	NewRetry,
+	NewReconnect,
+	NewDelivery,
+	NewDeadLetter,
+	NewStreams,
+	NewSchemaRegistry,
+	NewAdmin,
 )
 
 const (
-	mongoDBPrefix = "MONGO_DB_"
-	pubSubPrefix  = "PUBSUB_"
-	mrtricsPrefix = "METRICS_"
+	mongoDBPrefix        = "MONGO_DB_"
+	pubSubPrefix         = "PUBSUB_"
+	mrtricsPrefix        = "METRICS_"
+	sinkPrefix           = "SINK_"
+	kafkaPrefix          = "KAFKA_"
+	storagePrefix        = "STORAGE_"
+	retryPrefix          = "RETRY_"
+	reconnectPrefix      = "RECONNECT_"
+	deliveryPrefix       = "DELIVERY_"
+	deadLetterPrefix     = "DEAD_LETTER_"
+	streamsPrefix        = "STREAMS_"
+	schemaRegistryPrefix = "SCHEMA_REGISTRY_"
+	adminPrefix          = "ADMIN_"
 )
 
 // PublishFormat is the format of the message to publish.
@@ -26,14 +59,154 @@ const (
 	PubSubPublishFormatJSON = "json"
 	// PublishFormatAvro is the Avro format.
 	PubSubPublishFormatAvro = "avro"
+	// PubSubPublishFormatCanonicalExtJSON is the canonical MongoDB Extended JSON v2 format.
+	PubSubPublishFormatCanonicalExtJSON = "canonical_extjson"
+	// PubSubPublishFormatRelaxedExtJSON is the relaxed MongoDB Extended JSON v2 format.
+	PubSubPublishFormatRelaxedExtJSON = "relaxed_extjson"
+	// PubSubPublishFormatDebezium is the event format of the Debezium MongoDB connector.
+	// Events that the connector does not emit, e.g. drop or rename, are skipped.
+	PubSubPublishFormatDebezium = "debezium"
+	// PubSubPublishFormatProtobuf is the Protocol Buffers format of schema/change_stream.proto.
+	PubSubPublishFormatProtobuf = "protobuf"
+)
+
+// ProtobufDocuments is the encoding of the documents in the protobuf format.
+const (
+	// ProtobufDocumentsBSON carries documents as raw BSON bytes.
+	ProtobufDocumentsBSON = "bson"
+	// ProtobufDocumentsStruct carries documents as google.protobuf.Struct.
+	ProtobufDocumentsStruct = "struct"
+)
+
+// CloudEventsMode is the CloudEvents content mode to wrap published messages in.
+const (
+	// CloudEventsModeNone publishes the change event as is.
+	CloudEventsModeNone = "none"
+	// CloudEventsModeStructured wraps the change event in a JSON CloudEvents envelope.
+	CloudEventsModeStructured = "structured"
+	// CloudEventsModeBinary publishes the change event as data and the CloudEvents
+	// context attributes as message attributes.
+	CloudEventsModeBinary = "binary"
+)
+
+// OrderingKey is the source of the ordering key of published messages.
+const (
+	// OrderingKeyNone publishes messages without ordering keys.
+	OrderingKeyNone = "none"
+	// OrderingKeyDocumentKey orders the messages of the same document.
+	OrderingKeyDocumentKey = "document_key"
+	// OrderingKeyNamespace orders the messages of the same collection.
+	OrderingKeyNamespace = "namespace"
+	// OrderingKeyField orders the messages with the same value of a field of the document.
+	OrderingKeyField = "field"
+)
+
+// HistoryLostPolicy is the policy when the resume token is no longer in the oplog.
+const (
+	// HistoryLostPolicyFail stops the stream.
+	HistoryLostPolicyFail = "fail"
+	// HistoryLostPolicyRestart restarts the stream from now, the events since the
+	// resume token are not streamed.
+	HistoryLostPolicyRestart = "restart"
+	// HistoryLostPolicyResnapshot publishes the documents of the watched collections
+	// as snapshot events, then restarts the stream from the time of the snapshot.
+	HistoryLostPolicyResnapshot = "resnapshot"
+)
+
+// SnapshotMode is when the documents of the watched collections are snapshotted.
+const (
+	// SnapshotModeNever streams the changes only.
+	SnapshotModeNever = "never"
+	// SnapshotModeInitial publishes the documents of the watched collections as
+	// snapshot events when no resume token is saved, then streams the changes since
+	// the snapshot.
+	SnapshotModeInitial = "initial"
+)
+
+// SinkType is the type of the sink to publish change events to.
+const (
+	// SinkTypePubSub is the Google Cloud Pub/Sub sink.
+	SinkTypePubSub = "pubsub"
+	// SinkTypeKafka is the Apache Kafka sink.
+	SinkTypeKafka = "kafka"
+)
+
+// StorageType is the type of the storage to persist resume tokens to.
+const (
+	// StorageTypeLog only logs resume tokens and does not persist them.
+	StorageTypeLog = "log"
+	// StorageTypeFile persists resume tokens to a local file.
+	StorageTypeFile = "file"
+	// StorageTypeMongoDB persists resume tokens to a MongoDB collection.
+	StorageTypeMongoDB = "mongodb"
+	// StorageTypeRedis persists resume tokens to Redis.
+	StorageTypeRedis = "redis"
+)
+
+// DeadLetterType is the type of the dead letter queue to store failed change events to.
+const (
+	// DeadLetterTypeNone disables the dead letter queue, a failed event stops the stream.
+	DeadLetterTypeNone = "none"
+	// DeadLetterTypeFile stores failed events to a local file.
+	DeadLetterTypeFile = "file"
+	// DeadLetterTypePubSub publishes failed events to a Pub/Sub topic.
+	DeadLetterTypePubSub = "pubsub"
+	// DeadLetterTypeMongoDB stores failed events to a MongoDB collection.
+	DeadLetterTypeMongoDB = "mongodb"
 )
 
 type MongoDB struct {
-	URI        string `env:"URI, required"`
-	Password   string `env:"PASSWORD"`
-	User       string `env:"USER"`
-	Database   string `env:"DATABASE, required"`
-	Collection string `env:"COLLECTION, required"`
+	URI      string `env:"URI, required"`
+	Password string `env:"PASSWORD"`
+	User     string `env:"USER"`
+	// Database is the database to watch. All databases of the deployment are watched if it is empty.
+	Database string `env:"DATABASE"`
+	// Collection is the collection to watch. All collections of Database are watched if it is empty.
+	Collection string `env:"COLLECTION"`
+	// IncludeNamespaces are the patterns of "<database>.<collection>" to stream,
+	// all namespaces are streamed if it is empty. A pattern is a glob (e.g. "db.logs_*")
+	// or a regular expression enclosed in slashes (e.g. "/^db\.logs_[0-9]+$/").
+	// Events of a database, e.g. dropDatabase, are matched by "db.*".
+	IncludeNamespaces []string `env:"INCLUDE_NAMESPACES"`
+	// ExcludeNamespaces are the patterns of namespaces not to stream, in the same
+	// format as IncludeNamespaces. It takes precedence over IncludeNamespaces.
+	ExcludeNamespaces []string `env:"EXCLUDE_NAMESPACES"`
+	// Pipeline is the aggregation pipeline applied to the change stream, as an
+	// Extended JSON array of stages (e.g. `[{"$match": {"operationType": "insert"}}]`).
+	Pipeline string `env:"PIPELINE"`
+	// PipelineFile is the path of the file that contains Pipeline. It is
+	// exclusive with Pipeline.
+	PipelineFile string `env:"PIPELINE_FILE"`
+	// FullDocument is the mode to return the full document of update events.
+	// Supported modes are: default, updateLookup, whenAvailable, required.
+	// whenAvailable and required read post-images, which need changeStreamPreAndPostImages.
+	FullDocument string `env:"FULL_DOCUMENT"`
+	// FullDocumentBeforeChange is the mode to return the document before the change.
+	// Supported modes are: off, whenAvailable, required. whenAvailable and required
+	// read pre-images, which need changeStreamPreAndPostImages.
+	FullDocumentBeforeChange string `env:"FULL_DOCUMENT_BEFORE_CHANGE"`
+	// HistoryLostPolicy is the policy when the resume token is no longer in the oplog.
+	// Supported policies are: fail, restart, resnapshot.
+	HistoryLostPolicy string `env:"HISTORY_LOST_POLICY, default=fail"`
+	// StartAtOperationTime is the time to start the change stream at when no resume token
+	// is saved, as a cluster time "<seconds>.<increment>" or an RFC 3339 time.
+	StartAtOperationTime string `env:"START_AT_OPERATION_TIME"`
+	// StartAfter is the resume token to start the change stream after when no resume token
+	// is saved, as Extended JSON or the _data of the token. Unlike the saved resume token,
+	// the change stream can start after an invalidate event. It is exclusive with
+	// StartAtOperationTime.
+	StartAfter string `env:"START_AFTER"`
+	// StartOverride starts the change stream at StartAtOperationTime or StartAfter even if
+	// a resume token is saved, e.g. to replay the changes since a point in time. The saved
+	// resume token is discarded.
+	StartOverride bool `env:"START_OVERRIDE"`
+	// SnapshotMode is when the documents of the watched collections are snapshotted.
+	// Supported modes are: never, initial. initial is exclusive with StartAtOperationTime
+	// and StartAfter unless StartOverride is set, which skips the snapshot.
+	SnapshotMode string `env:"SNAPSHOT_MODE, default=never"`
+	// SnapshotChunkSize is the number of documents between the checkpoints of a snapshot,
+	// from which an interrupted snapshot is resumed.
+	SnapshotChunkSize int `env:"SNAPSHOT_CHUNK_SIZE, default=1000"`
 }
 
 type PubSub struct {
@@ -42,14 +215,181 @@ type PubSub struct {
 	// TopicID is the id of the topic to publish messages to.
 	TopicID string `env:"TOPIC_ID"`
 	// PublishFormat is the format of the message to publish.
-	// Supported format are: json, avro.
+	// Supported format are: json, avro, canonical_extjson, relaxed_extjson, debezium, protobuf.
 	PublishFormat string `env:"PUBLISH_FORMAT, default=json"`
+	// ProtobufDocuments is the encoding of the documents in the protobuf format.
+	// Supported encodings are: bson, struct.
+	ProtobufDocuments string `env:"PROTOBUF_DOCUMENTS, default=bson"`
+	// DebeziumServerName is the logical name of the connector in the source of debezium events.
+	DebeziumServerName string `env:"DEBEZIUM_SERVER_NAME, default=mongo-streamer"`
+	// OrderingKey is the source of the ordering key of the message to publish, message
+	// ordering of the topic is enabled unless it is none. Supported sources are:
+	// none, document_key, namespace, field. Messages with the same key are published
+	// one at a time, so they stay in order when they are retried.
+	OrderingKey string `env:"ORDERING_KEY, default=none"`
+	// OrderingKeyField is the dot-separated path of the field of the full document,
+	// or of the pre-image when it is not available, used when OrderingKey is field.
+	OrderingKeyField string `env:"ORDERING_KEY_FIELD"`
+	// Attributes are the static attributes added to every message, e.g. "team:search,env:prod".
+	// They do not override the attributes of the change event metadata.
+	Attributes map[string]string `env:"ATTRIBUTES"`
+	// CloudEvents is the CloudEvents 1.0 content mode of the message to publish.
+	// Supported modes are: none, structured, binary.
+	CloudEvents string `env:"CLOUD_EVENTS, default=none"`
 }
 
 type Metrics struct {
 	Addr string `env:"ADDR, default=:8080"`
 }
 
+// Admin is the configuration of the admin endpoints, served on the metrics address.
+type Admin struct {
+	// Enabled serves the admin endpoints.
+	Enabled bool `env:"ENABLED, default=false"`
+	// Token is the bearer token required by the admin endpoints. It is required
+	// when Enabled is true, since the endpoints are served on the metrics address.
+	Token string `env:"TOKEN"`
+}
+
+type Sink struct {
+	// Type is the type of the sink to publish change events to.
+	// Supported types are: pubsub, kafka.
+	Type string `env:"TYPE, default=pubsub"`
+}
+
+type Kafka struct {
+	// Brokers is the list of seed brokers to connect to.
+	Brokers []string `env:"BROKERS"`
+	// Topic is the topic to produce messages to.
+	Topic string `env:"TOPIC"`
+	// ClientID is the client id sent to the brokers.
+	ClientID string `env:"CLIENT_ID, default=mongo-streamer"`
+}
+
+// Validate returns an error when the brokers or the topic are not set.
+func (k *Kafka) Validate() error {
+	if len(k.Brokers) == 0 {
+		return ErrMissingKafkaBrokers
+	}
+	if k.Topic == "" {
+		return ErrMissingKafkaTopic
+	}
+	return nil
+}
+
+type Storage struct {
+	// Type is the type of the storage to persist resume tokens to.
+	// Supported types are: log, file, mongodb, redis. The log type does not persist
+	// resume tokens, so the stream starts from the current time after a restart.
+	Type string `env:"TYPE, default=log"`
+	// StreamID is the id of the stream the resume tokens belong to.
+	// Defaults to "<database>.<collection>" of the watched collection.
+	StreamID string `env:"STREAM_ID"`
+	// FilePath is the path of the file to persist resume tokens to.
+	// It is required when Type is file.
+	FilePath string `env:"FILE_PATH"`
+	// MongoDBDatabase is the database of the collection to persist resume tokens to.
+	// Defaults to the watched database. It is used when Type is mongodb.
+	MongoDBDatabase string `env:"MONGODB_DATABASE"`
+	// MongoDBCollection is the collection to persist resume tokens to.
+	// It is used when Type is mongodb.
+	MongoDBCollection string `env:"MONGODB_COLLECTION, default=mongo_streamer_checkpoints"`
+	// RedisAddr is the address of the Redis server to persist resume tokens to.
+	// It is used when Type is redis.
+	RedisAddr string `env:"REDIS_ADDR, default=localhost:6379"`
+	// RedisPassword is the password of the Redis server.
+	RedisPassword string `env:"REDIS_PASSWORD"`
+	// RedisDB is the database number of the Redis server.
+	RedisDB int `env:"REDIS_DB, default=0"`
+	// RedisKeyPrefix is the prefix of the key, the key is "<prefix>:<stream id>".
+	RedisKeyPrefix string `env:"REDIS_KEY_PREFIX, default=mongo-streamer"`
+	// RedisTTL is the expiration of the resume token, 0 means no expiration.
+	RedisTTL time.Duration `env:"REDIS_TTL, default=0"`
+	// BufferSize is the number of resume tokens to buffer before flushing to the storage.
+	BufferSize int `env:"BUFFER_SIZE, default=10"`
+	// FlushInterval is the interval at which buffered resume tokens are flushed to the storage.
+	FlushInterval time.Duration `env:"FLUSH_INTERVAL, default=5s"`
+}
+
+type Retry struct {
+	// MaxAttempts is the maximum number of attempts to handle a change event,
+	// 0 means the event is retried until it succeeds.
+	MaxAttempts int `env:"MAX_ATTEMPTS, default=0"`
+	// InitialInterval is the delay after the first failed attempt.
+	InitialInterval time.Duration `env:"INITIAL_INTERVAL, default=100ms"`
+	// MaxInterval is the upper bound of the delay between attempts.
+	MaxInterval time.Duration `env:"MAX_INTERVAL, default=30s"`
+	// Multiplier is the factor by which the delay grows after each attempt.
+	Multiplier float64 `env:"MULTIPLIER, default=2"`
+}
+
+// Reconnect is the policy to reopen a change stream that terminated with a resumable
+// error, e.g. a network error or a primary step-down.
+type Reconnect struct {
+	// MaxAttempts is the maximum number of consecutive attempts to reopen the change stream,
+	// 0 means the change stream is reopened until it succeeds.
+	MaxAttempts int `env:"MAX_ATTEMPTS, default=0"`
+	// InitialInterval is the delay before the first attempt.
+	InitialInterval time.Duration `env:"INITIAL_INTERVAL, default=1s"`
+	// MaxInterval is the upper bound of the delay between attempts.
+	MaxInterval time.Duration `env:"MAX_INTERVAL, default=1m"`
+	// Multiplier is the factor by which the delay grows after each attempt.
+	Multiplier float64 `env:"MULTIPLIER, default=2"`
+	// Jitter is the randomization factor of the delay in [0, 1], so that streams
+	// do not reconnect at the same time.
+	Jitter float64 `env:"JITTER, default=0.2"`
+}
+
+type Delivery struct {
+	// MaxInFlight is the maximum number of events waiting for the acknowledgement
+	// of the sink concurrently. 1 publishes events one by one.
+	MaxInFlight int `env:"MAX_IN_FLIGHT, default=100"`
+}
+
+type DeadLetter struct {
+	// Type is the type of the dead letter queue to store failed change events to.
+	// Supported types are: none, file, pubsub, mongodb.
+	Type string `env:"TYPE, default=none"`
+	// FilePath is the path of the file to store failed events to.
+	// It is used when Type is file.
+	FilePath string `env:"FILE_PATH, default=data/dead_letter.jsonl"`
+	// PubSubTopicID is the id of the topic to publish failed events to,
+	// in the project of PUBSUB_PROJECT_ID. It is used when Type is pubsub.
+	PubSubTopicID string `env:"PUBSUB_TOPIC_ID"`
+	// PubSubSubscriptionID is the id of the subscription of the topic to replay
+	// failed events from. It is used when Type is pubsub.
+	PubSubSubscriptionID string `env:"PUBSUB_SUBSCRIPTION_ID"`
+	// ReplayIdleTimeout is the time without messages after which the replay from
+	// the subscription ends, at least 1ms. It is used when Type is pubsub.
+	ReplayIdleTimeout time.Duration `env:"REPLAY_IDLE_TIMEOUT, default=10s"`
+	// MongoDBDatabase is the database of the collection to store failed events to.
+	// Defaults to the watched database. It is used when Type is mongodb.
+	MongoDBDatabase string `env:"MONGODB_DATABASE"`
+	// MongoDBCollection is the collection to store failed events to.
+	// It is used when Type is mongodb.
+	MongoDBCollection string `env:"MONGODB_COLLECTION, default=mongo_streamer_dead_letters"`
+}
+
+type Streams struct {
+	// ConfigFile is the path of the YAML file that declares the streams to run.
+	// A single stream is configured by the environment variables if it is empty.
+	ConfigFile string `env:"CONFIG_FILE"`
+}
+
+type SchemaRegistry struct {
+	// URL is the base URL of the Confluent Schema Registry compatible API.
+	// Avro messages are framed with the schema id when it is set.
+	URL string `env:"URL"`
+	// Subject is the subject to register the Avro schema under.
+	// Defaults to "<topic>-value" of the topic name strategy.
+	Subject string `env:"SUBJECT"`
+	// Username and Password are the credentials of the basic authentication.
+	Username string `env:"USERNAME"`
+	Password string `env:"PASSWORD"`
+	// Timeout is the timeout of a request to the registry.
+	Timeout time.Duration `env:"TIMEOUT, default=10s"`
+}
+
 func NewMongoDB(ctx context.Context) (*MongoDB, error) {
 	conf := &MongoDB{}
 	pl := envconfig.PrefixLookuper(mongoDBPrefix, envconfig.OsLookuper())
@@ -88,3 +428,151 @@ func NewMetrics(ctx context.Context) (*Metrics, error) {
 
 	return conf, nil
 }
+
+func NewSink(ctx context.Context) (*Sink, error) {
+	conf := &Sink{}
+	pl := envconfig.PrefixLookuper(sinkPrefix, envconfig.OsLookuper())
+	if err := envconfig.ProcessWith(ctx, &envconfig.Config{
+		Target:   conf,
+		Lookuper: pl,
+	}); err != nil {
+		return nil, err
+	}
+
+	return conf, nil
+}
+
+// NewKafka creates the Kafka configuration. The brokers and the topic are required
+// when the sink is kafka, the topic may be set by each stream of the streams config file instead.
+func NewKafka(ctx context.Context, sink *Sink, streams *Streams) (*Kafka, error) {
+	conf := &Kafka{}
+	pl := envconfig.PrefixLookuper(kafkaPrefix, envconfig.OsLookuper())
+	if err := envconfig.ProcessWith(ctx, &envconfig.Config{
+		Target:   conf,
+		Lookuper: pl,
+	}); err != nil {
+		return nil, err
+	}
+	if sink.Type == SinkTypeKafka {
+		// the topic may be set per stream in the streams config file instead
+		err := conf.Validate()
+		if errors.Is(err, ErrMissingKafkaTopic) && streams.ConfigFile != "" {
+			err = nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return conf, nil
+}
+
+func NewStorage(ctx context.Context) (*Storage, error) {
+	conf := &Storage{}
+	pl := envconfig.PrefixLookuper(storagePrefix, envconfig.OsLookuper())
+	if err := envconfig.ProcessWith(ctx, &envconfig.Config{
+		Target:   conf,
+		Lookuper: pl,
+	}); err != nil {
+		return nil, err
+	}
+	if conf.Type == StorageTypeFile && conf.FilePath == "" {
+		return nil, ErrMissingStorageFilePath
+	}
+
+	return conf, nil
+}
+
+func NewRetry(ctx context.Context) (*Retry, error) {
+	conf := &Retry{}
+	pl := envconfig.PrefixLookuper(retryPrefix, envconfig.OsLookuper())
+	if err := envconfig.ProcessWith(ctx, &envconfig.Config{
+		Target:   conf,
+		Lookuper: pl,
+	}); err != nil {
+		return nil, err
+	}
+
+	return conf, nil
+}
+
+func NewReconnect(ctx context.Context) (*Reconnect, error) {
+	conf := &Reconnect{}
+	pl := envconfig.PrefixLookuper(reconnectPrefix, envconfig.OsLookuper())
+	if err := envconfig.ProcessWith(ctx, &envconfig.Config{
+		Target:   conf,
+		Lookuper: pl,
+	}); err != nil {
+		return nil, err
+	}
+
+	return conf, nil
+}
+
+func NewDelivery(ctx context.Context) (*Delivery, error) {
+	conf := &Delivery{}
+	pl := envconfig.PrefixLookuper(deliveryPrefix, envconfig.OsLookuper())
+	if err := envconfig.ProcessWith(ctx, &envconfig.Config{
+		Target:   conf,
+		Lookuper: pl,
+	}); err != nil {
+		return nil, err
+	}
+
+	return conf, nil
+}
+
+func NewDeadLetter(ctx context.Context) (*DeadLetter, error) {
+	conf := &DeadLetter{}
+	pl := envconfig.PrefixLookuper(deadLetterPrefix, envconfig.OsLookuper())
+	if err := envconfig.ProcessWith(ctx, &envconfig.Config{
+		Target:   conf,
+		Lookuper: pl,
+	}); err != nil {
+		return nil, err
+	}
+
+	return conf, nil
+}
+
+func NewStreams(ctx context.Context) (*Streams, error) {
+	conf := &Streams{}
+	pl := envconfig.PrefixLookuper(streamsPrefix, envconfig.OsLookuper())
+	if err := envconfig.ProcessWith(ctx, &envconfig.Config{
+		Target:   conf,
+		Lookuper: pl,
+	}); err != nil {
+		return nil, err
+	}
+
+	return conf, nil
+}
+
+func NewSchemaRegistry(ctx context.Context) (*SchemaRegistry, error) {
+	conf := &SchemaRegistry{}
+	pl := envconfig.PrefixLookuper(schemaRegistryPrefix, envconfig.OsLookuper())
+	if err := envconfig.ProcessWith(ctx, &envconfig.Config{
+		Target:   conf,
+		Lookuper: pl,
+	}); err != nil {
+		return nil, err
+	}
+
+	return conf, nil
+}
+
+func NewAdmin(ctx context.Context) (*Admin, error) {
+	conf := &Admin{}
+	pl := envconfig.PrefixLookuper(adminPrefix, envconfig.OsLookuper())
+	if err := envconfig.ProcessWith(ctx, &envconfig.Config{
+		Target:   conf,
+		Lookuper: pl,
+	}); err != nil {
+		return nil, err
+	}
+	if conf.Enabled && conf.Token == "" {
+		return nil, ErrMissingAdminToken
+	}
+
+	return conf, nil
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/sethvargo/go-envconfig"
 	"github.com/stretchr/testify/assert"
@@ -34,13 +35,46 @@ func TestMongoDB(t *testing.T) {
 				t.Setenv("MONGO_DB_PASSWORD", "pass")
 				t.Setenv("MONGO_DB_DATABASE", "database")
 				t.Setenv("MONGO_DB_COLLECTION", "col")
+				t.Setenv("MONGO_DB_FULL_DOCUMENT", "updateLookup")
+				t.Setenv("MONGO_DB_FULL_DOCUMENT_BEFORE_CHANGE", "whenAvailable")
+				t.Setenv("MONGO_DB_HISTORY_LOST_POLICY", "resnapshot")
+				t.Setenv("MONGO_DB_START_AT_OPERATION_TIME", "1705555648.1")
+				t.Setenv("MONGO_DB_START_OVERRIDE", "true")
+				t.Setenv("MONGO_DB_SNAPSHOT_MODE", "initial")
+				t.Setenv("MONGO_DB_SNAPSHOT_CHUNK_SIZE", "500")
 			},
 			want: &MongoDB{
-				URI:        "mongodb://localhost:27017",
-				Password:   "pass",
-				User:       "root",
-				Database:   "database",
-				Collection: "col",
+				URI:                      "mongodb://localhost:27017",
+				Password:                 "pass",
+				User:                     "root",
+				Database:                 "database",
+				Collection:               "col",
+				FullDocument:             "updateLookup",
+				FullDocumentBeforeChange: "whenAvailable",
+				HistoryLostPolicy:        HistoryLostPolicyResnapshot,
+				StartAtOperationTime:     "1705555648.1",
+				StartOverride:            true,
+				SnapshotMode:             SnapshotModeInitial,
+				SnapshotChunkSize:        500,
+			},
+		},
+		{
+			name: "watch deployment with namespace patterns",
+			setup: func(t *testing.T) {
+				t.Helper()
+				t.Setenv("MONGO_DB_URI", "mongodb://localhost:27017")
+				t.Setenv("MONGO_DB_INCLUDE_NAMESPACES", "db.logs_*,/^app\\./")
+				t.Setenv("MONGO_DB_EXCLUDE_NAMESPACES", "db.logs_tmp")
+				t.Setenv("MONGO_DB_PIPELINE", `[{"$match": {"operationType": "insert"}}]`)
+			},
+			want: &MongoDB{
+				URI:               "mongodb://localhost:27017",
+				IncludeNamespaces: []string{"db.logs_*", `/^app\./`},
+				ExcludeNamespaces: []string{"db.logs_tmp"},
+				Pipeline:          `[{"$match": {"operationType": "insert"}}]`,
+				HistoryLostPolicy: HistoryLostPolicyFail,
+				SnapshotMode:      SnapshotModeNever,
+				SnapshotChunkSize: 1000,
 			},
 		},
 	}
@@ -73,7 +107,11 @@ func TestPubSub(t *testing.T) {
 				t.Helper()
 			},
 			want: &PubSub{
-				PublishFormat: PubSubPublishFormatJSON,
+				PublishFormat:      PubSubPublishFormatJSON,
+				ProtobufDocuments:  ProtobufDocumentsBSON,
+				DebeziumServerName: "mongo-streamer",
+				OrderingKey:        OrderingKeyNone,
+				CloudEvents:        CloudEventsModeNone,
 			},
 		},
 		{
@@ -83,11 +121,23 @@ func TestPubSub(t *testing.T) {
 				t.Setenv("PUBSUB_PROJECT_ID", "project")
 				t.Setenv("PUBSUB_TOPIC_ID", "topic")
 				t.Setenv("PUBSUB_PUBLISH_FORMAT", "avro")
+				t.Setenv("PUBSUB_PROTOBUF_DOCUMENTS", "struct")
+				t.Setenv("PUBSUB_DEBEZIUM_SERVER_NAME", "fulfillment")
+				t.Setenv("PUBSUB_ORDERING_KEY", "field")
+				t.Setenv("PUBSUB_ORDERING_KEY_FIELD", "customer.id")
+				t.Setenv("PUBSUB_ATTRIBUTES", "team:search,env:prod")
+				t.Setenv("PUBSUB_CLOUD_EVENTS", "binary")
 			},
 			want: &PubSub{
-				ProjectID:     "project",
-				TopicID:       "topic",
-				PublishFormat: PubSubPublishFormatAvro,
+				ProjectID:          "project",
+				TopicID:            "topic",
+				PublishFormat:      PubSubPublishFormatAvro,
+				ProtobufDocuments:  ProtobufDocumentsStruct,
+				DebeziumServerName: "fulfillment",
+				OrderingKey:        OrderingKeyField,
+				OrderingKeyField:   "customer.id",
+				Attributes:         map[string]string{"team": "search", "env": "prod"},
+				CloudEvents:        CloudEventsModeBinary,
 			},
 		},
 	}
@@ -144,3 +194,578 @@ func TestMetrics(t *testing.T) {
 		})
 	}
 }
+
+func TestSink(t *testing.T) {
+	ctx := context.Background()
+
+	patterns := []struct {
+		name  string
+		setup func(t *testing.T)
+		want  *Sink
+	}{
+		{
+			name: "default",
+			setup: func(t *testing.T) {
+				t.Helper()
+			},
+			want: &Sink{
+				Type: SinkTypePubSub,
+			},
+		},
+		{
+			name: "set envs",
+			setup: func(t *testing.T) {
+				t.Helper()
+				t.Setenv("SINK_TYPE", "kafka")
+			},
+			want: &Sink{
+				Type: SinkTypeKafka,
+			},
+		},
+	}
+
+	for _, tt := range patterns {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setup(t)
+
+			got, err := NewSink(ctx)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestKafka(t *testing.T) {
+	ctx := context.Background()
+
+	patterns := []struct {
+		name    string
+		setup   func(t *testing.T)
+		sink    *Sink
+		streams *Streams
+		want    *Kafka
+		err     error
+	}{
+		{
+			name: "default",
+			setup: func(t *testing.T) {
+				t.Helper()
+			},
+			want: &Kafka{
+				ClientID: "mongo-streamer",
+			},
+		},
+		{
+			name: "kafka sink without brokers",
+			setup: func(t *testing.T) {
+				t.Helper()
+				t.Setenv("KAFKA_TOPIC", "topic")
+			},
+			sink: &Sink{Type: SinkTypeKafka},
+			want: nil,
+			err:  ErrMissingKafkaBrokers,
+		},
+		{
+			name: "kafka sink without topic",
+			setup: func(t *testing.T) {
+				t.Helper()
+				t.Setenv("KAFKA_BROKERS", "localhost:9092")
+			},
+			sink: &Sink{Type: SinkTypeKafka},
+			want: nil,
+			err:  ErrMissingKafkaTopic,
+		},
+		{
+			name: "kafka sink with topics of the streams config file",
+			setup: func(t *testing.T) {
+				t.Helper()
+				t.Setenv("KAFKA_BROKERS", "localhost:9092")
+			},
+			sink:    &Sink{Type: SinkTypeKafka},
+			streams: &Streams{ConfigFile: "streams.yaml"},
+			want: &Kafka{
+				Brokers:  []string{"localhost:9092"},
+				ClientID: "mongo-streamer",
+			},
+		},
+		{
+			name: "kafka sink with the streams config file without brokers",
+			setup: func(t *testing.T) {
+				t.Helper()
+			},
+			sink:    &Sink{Type: SinkTypeKafka},
+			streams: &Streams{ConfigFile: "streams.yaml"},
+			want:    nil,
+			err:     ErrMissingKafkaBrokers,
+		},
+		{
+			name: "set envs",
+			setup: func(t *testing.T) {
+				t.Helper()
+				t.Setenv("KAFKA_BROKERS", "localhost:9092,localhost:9093")
+				t.Setenv("KAFKA_TOPIC", "topic")
+				t.Setenv("KAFKA_CLIENT_ID", "client")
+			},
+			sink: &Sink{Type: SinkTypeKafka},
+			want: &Kafka{
+				Brokers:  []string{"localhost:9092", "localhost:9093"},
+				Topic:    "topic",
+				ClientID: "client",
+			},
+		},
+	}
+
+	for _, tt := range patterns {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setup(t)
+
+			sink := tt.sink
+			if sink == nil {
+				sink = &Sink{Type: SinkTypePubSub}
+			}
+			streams := tt.streams
+			if streams == nil {
+				streams = &Streams{}
+			}
+			got, err := NewKafka(ctx, sink, streams)
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestStorage(t *testing.T) {
+	ctx := context.Background()
+
+	patterns := []struct {
+		name  string
+		setup func(t *testing.T)
+		want  *Storage
+		err   error
+	}{
+		{
+			name: "default",
+			setup: func(t *testing.T) {
+				t.Helper()
+			},
+			want: &Storage{
+				Type:              StorageTypeLog,
+				MongoDBCollection: "mongo_streamer_checkpoints",
+				RedisAddr:         "localhost:6379",
+				RedisKeyPrefix:    "mongo-streamer",
+				BufferSize:        10,
+				FlushInterval:     5 * time.Second,
+			},
+		},
+		{
+			name: "set envs",
+			setup: func(t *testing.T) {
+				t.Helper()
+				t.Setenv("STORAGE_TYPE", "mongodb")
+				t.Setenv("STORAGE_STREAM_ID", "stream")
+				t.Setenv("STORAGE_FILE_PATH", "/var/lib/mongo-streamer/token")
+				t.Setenv("STORAGE_MONGODB_DATABASE", "streamer")
+				t.Setenv("STORAGE_MONGODB_COLLECTION", "checkpoints")
+				t.Setenv("STORAGE_REDIS_ADDR", "redis:6379")
+				t.Setenv("STORAGE_REDIS_PASSWORD", "pass")
+				t.Setenv("STORAGE_REDIS_DB", "1")
+				t.Setenv("STORAGE_REDIS_KEY_PREFIX", "streamer")
+				t.Setenv("STORAGE_REDIS_TTL", "24h")
+				t.Setenv("STORAGE_BUFFER_SIZE", "1")
+				t.Setenv("STORAGE_FLUSH_INTERVAL", "1s")
+			},
+			want: &Storage{
+				Type:              StorageTypeMongoDB,
+				StreamID:          "stream",
+				FilePath:          "/var/lib/mongo-streamer/token",
+				MongoDBDatabase:   "streamer",
+				MongoDBCollection: "checkpoints",
+				RedisAddr:         "redis:6379",
+				RedisPassword:     "pass",
+				RedisDB:           1,
+				RedisKeyPrefix:    "streamer",
+				RedisTTL:          24 * time.Hour,
+				BufferSize:        1,
+				FlushInterval:     time.Second,
+			},
+		},
+		{
+			name: "file without path",
+			setup: func(t *testing.T) {
+				t.Helper()
+				t.Setenv("STORAGE_TYPE", "file")
+			},
+			want: nil,
+			err:  ErrMissingStorageFilePath,
+		},
+		{
+			name: "file",
+			setup: func(t *testing.T) {
+				t.Helper()
+				t.Setenv("STORAGE_TYPE", "file")
+				t.Setenv("STORAGE_FILE_PATH", "data/resume_token")
+			},
+			want: &Storage{
+				Type:              StorageTypeFile,
+				FilePath:          "data/resume_token",
+				MongoDBCollection: "mongo_streamer_checkpoints",
+				RedisAddr:         "localhost:6379",
+				RedisKeyPrefix:    "mongo-streamer",
+				BufferSize:        10,
+				FlushInterval:     5 * time.Second,
+			},
+		},
+	}
+
+	for _, tt := range patterns {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setup(t)
+
+			got, err := NewStorage(ctx)
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRetry(t *testing.T) {
+	ctx := context.Background()
+
+	patterns := []struct {
+		name  string
+		setup func(t *testing.T)
+		want  *Retry
+	}{
+		{
+			name: "default",
+			setup: func(t *testing.T) {
+				t.Helper()
+			},
+			want: &Retry{
+				MaxAttempts:     0,
+				InitialInterval: 100 * time.Millisecond,
+				MaxInterval:     30 * time.Second,
+				Multiplier:      2,
+			},
+		},
+		{
+			name: "set envs",
+			setup: func(t *testing.T) {
+				t.Helper()
+				t.Setenv("RETRY_MAX_ATTEMPTS", "5")
+				t.Setenv("RETRY_INITIAL_INTERVAL", "1s")
+				t.Setenv("RETRY_MAX_INTERVAL", "1m")
+				t.Setenv("RETRY_MULTIPLIER", "1.5")
+			},
+			want: &Retry{
+				MaxAttempts:     5,
+				InitialInterval: time.Second,
+				MaxInterval:     time.Minute,
+				Multiplier:      1.5,
+			},
+		},
+	}
+
+	for _, tt := range patterns {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setup(t)
+
+			got, err := NewRetry(ctx)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestAdmin(t *testing.T) {
+	ctx := context.Background()
+
+	patterns := []struct {
+		name  string
+		setup func(t *testing.T)
+		want  *Admin
+		err   error
+	}{
+		{
+			name: "default",
+			setup: func(t *testing.T) {
+				t.Helper()
+			},
+			want: &Admin{},
+		},
+		{
+			name: "set envs",
+			setup: func(t *testing.T) {
+				t.Helper()
+				t.Setenv("ADMIN_ENABLED", "true")
+				t.Setenv("ADMIN_TOKEN", "secret")
+			},
+			want: &Admin{
+				Enabled: true,
+				Token:   "secret",
+			},
+		},
+		{
+			name: "enabled without token",
+			setup: func(t *testing.T) {
+				t.Helper()
+				t.Setenv("ADMIN_ENABLED", "true")
+			},
+			want: nil,
+			err:  ErrMissingAdminToken,
+		},
+	}
+
+	for _, tt := range patterns {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setup(t)
+
+			got, err := NewAdmin(ctx)
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestReconnect(t *testing.T) {
+	ctx := context.Background()
+
+	patterns := []struct {
+		name  string
+		setup func(t *testing.T)
+		want  *Reconnect
+	}{
+		{
+			name: "default",
+			setup: func(t *testing.T) {
+				t.Helper()
+			},
+			want: &Reconnect{
+				MaxAttempts:     0,
+				InitialInterval: time.Second,
+				MaxInterval:     time.Minute,
+				Multiplier:      2,
+				Jitter:          0.2,
+			},
+		},
+		{
+			name: "set envs",
+			setup: func(t *testing.T) {
+				t.Helper()
+				t.Setenv("RECONNECT_MAX_ATTEMPTS", "10")
+				t.Setenv("RECONNECT_INITIAL_INTERVAL", "500ms")
+				t.Setenv("RECONNECT_MAX_INTERVAL", "5m")
+				t.Setenv("RECONNECT_MULTIPLIER", "3")
+				t.Setenv("RECONNECT_JITTER", "0.5")
+			},
+			want: &Reconnect{
+				MaxAttempts:     10,
+				InitialInterval: 500 * time.Millisecond,
+				MaxInterval:     5 * time.Minute,
+				Multiplier:      3,
+				Jitter:          0.5,
+			},
+		},
+	}
+
+	for _, tt := range patterns {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setup(t)
+
+			got, err := NewReconnect(ctx)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestDelivery(t *testing.T) {
+	ctx := context.Background()
+
+	patterns := []struct {
+		name  string
+		setup func(t *testing.T)
+		want  *Delivery
+	}{
+		{
+			name: "default",
+			setup: func(t *testing.T) {
+				t.Helper()
+			},
+			want: &Delivery{
+				MaxInFlight: 100,
+			},
+		},
+		{
+			name: "set envs",
+			setup: func(t *testing.T) {
+				t.Helper()
+				t.Setenv("DELIVERY_MAX_IN_FLIGHT", "1")
+			},
+			want: &Delivery{
+				MaxInFlight: 1,
+			},
+		},
+	}
+
+	for _, tt := range patterns {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setup(t)
+
+			got, err := NewDelivery(ctx)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestDeadLetter(t *testing.T) {
+	ctx := context.Background()
+
+	patterns := []struct {
+		name  string
+		setup func(t *testing.T)
+		want  *DeadLetter
+	}{
+		{
+			name: "default",
+			setup: func(t *testing.T) {
+				t.Helper()
+			},
+			want: &DeadLetter{
+				Type:              DeadLetterTypeNone,
+				FilePath:          "data/dead_letter.jsonl",
+				ReplayIdleTimeout: 10 * time.Second,
+				MongoDBCollection: "mongo_streamer_dead_letters",
+			},
+		},
+		{
+			name: "set envs",
+			setup: func(t *testing.T) {
+				t.Helper()
+				t.Setenv("DEAD_LETTER_TYPE", "pubsub")
+				t.Setenv("DEAD_LETTER_FILE_PATH", "/tmp/dead_letter.jsonl")
+				t.Setenv("DEAD_LETTER_PUBSUB_TOPIC_ID", "dead-letter")
+				t.Setenv("DEAD_LETTER_PUBSUB_SUBSCRIPTION_ID", "dead-letter-sub")
+				t.Setenv("DEAD_LETTER_REPLAY_IDLE_TIMEOUT", "1m")
+				t.Setenv("DEAD_LETTER_MONGODB_DATABASE", "streamer")
+				t.Setenv("DEAD_LETTER_MONGODB_COLLECTION", "dead_letters")
+			},
+			want: &DeadLetter{
+				Type:                 DeadLetterTypePubSub,
+				FilePath:             "/tmp/dead_letter.jsonl",
+				PubSubTopicID:        "dead-letter",
+				PubSubSubscriptionID: "dead-letter-sub",
+				ReplayIdleTimeout:    time.Minute,
+				MongoDBDatabase:      "streamer",
+				MongoDBCollection:    "dead_letters",
+			},
+		},
+	}
+
+	for _, tt := range patterns {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setup(t)
+
+			got, err := NewDeadLetter(ctx)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestStreams(t *testing.T) {
+	ctx := context.Background()
+
+	patterns := []struct {
+		name  string
+		setup func(t *testing.T)
+		want  *Streams
+	}{
+		{
+			name: "default",
+			setup: func(t *testing.T) {
+				t.Helper()
+			},
+			want: &Streams{},
+		},
+		{
+			name: "set envs",
+			setup: func(t *testing.T) {
+				t.Helper()
+				t.Setenv("STREAMS_CONFIG_FILE", "/etc/mongo-streamer/streams.yaml")
+			},
+			want: &Streams{
+				ConfigFile: "/etc/mongo-streamer/streams.yaml",
+			},
+		},
+	}
+
+	for _, tt := range patterns {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setup(t)
+
+			got, err := NewStreams(ctx)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSchemaRegistry(t *testing.T) {
+	ctx := context.Background()
+
+	patterns := []struct {
+		name  string
+		setup func(t *testing.T)
+		want  *SchemaRegistry
+	}{
+		{
+			name: "default",
+			setup: func(t *testing.T) {
+				t.Helper()
+			},
+			want: &SchemaRegistry{
+				Timeout: 10 * time.Second,
+			},
+		},
+		{
+			name: "set envs",
+			setup: func(t *testing.T) {
+				t.Helper()
+				t.Setenv("SCHEMA_REGISTRY_URL", "http://localhost:8081")
+				t.Setenv("SCHEMA_REGISTRY_SUBJECT", "tweets-value")
+				t.Setenv("SCHEMA_REGISTRY_USERNAME", "user")
+				t.Setenv("SCHEMA_REGISTRY_PASSWORD", "password")
+				t.Setenv("SCHEMA_REGISTRY_TIMEOUT", "1s")
+			},
+			want: &SchemaRegistry{
+				URL:      "http://localhost:8081",
+				Subject:  "tweets-value",
+				Username: "user",
+				Password: "password",
+				Timeout:  time.Second,
+			},
+		},
+	}
+
+	for _, tt := range patterns {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setup(t)
+
+			got, err := NewSchemaRegistry(ctx)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Stream is a change stream declared in the streams config file.
+// FullDocument, FullDocumentBeforeChange, HistoryLostPolicy, StartAtOperationTime, StartAfter,
+// SnapshotMode, PublishFormat, CloudEvents, Sink and Topic inherit
+// the settings of the environment variables when empty.
+type Stream struct {
+	// Name identifies the stream in logs and metrics. It must be unique.
+	Name string `yaml:"name"`
+	// Database is the database to watch. All databases are watched if it is empty.
+	Database string `yaml:"database"`
+	// Collection is the collection to watch. All collections of Database are watched if it is empty.
+	Collection string `yaml:"collection"`
+	// IncludeNamespaces are the patterns of namespaces to stream, see MongoDB.IncludeNamespaces.
+	IncludeNamespaces []string `yaml:"include_namespaces"`
+	// ExcludeNamespaces are the patterns of namespaces not to stream, see MongoDB.ExcludeNamespaces.
+	ExcludeNamespaces []string `yaml:"exclude_namespaces"`
+	// Pipeline is the aggregation pipeline applied to the change stream, see MongoDB.Pipeline.
+	Pipeline string `yaml:"pipeline"`
+	// PipelineFile is the path of the file that contains Pipeline, relative to the config file.
+	PipelineFile string `yaml:"pipeline_file"`
+	// FullDocument is the fullDocument mode, overriding MONGO_DB_FULL_DOCUMENT.
+	FullDocument string `yaml:"full_document"`
+	// FullDocumentBeforeChange is the fullDocumentBeforeChange mode, overriding
+	// MONGO_DB_FULL_DOCUMENT_BEFORE_CHANGE.
+	FullDocumentBeforeChange string `yaml:"full_document_before_change"`
+	// HistoryLostPolicy is the policy when the resume token is no longer in the oplog,
+	// overriding MONGO_DB_HISTORY_LOST_POLICY.
+	HistoryLostPolicy string `yaml:"history_lost_policy"`
+	// StartAtOperationTime is the time to start the change stream at when no resume token
+	// is saved, overriding MONGO_DB_START_AT_OPERATION_TIME.
+	StartAtOperationTime string `yaml:"start_at_operation_time"`
+	// StartAfter is the resume token to start the change stream after when no resume token
+	// is saved, overriding MONGO_DB_START_AFTER.
+	StartAfter string `yaml:"start_after"`
+	// SnapshotMode is when the documents of the watched collections are snapshotted,
+	// overriding MONGO_DB_SNAPSHOT_MODE.
+	SnapshotMode string `yaml:"snapshot_mode"`
+	// PublishFormat is the format of the message to publish, overriding PUBSUB_PUBLISH_FORMAT.
+	PublishFormat string `yaml:"publish_format"`
+	// Attributes are the static attributes added to every message, merged with PUBSUB_ATTRIBUTES.
+	Attributes map[string]string `yaml:"attributes"`
+	// CloudEvents is the CloudEvents mode of the message to publish, overriding PUBSUB_CLOUD_EVENTS.
+	CloudEvents string `yaml:"cloud_events"`
+	// Sink is the type of the sink, overriding SINK_TYPE.
+	Sink string `yaml:"sink"`
+	// Topic is the Pub/Sub topic id or the Kafka topic, overriding PUBSUB_TOPIC_ID or KAFKA_TOPIC.
+	Topic string `yaml:"topic"`
+	// CheckpointKey is the id of the stream in the checkpoint storage. Defaults to Name.
+	CheckpointKey string `yaml:"checkpoint_key"`
+}
+
+// CheckpointID returns the id of the stream in the checkpoint storage.
+func (s Stream) CheckpointID() string {
+	if s.CheckpointKey != "" {
+		return s.CheckpointKey
+	}
+	return s.Name
+}
+
+// streamsFile is the content of the streams config file.
+type streamsFile struct {
+	Streams []Stream `yaml:"streams"`
+}
+
+// LoadStreams reads the streams declared in the YAML file at path.
+func LoadStreams(path string) ([]Stream, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read streams config file: %w", err)
+	}
+
+	var f streamsFile
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	if err := dec.Decode(&f); err != nil {
+		return nil, fmt.Errorf("failed to parse streams config file: %w", err)
+	}
+	if err := validateStreams(f.Streams); err != nil {
+		return nil, err
+	}
+	for i := range f.Streams {
+		// relative paths are relative to the config file
+		if p := f.Streams[i].PipelineFile; p != "" && !filepath.IsAbs(p) {
+			f.Streams[i].PipelineFile = filepath.Join(filepath.Dir(path), p)
+		}
+	}
+	return f.Streams, nil
+}
+
+func validateStreams(streams []Stream) error {
+	if len(streams) == 0 {
+		return errors.New("no streams are declared")
+	}
+
+	names := make(map[string]struct{}, len(streams))
+	keys := make(map[string]string, len(streams))
+	for i, s := range streams {
+		if s.Name == "" {
+			return fmt.Errorf("streams[%d]: name is required", i)
+		}
+		if _, ok := names[s.Name]; ok {
+			return fmt.Errorf("streams[%d]: duplicate name %q", i, s.Name)
+		}
+		names[s.Name] = struct{}{}
+
+		if s.Database == "" && s.Collection != "" {
+			return fmt.Errorf("stream %q: collection is set without database", s.Name)
+		}
+		if s.Pipeline != "" && s.PipelineFile != "" {
+			return fmt.Errorf("stream %q: pipeline and pipeline_file are exclusive", s.Name)
+		}
+
+		key := s.CheckpointID()
+		if other, ok := keys[key]; ok {
+			return fmt.Errorf("stream %q: checkpoint key %q is shared with stream %q", s.Name, key, other)
+		}
+		keys[key] = s.Name
+	}
+	return nil
+}
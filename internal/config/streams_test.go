@@ -0,0 +1,140 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadStreams(t *testing.T) {
+	t.Parallel()
+
+	patterns := []struct {
+		name    string
+		content string
+		want    []Stream
+		wantErr bool
+	}{
+		{
+			name: "success",
+			content: `
+streams:
+  - name: users
+    database: app
+    collection: users
+    sink: kafka
+    topic: users
+    full_document: updateLookup
+    full_document_before_change: whenAvailable
+    history_lost_policy: restart
+    snapshot_mode: initial
+  - name: logs
+    database: app
+    include_namespaces: ["app.logs_*"]
+    exclude_namespaces: ["app.logs_tmp"]
+    publish_format: avro
+    checkpoint_key: app-logs
+    pipeline: '[{"$match": {"operationType": "insert"}}]'
+`,
+			want: []Stream{
+				{
+					Name:                     "users",
+					Database:                 "app",
+					Collection:               "users",
+					Sink:                     SinkTypeKafka,
+					Topic:                    "users",
+					FullDocument:             "updateLookup",
+					FullDocumentBeforeChange: "whenAvailable",
+					HistoryLostPolicy:        HistoryLostPolicyRestart,
+					SnapshotMode:             SnapshotModeInitial,
+				},
+				{
+					Name:              "logs",
+					Database:          "app",
+					IncludeNamespaces: []string{"app.logs_*"},
+					ExcludeNamespaces: []string{"app.logs_tmp"},
+					PublishFormat:     PubSubPublishFormatAvro,
+					CheckpointKey:     "app-logs",
+					Pipeline:          `[{"$match": {"operationType": "insert"}}]`,
+				},
+			},
+		},
+		{
+			name:    "pipeline file relative to config file",
+			content: "streams:\n  - name: users\n    pipeline_file: pipelines/users.json\n",
+			want: []Stream{
+				{
+					Name:         "users",
+					PipelineFile: filepath.Join("{dir}", "pipelines", "users.json"),
+				},
+			},
+		},
+		{
+			name:    "no streams",
+			content: "streams: []\n",
+			wantErr: true,
+		},
+		{
+			name:    "unknown field",
+			content: "streams:\n  - name: users\n    colection: users\n",
+			wantErr: true,
+		},
+		{
+			name:    "missing name",
+			content: "streams:\n  - database: app\n",
+			wantErr: true,
+		},
+		{
+			name:    "duplicate name",
+			content: "streams:\n  - name: users\n  - name: users\n",
+			wantErr: true,
+		},
+		{
+			name:    "collection without database",
+			content: "streams:\n  - name: users\n    collection: users\n",
+			wantErr: true,
+		},
+		{
+			name:    "both pipeline and pipeline file",
+			content: "streams:\n  - name: users\n    pipeline: '[]'\n    pipeline_file: pipeline.json\n",
+			wantErr: true,
+		},
+		{
+			name:    "shared checkpoint key",
+			content: "streams:\n  - name: users\n  - name: logs\n    checkpoint_key: users\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range patterns {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "streams.yaml")
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0o600))
+
+			got, err := LoadStreams(path)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			for i := range tt.want {
+				tt.want[i].PipelineFile = strings.Replace(tt.want[i].PipelineFile, "{dir}", filepath.Dir(path), 1)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestStream_CheckpointID(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "users", Stream{Name: "users"}.CheckpointID())
+	assert.Equal(t, "key", Stream{Name: "users", CheckpointKey: "key"}.CheckpointID())
+}
@@ -0,0 +1,47 @@
+package deadletter
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type (
+	// Entry is a change event that could not be decoded or published.
+	Entry struct {
+		// Stream is the name of the stream the event belongs to.
+		Stream string `bson:"stream,omitempty" json:"stream,omitempty"`
+		// Event is the raw BSON change event.
+		Event bson.Raw `bson:"event" json:"event"`
+		// Token is the resume token of the event.
+		Token string `bson:"token" json:"token"`
+		// Error is the error of the last attempt.
+		Error string `bson:"error" json:"error"`
+		// Attempts is the number of attempts to handle the event.
+		Attempts int `bson:"attempts" json:"attempts"`
+		// Database is the database of the change stream.
+		Database string `bson:"database" json:"database"`
+		// Collection is the collection of the change stream.
+		Collection string `bson:"collection" json:"collection"`
+		// CreatedAt is the time the event was dead-lettered.
+		CreatedAt time.Time `bson:"created_at" json:"created_at"`
+	}
+
+	// Sink stores dead-lettered events.
+	Sink interface {
+		Put(ctx context.Context, entry Entry) error
+		Close(ctx context.Context) error
+	}
+
+	// Source reads dead-lettered events to replay them.
+	Source interface {
+		// Replay calls fn for each stored entry and removes the entries for which
+		// fn succeeds. It returns the number of replayed entries.
+		Replay(ctx context.Context, fn ReplayFunc) (int, error)
+		Close(ctx context.Context) error
+	}
+
+	// ReplayFunc is a function that replays a dead-lettered event.
+	ReplayFunc func(ctx context.Context, entry Entry) error
+)
@@ -0,0 +1,166 @@
+package deadletter
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/ucpr/mongo-streamer/pkg/log"
+)
+
+const (
+	fileDirPerm  = 0o755
+	fileFilePerm = 0o644
+)
+
+// File stores dead-lettered events to a local file as JSON lines.
+type File struct {
+	mu   sync.Mutex
+	path string
+}
+
+var (
+	_ Sink   = (*File)(nil)
+	_ Source = (*File)(nil)
+)
+
+// NewFile creates a new file dead letter queue. The parent directories of the
+// file are created if they do not exist.
+func NewFile(path string) (*File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), fileDirPerm); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	return &File{path: path}, nil
+}
+
+// Put appends the entry to the file.
+func (f *File) Put(ctx context.Context, entry Entry) error {
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal dead letter entry: %w", err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, fileFilePerm)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(b, '\n')); err != nil {
+		return fmt.Errorf("failed to write dead letter entry: %w", err)
+	}
+	return file.Sync()
+}
+
+// Replay calls fn for each entry in the file. The entries for which fn fails
+// are kept in the file, the others are removed.
+func (f *File) Replay(ctx context.Context, fn ReplayFunc) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	entries, err := f.read()
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		replayed int
+		remains  [][]byte
+	)
+	for i, line := range entries {
+		if ctx.Err() != nil {
+			remains = append(remains, entries[i:]...)
+			break
+		}
+
+		var entry Entry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			log.Error("Failed to unmarshal dead letter entry", log.Ferror(err))
+			remains = append(remains, line)
+			continue
+		}
+		if err := fn(ctx, entry); err != nil {
+			log.Error("Failed to replay dead letter entry", log.Ferror(err), log.Fstring("token", entry.Token))
+			remains = append(remains, line)
+			continue
+		}
+		replayed++
+	}
+
+	if err := f.rewrite(remains); err != nil {
+		return replayed, err
+	}
+	return replayed, ctx.Err()
+}
+
+// read returns the lines of the file.
+func (f *File) read() ([][]byte, error) {
+	file, err := os.Open(f.path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	var lines [][]byte
+	r := bufio.NewReader(file)
+	for {
+		line, err := r.ReadBytes('\n')
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			lines = append(lines, line)
+		}
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return lines, nil
+			}
+			return nil, err
+		}
+	}
+}
+
+// rewrite replaces the file with the lines atomically.
+func (f *File) rewrite(lines [][]byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), filepath.Base(f.path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	for _, line := range lines {
+		if _, err := tmp.Write(append(line, '\n')); err != nil {
+			tmp.Close()
+			return fmt.Errorf("failed to write temporary file: %w", err)
+		}
+	}
+	if err := tmp.Chmod(fileFilePerm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), f.path)
+}
+
+// Close closes the file dead letter queue.
+func (f *File) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,74 @@
+package deadletter
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+)
+
+func TestFile(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "nested", "dead_letter.jsonl")
+	f, err := NewFile(path)
+	require.NoError(t, err)
+
+	// replay before put replays nothing
+	n, err := f.Replay(ctx, func(ctx context.Context, entry Entry) error {
+		t.Fatal("unexpected entry")
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	entries := make([]Entry, 3)
+	for i, token := range []string{"token-1", "token-2", "token-3"} {
+		entries[i] = Entry{
+			Event:      bson.Raw(bsoncore.NewDocumentBuilder().AppendString("_id", token).Build()),
+			Token:      token,
+			Error:      "failed",
+			Attempts:   3,
+			Database:   "db",
+			Collection: "col",
+			CreatedAt:  createdAt,
+		}
+		require.NoError(t, f.Put(ctx, entries[i]))
+	}
+
+	// entries for which fn fails are kept
+	var got []Entry
+	n, err = f.Replay(ctx, func(ctx context.Context, entry Entry) error {
+		got = append(got, entry)
+		if entry.Token == "token-2" {
+			return assert.AnError
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, entries, got)
+
+	got = nil
+	n, err = f.Replay(ctx, func(ctx context.Context, entry Entry) error {
+		got = append(got, entry)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, entries[1:2], got)
+
+	// no temporary files are left behind
+	files, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Len(t, files, 1)
+	require.NoError(t, f.Close(ctx))
+}
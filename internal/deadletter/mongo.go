@@ -0,0 +1,77 @@
+package deadletter
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/ucpr/mongo-streamer/pkg/log"
+)
+
+// Mongo stores dead-lettered events to a MongoDB collection.
+type Mongo struct {
+	col *mongo.Collection
+}
+
+var (
+	_ Sink   = (*Mongo)(nil)
+	_ Source = (*Mongo)(nil)
+)
+
+// mongoEntry is the document stored in the dead letter collection.
+type mongoEntry struct {
+	ID    primitive.ObjectID `bson:"_id,omitempty"`
+	Entry `bson:",inline"`
+}
+
+// NewMongo creates a new MongoDB dead letter queue that stores entries to col.
+func NewMongo(col *mongo.Collection) *Mongo {
+	return &Mongo{
+		col: col,
+	}
+}
+
+// Put inserts the entry to the collection.
+func (m *Mongo) Put(ctx context.Context, entry Entry) error {
+	if _, err := m.col.InsertOne(ctx, mongoEntry{Entry: entry}); err != nil {
+		return fmt.Errorf("failed to insert dead letter entry: %w", err)
+	}
+	return nil
+}
+
+// Replay calls fn for each entry in the collection in the order they were
+// dead-lettered. The entries for which fn succeeds are deleted.
+func (m *Mongo) Replay(ctx context.Context, fn ReplayFunc) (int, error) {
+	cur, err := m.col.Find(ctx, bson.D{}, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}))
+	if err != nil {
+		return 0, fmt.Errorf("failed to find dead letter entries: %w", err)
+	}
+	defer cur.Close(ctx)
+
+	replayed := 0
+	for cur.Next(ctx) {
+		var entry mongoEntry
+		if err := cur.Decode(&entry); err != nil {
+			log.Error("Failed to decode dead letter entry", log.Ferror(err))
+			continue
+		}
+		if err := fn(ctx, entry.Entry); err != nil {
+			log.Error("Failed to replay dead letter entry", log.Ferror(err), log.Fstring("token", entry.Token))
+			continue
+		}
+		if _, err := m.col.DeleteOne(ctx, bson.D{{Key: "_id", Value: entry.ID}}); err != nil {
+			return replayed, fmt.Errorf("failed to delete dead letter entry: %w", err)
+		}
+		replayed++
+	}
+	return replayed, cur.Err()
+}
+
+// Close does nothing, the client is owned by the caller.
+func (m *Mongo) Close(ctx context.Context) error {
+	return nil
+}
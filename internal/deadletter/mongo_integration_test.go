@@ -0,0 +1,65 @@
+//go:build integration
+
+package deadletter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+)
+
+const (
+	testMongoURI        = "mongodb://localhost:27017/?directConnection=true"
+	testMongoDatabase   = "test"
+	testMongoCollection = "mongo_streamer_dead_letters"
+)
+
+//nolint:paralleltest
+func TestMongo(t *testing.T) {
+	ctx := context.Background()
+
+	cli, err := mongo.Connect(ctx, options.Client().ApplyURI(testMongoURI))
+	require.NoError(t, err)
+	defer cli.Disconnect(ctx)
+	col := cli.Database(testMongoDatabase).Collection(testMongoCollection)
+	require.NoError(t, col.Drop(ctx))
+
+	dl := NewMongo(col)
+	createdAt := time.Now().UTC().Truncate(time.Millisecond)
+	for i, token := range []string{"token-1", "token-2"} {
+		require.NoError(t, dl.Put(ctx, Entry{
+			Event:      bson.Raw(bsoncore.NewDocumentBuilder().AppendString("_id", token).Build()),
+			Token:      token,
+			Error:      "failed",
+			Attempts:   1,
+			Database:   "test",
+			Collection: "tweets",
+			CreatedAt:  createdAt.Add(time.Duration(i) * time.Millisecond),
+		}))
+	}
+
+	// entries are replayed in order and failed entries are kept
+	var tokens []string
+	n, err := dl.Replay(ctx, func(ctx context.Context, entry Entry) error {
+		tokens = append(tokens, entry.Token)
+		if entry.Token == "token-2" {
+			return assert.AnError
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, []string{"token-1", "token-2"}, tokens)
+
+	count, err := col.CountDocuments(ctx, bson.D{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+	require.NoError(t, dl.Close(ctx))
+}
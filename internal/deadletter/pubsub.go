@@ -0,0 +1,209 @@
+package deadletter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	gpubsub "cloud.google.com/go/pubsub"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/ucpr/mongo-streamer/internal/pubsub"
+)
+
+const (
+	attrStream     = "stream"
+	attrError      = "error"
+	attrAttempts   = "attempts"
+	attrToken      = "token"
+	attrDatabase   = "database"
+	attrCollection = "collection"
+	attrCreatedAt  = "created_at"
+
+	// maxAttributeValueSize is the maximum size of an attribute value of Pub/Sub.
+	maxAttributeValueSize = 1024
+)
+
+// PubSub stores dead-lettered events to a Pub/Sub topic.
+// The raw BSON event is the data of the message and the metadata are attributes.
+type PubSub struct {
+	pub pubsub.Publisher
+}
+
+var _ Sink = (*PubSub)(nil)
+
+// NewPubSub creates a new Pub/Sub dead letter queue that publishes entries with pub.
+// The dead letter queue takes ownership of pub and closes it on Close.
+func NewPubSub(pub pubsub.Publisher) *PubSub {
+	return &PubSub{
+		pub: pub,
+	}
+}
+
+// Put publishes the entry and waits until it is acknowledged.
+// Attribute values longer than the limit of Pub/Sub, e.g. long errors, are truncated.
+func (p *PubSub) Put(ctx context.Context, entry Entry) error {
+	attrs := map[string]string{
+		attrStream:     entry.Stream,
+		attrError:      entry.Error,
+		attrAttempts:   strconv.Itoa(entry.Attempts),
+		attrToken:      entry.Token,
+		attrDatabase:   entry.Database,
+		attrCollection: entry.Collection,
+		attrCreatedAt:  entry.CreatedAt.Format(time.RFC3339Nano),
+	}
+	for k, v := range attrs {
+		attrs[k] = truncateAttribute(v)
+	}
+	res := p.pub.AsyncPublish(ctx, pubsub.Message{
+		Data:       entry.Event,
+		Attributes: attrs,
+	})
+	if _, err := res.Get(ctx); err != nil {
+		return fmt.Errorf("failed to publish dead letter entry: %w", err)
+	}
+	return nil
+}
+
+// truncateAttribute truncates the value to the maximum size of an attribute value,
+// without splitting a UTF-8 character.
+func truncateAttribute(v string) string {
+	if len(v) <= maxAttributeValueSize {
+		return v
+	}
+	i := maxAttributeValueSize
+	for i > 0 && !utf8.RuneStart(v[i]) {
+		i--
+	}
+	return v[:i]
+}
+
+// Close closes the publisher.
+func (p *PubSub) Close(ctx context.Context) error {
+	return p.pub.Close()
+}
+
+// minReplayIdleTimeout is the minimum idle timeout of PubSubSource, which checks
+// the idle time at half of the timeout.
+const minReplayIdleTimeout = time.Millisecond
+
+// PubSubSource reads dead-lettered events from a Pub/Sub subscription.
+type PubSubSource struct {
+	cli  *gpubsub.Client
+	sub  *gpubsub.Subscription
+	idle time.Duration
+}
+
+var _ Source = (*PubSubSource)(nil)
+
+// NewPubSubSource creates a new source that receives dead-lettered events from
+// the subscription. Replay returns when no message is received for idle, which
+// must be at least minReplayIdleTimeout.
+func NewPubSubSource(ctx context.Context, projectID, subscriptionID string, idle time.Duration) (*PubSubSource, error) {
+	if idle < minReplayIdleTimeout {
+		return nil, fmt.Errorf("replay idle timeout must be at least %s: %s", minReplayIdleTimeout, idle)
+	}
+	cli, err := gpubsub.NewClient(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	sub := cli.Subscription(subscriptionID)
+	// replay entries one by one to keep the order of the events
+	sub.ReceiveSettings.MaxOutstandingMessages = 1
+	return &PubSubSource{
+		cli:  cli,
+		sub:  sub,
+		idle: idle,
+	}, nil
+}
+
+// Replay receives entries from the subscription and acknowledges the entries for
+// which fn succeeds. Since a failed entry would be redelivered, Replay stops at
+// the first entry for which fn fails.
+func (p *PubSubSource) Replay(ctx context.Context, fn ReplayFunc) (int, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		replayed atomic.Int64
+		last     atomic.Int64
+		once     sync.Once
+		rerr     error
+	)
+	last.Store(time.Now().UnixNano())
+	go func() {
+		ticker := time.NewTicker(p.idle / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if time.Since(time.Unix(0, last.Load())) >= p.idle {
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+
+	err := p.sub.Receive(ctx, func(ctx context.Context, msg *gpubsub.Message) {
+		last.Store(time.Now().UnixNano())
+		entry, err := entryFromMessage(msg)
+		if err == nil {
+			err = fn(ctx, entry)
+		}
+		if err != nil {
+			msg.Nack()
+			once.Do(func() {
+				rerr = fmt.Errorf("failed to replay dead letter entry %s: %w", msg.ID, err)
+				cancel()
+			})
+			return
+		}
+		msg.Ack()
+		replayed.Add(1)
+	})
+	if err != nil && !errors.Is(err, context.Canceled) {
+		return int(replayed.Load()), err
+	}
+	return int(replayed.Load()), rerr
+}
+
+// Close closes the Pub/Sub client.
+func (p *PubSubSource) Close(ctx context.Context) error {
+	return p.cli.Close()
+}
+
+// entryFromMessage converts the message published by PubSub to an entry.
+func entryFromMessage(msg *gpubsub.Message) (Entry, error) {
+	entry := Entry{
+		Stream:     msg.Attributes[attrStream],
+		Event:      bson.Raw(msg.Data),
+		Error:      msg.Attributes[attrError],
+		Token:      msg.Attributes[attrToken],
+		Database:   msg.Attributes[attrDatabase],
+		Collection: msg.Attributes[attrCollection],
+	}
+	if v, ok := msg.Attributes[attrAttempts]; ok {
+		attempts, err := strconv.Atoi(v)
+		if err != nil {
+			return Entry{}, fmt.Errorf("invalid attempts attribute: %w", err)
+		}
+		entry.Attempts = attempts
+	}
+	if v, ok := msg.Attributes[attrCreatedAt]; ok {
+		createdAt, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return Entry{}, fmt.Errorf("invalid created_at attribute: %w", err)
+		}
+		entry.CreatedAt = createdAt
+	}
+	return entry, nil
+}
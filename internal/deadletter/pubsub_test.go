@@ -0,0 +1,91 @@
+package deadletter
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/ucpr/mongo-streamer/internal/pubsub"
+	"github.com/ucpr/mongo-streamer/internal/pubsub/mock"
+)
+
+func TestNewPubSubSource_InvalidIdleTimeout(t *testing.T) {
+	t.Parallel()
+
+	patterns := []struct {
+		name string
+		idle time.Duration
+	}{
+		{name: "zero", idle: 0},
+		{name: "negative", idle: -time.Second},
+		{name: "too short", idle: time.Nanosecond},
+	}
+
+	for _, tt := range patterns {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := NewPubSubSource(context.Background(), "project", "subscription", tt.idle)
+			assert.Error(t, err)
+			assert.Nil(t, got)
+		})
+	}
+}
+
+func TestPubSub_Put_LongError(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	mp := mock.NewMockPublisher(ctrl)
+	mpr := mock.NewMockPublishResult(ctrl)
+	// the attributes are truncated to the limit of Pub/Sub
+	mp.EXPECT().AsyncPublish(ctx, gomock.Any()).DoAndReturn(func(_ context.Context, msg pubsub.Message) pubsub.PublishResult {
+		for k, v := range msg.Attributes {
+			assert.LessOrEqual(t, len(v), maxAttributeValueSize, k)
+			assert.True(t, utf8.ValidString(v), k)
+		}
+		assert.True(t, strings.HasPrefix(msg.Attributes[attrError], "validation failed: "))
+		assert.Equal(t, "token", msg.Attributes[attrToken])
+		return mpr
+	}).Times(1)
+	mpr.EXPECT().Get(ctx).Return("id", nil).Times(1)
+
+	dl := NewPubSub(mp)
+	require.NoError(t, dl.Put(ctx, Entry{
+		Event: []byte{},
+		Token: "token",
+		Error: "validation failed: " + strings.Repeat("é", maxAttributeValueSize),
+	}))
+}
+
+func TestTruncateAttribute(t *testing.T) {
+	t.Parallel()
+
+	patterns := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "short", in: "error", want: "error"},
+		{name: "limit", in: strings.Repeat("a", maxAttributeValueSize), want: strings.Repeat("a", maxAttributeValueSize)},
+		{name: "long", in: strings.Repeat("a", maxAttributeValueSize+1), want: strings.Repeat("a", maxAttributeValueSize)},
+		{name: "multibyte character at the limit", in: "a" + strings.Repeat("é", maxAttributeValueSize), want: "a" + strings.Repeat("é", (maxAttributeValueSize-1)/2)},
+	}
+
+	for _, tt := range patterns {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.want, truncateAttribute(tt.in))
+		})
+	}
+}
@@ -0,0 +1,117 @@
+//go:generate $GOBIN/mockgen -package=mock -source=$GOFILE -destination=./mock/$GOFILE
+
+package http
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/ucpr/mongo-streamer/internal/mongo"
+	"github.com/ucpr/mongo-streamer/pkg/log"
+)
+
+// maxAdminRequestBytes is the maximum size of the body of an admin request.
+const maxAdminRequestBytes = 1 << 20
+
+// Resnapshotter starts re-snapshots of the streams and reports their progress.
+type Resnapshotter interface {
+	Start(ctx context.Context, req mongo.ResnapshotRequest) (mongo.ResnapshotStatus, error)
+	Status(id string) (mongo.ResnapshotStatus, bool)
+}
+
+// ResnapshotRequest is the body of POST /admin/resnapshots.
+type ResnapshotRequest struct {
+	Stream     string `json:"stream"`
+	Database   string `json:"database,omitempty"`
+	Collection string `json:"collection,omitempty"`
+	// Filter is the query filter in Extended JSON, all documents are sent if it is empty.
+	Filter json.RawMessage `json:"filter,omitempty"`
+}
+
+// errorResponse is the body of an error response of the admin endpoints.
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// resnapshotHandler serves the admin endpoints of re-snapshots.
+type resnapshotHandler struct {
+	r     Resnapshotter
+	token string
+}
+
+// register registers the endpoints to the mux.
+func (h *resnapshotHandler) register(mux *http.ServeMux) {
+	mux.Handle("POST /admin/resnapshots", h.authorize(h.start))
+	mux.Handle("GET /admin/resnapshots/{id}", h.authorize(h.status))
+}
+
+// authorize requires the bearer token. All requests are rejected if no token is configured.
+func (h *resnapshotHandler) authorize(next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || h.token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) != 1 {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
+			return
+		}
+		next(w, r)
+	})
+}
+
+func (h *resnapshotHandler) start(w http.ResponseWriter, r *http.Request) {
+	var body ResnapshotRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAdminRequestBytes)).Decode(&body); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid request: " + err.Error()})
+		return
+	}
+	req := mongo.ResnapshotRequest{
+		Stream:     body.Stream,
+		Database:   body.Database,
+		Collection: body.Collection,
+	}
+	if len(body.Filter) > 0 && string(body.Filter) != "null" {
+		if err := bson.UnmarshalExtJSON(body.Filter, false, &req.Filter); err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid filter: " + err.Error()})
+			return
+		}
+	}
+
+	status, err := h.r.Start(r.Context(), req)
+	switch {
+	case err == nil:
+		w.Header().Set("Location", "/admin/resnapshots/"+status.ID)
+		writeJSON(w, http.StatusAccepted, status)
+	case errors.Is(err, mongo.ErrStreamNotFound):
+		writeJSON(w, http.StatusNotFound, errorResponse{Error: err.Error()})
+	case errors.Is(err, mongo.ErrNamespaceNotWatched):
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+	case errors.Is(err, mongo.ErrResnapshotRunning):
+		writeJSON(w, http.StatusConflict, errorResponse{Error: err.Error()})
+	default:
+		log.Error("Failed to start re-snapshot", log.Ferror(err))
+		writeJSON(w, http.StatusInternalServerError, errorResponse{Error: err.Error()})
+	}
+}
+
+func (h *resnapshotHandler) status(w http.ResponseWriter, r *http.Request) {
+	status, ok := h.r.Status(r.PathValue("id"))
+	if !ok {
+		writeJSON(w, http.StatusNotFound, errorResponse{Error: "re-snapshot is not found"})
+		return
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+// writeJSON writes v as the JSON body of the response.
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Error("Failed to write response", log.Ferror(err))
+	}
+}
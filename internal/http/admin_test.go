@@ -0,0 +1,203 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.uber.org/mock/gomock"
+
+	"github.com/ucpr/mongo-streamer/internal/config"
+	"github.com/ucpr/mongo-streamer/internal/http/mock"
+	"github.com/ucpr/mongo-streamer/internal/mongo"
+)
+
+func TestResnapshotHandler_start(t *testing.T) {
+	t.Parallel()
+
+	filter, err := bson.Marshal(bson.D{{Key: "status", Value: "active"}})
+	require.NoError(t, err)
+
+	patterns := []struct {
+		name     string
+		body     string
+		token    string
+		setup    func(m *mock.MockResnapshotter)
+		wantCode int
+		wantID   string
+	}{
+		{
+			name: "success",
+			body: `{"stream": "users", "database": "app", "collection": "users", "filter": {"status": "active"}}`,
+			setup: func(m *mock.MockResnapshotter) {
+				m.EXPECT().Start(gomock.Any(), mongo.ResnapshotRequest{
+					Stream:     "users",
+					Database:   "app",
+					Collection: "users",
+					Filter:     filter,
+				}).Return(mongo.ResnapshotStatus{ID: "1", State: mongo.ResnapshotRunning}, nil)
+			},
+			wantCode: http.StatusAccepted,
+			wantID:   "1",
+		},
+		{
+			name: "without filter",
+			body: `{"stream": "users"}`,
+			setup: func(m *mock.MockResnapshotter) {
+				m.EXPECT().Start(gomock.Any(), mongo.ResnapshotRequest{Stream: "users"}).
+					Return(mongo.ResnapshotStatus{ID: "1", State: mongo.ResnapshotRunning}, nil)
+			},
+			wantCode: http.StatusAccepted,
+			wantID:   "1",
+		},
+		{
+			name:     "invalid body",
+			body:     `{"stream": `,
+			setup:    func(m *mock.MockResnapshotter) {},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "invalid filter",
+			body:     `{"stream": "users", "filter": [1]}`,
+			setup:    func(m *mock.MockResnapshotter) {},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name: "unknown stream",
+			body: `{"stream": "unknown"}`,
+			setup: func(m *mock.MockResnapshotter) {
+				m.EXPECT().Start(gomock.Any(), gomock.Any()).Return(mongo.ResnapshotStatus{}, mongo.ErrStreamNotFound)
+			},
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name: "namespace is not watched",
+			body: `{"stream": "users", "collection": "logs"}`,
+			setup: func(m *mock.MockResnapshotter) {
+				m.EXPECT().Start(gomock.Any(), gomock.Any()).Return(mongo.ResnapshotStatus{}, mongo.ErrNamespaceNotWatched)
+			},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name: "running",
+			body: `{"stream": "users"}`,
+			setup: func(m *mock.MockResnapshotter) {
+				m.EXPECT().Start(gomock.Any(), gomock.Any()).Return(mongo.ResnapshotStatus{}, mongo.ErrResnapshotRunning)
+			},
+			wantCode: http.StatusConflict,
+		},
+		{
+			name:     "unauthorized",
+			body:     `{"stream": "users"}`,
+			token:    "invalid",
+			setup:    func(m *mock.MockResnapshotter) {},
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:  "authorized",
+			body:  `{"stream": "users"}`,
+			token: "secret",
+			setup: func(m *mock.MockResnapshotter) {
+				m.EXPECT().Start(gomock.Any(), gomock.Any()).Return(mongo.ResnapshotStatus{ID: "1"}, nil)
+			},
+			wantCode: http.StatusAccepted,
+			wantID:   "1",
+		},
+	}
+
+	for _, tt := range patterns {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			m := mock.NewMockResnapshotter(ctrl)
+			tt.setup(m)
+
+			srv := NewServer(&config.Metrics{}, &config.Admin{Enabled: true, Token: "secret"})
+			srv.HandleResnapshots(m)
+			req := httptest.NewRequest(http.MethodPost, "/admin/resnapshots", strings.NewReader(tt.body))
+			token := tt.token
+			if token == "" {
+				token = "secret"
+			}
+			req.Header.Set("Authorization", "Bearer "+token)
+			rec := httptest.NewRecorder()
+			srv.mux.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantCode, rec.Code)
+			assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+			if tt.wantID != "" {
+				var got mongo.ResnapshotStatus
+				require.NoError(t, json.NewDecoder(rec.Body).Decode(&got))
+				assert.Equal(t, tt.wantID, got.ID)
+				assert.Equal(t, "/admin/resnapshots/"+tt.wantID, rec.Header().Get("Location"))
+			}
+		})
+	}
+}
+
+func TestResnapshotHandler_status(t *testing.T) {
+	t.Parallel()
+
+	patterns := []struct {
+		name     string
+		id       string
+		setup    func(m *mock.MockResnapshotter)
+		wantCode int
+	}{
+		{
+			name: "found",
+			id:   "1",
+			setup: func(m *mock.MockResnapshotter) {
+				m.EXPECT().Status("1").Return(mongo.ResnapshotStatus{ID: "1", Total: 2, Sent: 1}, true)
+			},
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "not found",
+			id:   "2",
+			setup: func(m *mock.MockResnapshotter) {
+				m.EXPECT().Status("2").Return(mongo.ResnapshotStatus{}, false)
+			},
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range patterns {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			m := mock.NewMockResnapshotter(ctrl)
+			tt.setup(m)
+
+			srv := NewServer(&config.Metrics{}, &config.Admin{Enabled: true, Token: "secret"})
+			srv.HandleResnapshots(m)
+			req := httptest.NewRequest(http.MethodGet, "/admin/resnapshots/"+tt.id, nil)
+			req.Header.Set("Authorization", "Bearer secret")
+			rec := httptest.NewRecorder()
+			srv.mux.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantCode, rec.Code)
+		})
+	}
+}
+
+func TestServer_HandleResnapshots_Disabled(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	srv := NewServer(&config.Metrics{}, &config.Admin{})
+	srv.HandleResnapshots(mock.NewMockResnapshotter(ctrl))
+
+	rec := httptest.NewRecorder()
+	srv.mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/admin/resnapshots", strings.NewReader(`{}`)))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -22,10 +22,12 @@ const (
 )
 
 type Server struct {
-	srv *http.Server
+	srv   *http.Server
+	mux   *http.ServeMux
+	admin *config.Admin
 }
 
-func NewServer(cfg *config.Metrics) *Server {
+func NewServer(cfg *config.Metrics, admin *config.Admin) *Server {
 	mux := http.NewServeMux()
 	mux.Handle("/health", http.HandlerFunc(health))
 	metric.Register(mux)
@@ -38,10 +40,22 @@ func NewServer(cfg *config.Metrics) *Server {
 	}
 
 	return &Server{
-		srv: srv,
+		srv:   srv,
+		mux:   mux,
+		admin: admin,
 	}
 }
 
+// HandleResnapshots serves the admin endpoints of re-snapshots if they are enabled.
+// It must be called before Serve.
+func (s *Server) HandleResnapshots(r Resnapshotter) {
+	if !s.admin.Enabled {
+		return
+	}
+	h := &resnapshotHandler{r: r, token: s.admin.Token}
+	h.register(s.mux)
+}
+
 // Serve starts the HTTP server.
 func (s *Server) Serve() error {
 	return s.srv.ListenAndServe()
@@ -13,6 +13,6 @@ func TestNewServer(t *testing.T) {
 
 	cfg := &config.Metrics{}
 
-	got := NewServer(cfg)
+	got := NewServer(cfg, &config.Admin{})
 	assert.NotNil(t, got)
 }
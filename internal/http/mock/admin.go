@@ -0,0 +1,71 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: admin.go
+//
+// Generated by this command:
+//
+//	mockgen -package=mock -source=admin.go -destination=./mock/admin.go
+//
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	reflect "reflect"
+
+	mongo "github.com/ucpr/mongo-streamer/internal/mongo"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockResnapshotter is a mock of Resnapshotter interface.
+type MockResnapshotter struct {
+	ctrl     *gomock.Controller
+	recorder *MockResnapshotterMockRecorder
+}
+
+// MockResnapshotterMockRecorder is the mock recorder for MockResnapshotter.
+type MockResnapshotterMockRecorder struct {
+	mock *MockResnapshotter
+}
+
+// NewMockResnapshotter creates a new mock instance.
+func NewMockResnapshotter(ctrl *gomock.Controller) *MockResnapshotter {
+	mock := &MockResnapshotter{ctrl: ctrl}
+	mock.recorder = &MockResnapshotterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockResnapshotter) EXPECT() *MockResnapshotterMockRecorder {
+	return m.recorder
+}
+
+// Start mocks base method.
+func (m *MockResnapshotter) Start(ctx context.Context, req mongo.ResnapshotRequest) (mongo.ResnapshotStatus, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Start", ctx, req)
+	ret0, _ := ret[0].(mongo.ResnapshotStatus)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Start indicates an expected call of Start.
+func (mr *MockResnapshotterMockRecorder) Start(ctx, req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Start", reflect.TypeOf((*MockResnapshotter)(nil).Start), ctx, req)
+}
+
+// Status mocks base method.
+func (m *MockResnapshotter) Status(id string) (mongo.ResnapshotStatus, bool) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Status", id)
+	ret0, _ := ret[0].(mongo.ResnapshotStatus)
+	ret1, _ := ret[1].(bool)
+	return ret0, ret1
+}
+
+// Status indicates an expected call of Status.
+func (mr *MockResnapshotterMockRecorder) Status(id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Status", reflect.TypeOf((*MockResnapshotter)(nil).Status), id)
+}
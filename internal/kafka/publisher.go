@@ -0,0 +1,148 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+
+	"github.com/ucpr/mongo-streamer/internal/config"
+	"github.com/ucpr/mongo-streamer/internal/pubsub"
+)
+
+const (
+	publisherLinger = 100 * time.Millisecond
+	closeTimeout    = 5 * time.Second
+)
+
+// Publisher is a publisher for Apache Kafka.
+type Publisher struct {
+	cli *kgo.Client
+}
+
+// Ensure that Publisher implements pubsub.Publisher.
+//
+//nolint:gochecknoglobals
+var _ pubsub.Publisher = (*Publisher)(nil)
+
+// NewPublisher creates a new publisher.
+func NewPublisher(ctx context.Context, cfg *config.Kafka) (*Publisher, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	cli, err := kgo.NewClient(
+		kgo.SeedBrokers(cfg.Brokers...),
+		kgo.DefaultProduceTopic(cfg.Topic),
+		kgo.ClientID(cfg.ClientID),
+		// Records with the same key are always assigned to the same partition,
+		// so that the order of the events for a document is preserved.
+		kgo.RecordPartitioner(kgo.StickyKeyPartitioner(nil)),
+		kgo.ProducerLinger(publisherLinger),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kafka client: %w", err)
+	}
+	if err := cli.Ping(ctx); err != nil {
+		cli.Close()
+		return nil, fmt.Errorf("failed to ping kafka brokers: %w", err)
+	}
+
+	return &Publisher{
+		cli: cli,
+	}, nil
+}
+
+// AsyncPublish publishes a message to the topic.
+// The key of the message is used as the record key.
+func (p *Publisher) AsyncPublish(ctx context.Context, msg pubsub.Message) pubsub.PublishResult {
+	record := &kgo.Record{
+		Value:   msg.Data,
+		Headers: headers(msg.Attributes),
+	}
+	if msg.Key != "" {
+		record.Key = []byte(msg.Key)
+	}
+
+	result := &PublishResult{
+		ready: make(chan struct{}),
+	}
+	p.cli.Produce(ctx, record, func(r *kgo.Record, err error) {
+		result.partition = r.Partition
+		result.offset = r.Offset
+		result.err = err
+		close(result.ready)
+	})
+	return result
+}
+
+// Close flushes the buffered records and closes the publisher.
+func (p *Publisher) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), closeTimeout)
+	defer cancel()
+
+	defer p.cli.Close()
+	return p.cli.Flush(ctx)
+}
+
+// headers converts the attributes of the message to record headers.
+func headers(attrs map[string]string) []kgo.RecordHeader {
+	if len(attrs) == 0 {
+		return nil
+	}
+
+	hs := make([]kgo.RecordHeader, 0, len(attrs))
+	for k, v := range attrs {
+		hs = append(hs, kgo.RecordHeader{Key: k, Value: []byte(v)})
+	}
+	// sort headers to make the record deterministic
+	sort.Slice(hs, func(i, j int) bool {
+		return hs[i].Key < hs[j].Key
+	})
+	return hs
+}
+
+// PublishResult is the result of publishing a record to Kafka.
+type PublishResult struct {
+	ready     chan struct{}
+	partition int32
+	offset    int64
+	err       error
+}
+
+// Ensure that PublishResult implements pubsub.PublishResult.
+//
+//nolint:gochecknoglobals
+var _ pubsub.PublishResult = (*PublishResult)(nil)
+
+// Ready returns a channel that is closed when the record is acknowledged.
+func (r *PublishResult) Ready() <-chan struct{} {
+	return r.ready
+}
+
+// Get blocks until the record is acknowledged and returns the "<partition>/<offset>"
+// of the record as the server id.
+func (r *PublishResult) Get(ctx context.Context) (string, error) {
+	select {
+	case <-r.ready:
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+	if r.err != nil {
+		return "", r.err
+	}
+	return fmt.Sprintf("%d/%d", r.partition, r.offset), nil
+}
+
+// Partition returns the partition the record was produced to.
+// It is only valid after the result is ready.
+func (r *PublishResult) Partition() int32 {
+	return r.partition
+}
+
+// Offset returns the offset of the record in the partition.
+// It is only valid after the result is ready.
+func (r *PublishResult) Offset() int64 {
+	return r.offset
+}
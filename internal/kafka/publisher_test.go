@@ -0,0 +1,209 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twmb/franz-go/pkg/kfake"
+	"github.com/twmb/franz-go/pkg/kgo"
+
+	"github.com/ucpr/mongo-streamer/internal/config"
+	"github.com/ucpr/mongo-streamer/internal/pubsub"
+)
+
+const (
+	testTopic      = "topic"
+	testPartitions = 8
+)
+
+func newTestCluster(t *testing.T) *kfake.Cluster {
+	t.Helper()
+
+	cluster, err := kfake.NewCluster(
+		kfake.NumBrokers(1),
+		kfake.SeedTopics(testPartitions, testTopic),
+	)
+	require.NoError(t, err)
+	t.Cleanup(cluster.Close)
+	return cluster
+}
+
+func TestNewPublisher_InvalidConfig(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	patterns := []struct {
+		name string
+		cfg  *config.Kafka
+		err  error
+	}{
+		{
+			name: "no brokers",
+			cfg:  &config.Kafka{Topic: testTopic},
+			err:  config.ErrMissingKafkaBrokers,
+		},
+		{
+			name: "no topic",
+			cfg:  &config.Kafka{Brokers: []string{"localhost:9092"}},
+			err:  config.ErrMissingKafkaTopic,
+		},
+	}
+
+	for _, tt := range patterns {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := NewPublisher(ctx, tt.cfg)
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+}
+
+func TestPublisher_AsyncPublish(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	cluster := newTestCluster(t)
+	publisher, err := NewPublisher(ctx, &config.Kafka{
+		Brokers:  cluster.ListenAddrs(),
+		Topic:    testTopic,
+		ClientID: "test",
+	})
+	require.NoError(t, err)
+	defer publisher.Close()
+
+	res := publisher.AsyncPublish(ctx, pubsub.Message{
+		Data: []byte("hoge"),
+		Attributes: map[string]string{
+			"foo": "bar",
+		},
+		Key: "document-key",
+	})
+	select {
+	case <-res.Ready():
+	case <-time.After(10 * time.Second):
+		t.Fatal("timeout")
+	}
+	id, err := res.Get(ctx)
+	require.NoError(t, err)
+
+	kres, ok := res.(*PublishResult)
+	require.True(t, ok)
+	assert.Equal(t, fmt.Sprintf("%d/%d", kres.Partition(), kres.Offset()), id)
+
+	consumer, err := kgo.NewClient(
+		kgo.SeedBrokers(cluster.ListenAddrs()...),
+		kgo.ConsumeTopics(testTopic),
+	)
+	require.NoError(t, err)
+	defer consumer.Close()
+
+	tctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	fetches := consumer.PollRecords(tctx, 1)
+	require.NoError(t, fetches.Err())
+	records := fetches.Records()
+	require.Len(t, records, 1)
+	assert.Equal(t, []byte("hoge"), records[0].Value)
+	assert.Equal(t, []byte("document-key"), records[0].Key)
+	assert.Equal(t, []kgo.RecordHeader{{Key: "foo", Value: []byte("bar")}}, records[0].Headers)
+	assert.Equal(t, kres.Partition(), records[0].Partition)
+	assert.Equal(t, kres.Offset(), records[0].Offset)
+}
+
+func TestPublisher_AsyncPublish_KeyOrdering(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	cluster := newTestCluster(t)
+	publisher, err := NewPublisher(ctx, &config.Kafka{
+		Brokers:  cluster.ListenAddrs(),
+		Topic:    testTopic,
+		ClientID: "test",
+	})
+	require.NoError(t, err)
+	defer publisher.Close()
+
+	const events = 20
+	results := make(map[string][]*PublishResult)
+	for i := 0; i < events; i++ {
+		key := fmt.Sprintf("document-%d", i%4)
+		res := publisher.AsyncPublish(ctx, pubsub.Message{
+			Data: []byte(fmt.Sprintf("event-%d", i)),
+			Key:  key,
+		})
+		results[key] = append(results[key], res.(*PublishResult))
+	}
+
+	for key, rs := range results {
+		var prev int64 = -1
+		for _, res := range rs {
+			_, err := res.Get(ctx)
+			require.NoError(t, err)
+			// all events for the same document must be in the same partition and in order
+			assert.Equal(t, rs[0].Partition(), res.Partition(), key)
+			assert.Greater(t, res.Offset(), prev, key)
+			prev = res.Offset()
+		}
+	}
+}
+
+func TestPublishResult_Get(t *testing.T) {
+	t.Parallel()
+
+	patterns := []struct {
+		name     string
+		setup    func(r *PublishResult)
+		canceled bool
+		want     string
+		err      error
+	}{
+		{
+			name: "success",
+			setup: func(r *PublishResult) {
+				r.partition = 3
+				r.offset = 42
+				close(r.ready)
+			},
+			want: "3/42",
+		},
+		{
+			name: "failed to produce",
+			setup: func(r *PublishResult) {
+				r.err = assert.AnError
+				close(r.ready)
+			},
+			err: assert.AnError,
+		},
+		{
+			name:     "context canceled",
+			setup:    func(r *PublishResult) {},
+			canceled: true,
+			err:      context.Canceled,
+		},
+	}
+
+	for _, tt := range patterns {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.canceled {
+				cancel()
+			}
+
+			r := &PublishResult{ready: make(chan struct{})}
+			tt.setup(r)
+			got, err := r.Get(ctx)
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -10,8 +10,11 @@ const (
 	// subSystem is the subSystem for the metrics.
 	subSystem = "mongodb"
 
+	lStream     = "stream"
 	lDatabase   = "database"
 	lCollection = "collection"
+	lReason     = "reason"
+	lPolicy     = "policy"
 )
 
 var (
@@ -22,7 +25,7 @@ var (
 			Subsystem: subSystem,
 			Name:      "change_stream_received_total",
 			Help:      "Total number of change stream received",
-		}, []string{lDatabase, lCollection},
+		}, []string{lStream, lDatabase, lCollection},
 	)
 
 	// receivedBytesTotal is the total number of change stream received bytes.
@@ -32,7 +35,7 @@ var (
 			Subsystem: subSystem,
 			Name:      "change_stream_received_bytes_total",
 			Help:      "Total number of change stream received bytes",
-		}, []string{lDatabase, lCollection},
+		}, []string{lStream, lDatabase, lCollection},
 	)
 
 	// successHandleEventTotal is the total number of change stream handle event success.
@@ -42,7 +45,57 @@ var (
 			Subsystem: subSystem,
 			Name:      "change_stream_handle_event_success_total",
 			Help:      "Total number of change stream handle event success",
-		}, []string{lDatabase, lCollection},
+		}, []string{lStream, lDatabase, lCollection},
+	)
+
+	// deadLetteredTotal is the total number of change stream events stored to the dead letter queue.
+	deadLetteredTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subSystem,
+			Name:      "change_stream_dead_lettered_total",
+			Help:      "Total number of change stream events stored to the dead letter queue",
+		}, []string{lStream, lDatabase, lCollection},
+	)
+
+	// restartsTotal is the total number of change stream restarts.
+	restartsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subSystem,
+			Name:      "change_stream_restarts_total",
+			Help:      "Total number of change stream restarts",
+		}, []string{lStream, lReason},
+	)
+
+	// terminationsTotal is the total number of change stream terminations.
+	terminationsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subSystem,
+			Name:      "change_stream_terminations_total",
+			Help:      "Total number of change stream terminations",
+		}, []string{lStream, lReason},
+	)
+
+	// historyLostTotal is the total number of change streams whose resume token is no longer in the oplog.
+	historyLostTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subSystem,
+			Name:      "change_stream_history_lost_total",
+			Help:      "Total number of change streams whose resume token is no longer in the oplog",
+		}, []string{lStream, lPolicy},
+	)
+
+	// snapshotDocumentsTotal is the total number of documents published by snapshots.
+	snapshotDocumentsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subSystem,
+			Name:      "snapshot_documents_total",
+			Help:      "Total number of documents published by snapshots",
+		}, []string{lStream, lDatabase, lCollection},
 	)
 
 	// failedHandleEventTotal is the total number of change stream handle event failed.
@@ -52,7 +105,7 @@ var (
 			Subsystem: subSystem,
 			Name:      "change_stream_handle_event_failed_total",
 			Help:      "Total number of change stream handle event failed",
-		}, []string{lDatabase, lCollection},
+		}, []string{lStream, lDatabase, lCollection},
 	)
 )
 
@@ -63,25 +116,55 @@ func Collectors() []prometheus.Collector {
 		receivedBytesTotal,
 		successHandleEventTotal,
 		failedHandleEventTotal,
+		deadLetteredTotal,
+		restartsTotal,
+		terminationsTotal,
+		historyLostTotal,
+		snapshotDocumentsTotal,
 	}
 }
 
 // ReceiveChangeStream increase the total number of change stream received.
-func ReceiveChangeStream(database, collection string) {
-	receivedTotal.WithLabelValues(database, collection).Inc()
+func ReceiveChangeStream(stream, database, collection string) {
+	receivedTotal.WithLabelValues(stream, database, collection).Inc()
 }
 
 // ReceiveChangeStreamBytes increase the total number of change stream received bytes.
-func ReceiveBytes(database, collection string, size int) {
-	receivedBytesTotal.WithLabelValues(database, collection).Add(float64(size))
+func ReceiveBytes(stream, database, collection string, size int) {
+	receivedBytesTotal.WithLabelValues(stream, database, collection).Add(float64(size))
 }
 
 // HandleChangeEventSuccess increase the total number of change stream handle event success.
-func HandleChangeEventSuccess(database, collection string) {
-	successHandleEventTotal.WithLabelValues(database, collection).Inc()
+func HandleChangeEventSuccess(stream, database, collection string) {
+	successHandleEventTotal.WithLabelValues(stream, database, collection).Inc()
 }
 
 // HandleChangeEventFailed increase the total number of change stream handle event failed.
-func HandleChangeEventFailed(database, collection string) {
-	failedHandleEventTotal.WithLabelValues(database, collection).Inc()
+func HandleChangeEventFailed(stream, database, collection string) {
+	failedHandleEventTotal.WithLabelValues(stream, database, collection).Inc()
+}
+
+// DeadLetterChangeEvent increase the total number of change stream events stored to the dead letter queue.
+func DeadLetterChangeEvent(stream, database, collection string) {
+	deadLetteredTotal.WithLabelValues(stream, database, collection).Inc()
+}
+
+// TerminateChangeStream increase the total number of change stream terminations by the reason.
+func TerminateChangeStream(stream, reason string) {
+	terminationsTotal.WithLabelValues(stream, reason).Inc()
+}
+
+// RestartChangeStream increase the total number of change stream restarts by the reason.
+func RestartChangeStream(stream, reason string) {
+	restartsTotal.WithLabelValues(stream, reason).Inc()
+}
+
+// LoseHistory increase the total number of change streams whose resume token is no longer in the oplog.
+func LoseHistory(stream, policy string) {
+	historyLostTotal.WithLabelValues(stream, policy).Inc()
+}
+
+// SnapshotDocument increase the total number of documents published by snapshots.
+func SnapshotDocument(stream, database, collection string) {
+	snapshotDocumentsTotal.WithLabelValues(stream, database, collection).Inc()
 }
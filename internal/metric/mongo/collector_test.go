@@ -11,19 +11,39 @@ func TestCollector(t *testing.T) {
 
 	// Run ReceiveChangeStream to test the function
 	// no return value, just test if it runs without runtime errors
-	ReceiveChangeStream("database", "collection")
+	ReceiveChangeStream("stream", "database", "collection")
 
 	// Run ReceiveBytes to test the function
 	// no return value, just test if it runs without runtime errors
-	ReceiveBytes("database", "collection", 256)
+	ReceiveBytes("stream", "database", "collection", 256)
 
 	// Run HandleChangeEventFailed to test the function
 	// no return value, just test if it runs without runtime errors
-	HandleChangeEventFailed("database", "collection")
+	HandleChangeEventFailed("stream", "database", "collection")
 
 	// Run HandleChangeEventSuccess to test the function
 	// no return value, just test if it runs without runtime errors
-	HandleChangeEventSuccess("database", "collection")
+	HandleChangeEventSuccess("stream", "database", "collection")
+
+	// Run DeadLetterChangeEvent to test the function
+	// no return value, just test if it runs without runtime errors
+	DeadLetterChangeEvent("stream", "database", "collection")
+
+	// Run TerminateChangeStream to test the function
+	// no return value, just test if it runs without runtime errors
+	TerminateChangeStream("stream", "resumable")
+
+	// Run RestartChangeStream to test the function
+	// no return value, just test if it runs without runtime errors
+	RestartChangeStream("stream", "resumable")
+
+	// Run LoseHistory to test the function
+	// no return value, just test if it runs without runtime errors
+	LoseHistory("stream", "restart")
+
+	// Run SnapshotDocument to test the function
+	// no return value, just test if it runs without runtime errors
+	SnapshotDocument("stream", "database", "collection")
 
 	// Check if the Collectors function returns a non-empty slice
 	cols := Collectors()
@@ -0,0 +1,149 @@
+package model
+
+import (
+	_ "embed"
+	"sync"
+	"time"
+
+	"github.com/hamba/avro/v2"
+)
+
+//go:embed schema/change_stream.avsc
+var avroSchema string
+
+type (
+	// avroChangeEvent is the Avro representation of ChangeEvent.
+	// Documents are encoded as raw BSON bytes.
+	avroChangeEvent struct {
+		ID                       []byte                 `avro:"_id"`
+		OperationType            string                 `avro:"operationType"`
+		ClusterTime              avroTimestamp          `avro:"clusterTime"`
+		WallTime                 *time.Time             `avro:"wallTime"`
+		Namespace                Namespace              `avro:"ns"`
+		To                       *Namespace             `avro:"to"`
+		DocumentKey              *[]byte                `avro:"documentKey"`
+		FullDocument             *[]byte                `avro:"fullDocument"`
+		FullDocumentBeforeChange *[]byte                `avro:"fullDocumentBeforeChange"`
+		UpdateDescription        *avroUpdateDescription `avro:"updateDescription"`
+		TxnNumber                *int64                 `avro:"txnNumber"`
+		LSID                     *[]byte                `avro:"lsid"`
+		CollectionUUID           *[]byte                `avro:"collectionUUID"`
+		OperationDescription     *[]byte                `avro:"operationDescription"`
+		StateBeforeChange        *[]byte                `avro:"stateBeforeChange"`
+	}
+
+	avroTimestamp struct {
+		T int64 `avro:"t"`
+		I int64 `avro:"i"`
+	}
+
+	avroUpdateDescription struct {
+		UpdatedFields      []byte               `avro:"updatedFields"`
+		RemovedFields      []string             `avro:"removedFields"`
+		TruncatedArrays    []avroTruncatedArray `avro:"truncatedArrays"`
+		DisambiguatedPaths *[]byte              `avro:"disambiguatedPaths"`
+	}
+
+	avroTruncatedArray struct {
+		Field   string `avro:"field"`
+		NewSize int32  `avro:"newSize"`
+	}
+)
+
+// AvroSchema returns the Avro schema of the change stream event.
+func AvroSchema() string {
+	return avroSchema
+}
+
+// parseAvroSchema parses the Avro schema once.
+//
+//nolint:gochecknoglobals
+var parseAvroSchema = sync.OnceValues(func() (avro.Schema, error) {
+	return avro.Parse(avroSchema)
+})
+
+// AvroEncoder encodes change stream events as Avro of the embedded schema.
+type AvroEncoder struct {
+	schema avro.Schema
+}
+
+// NewAvroEncoder creates a new Avro encoder.
+func NewAvroEncoder() (*AvroEncoder, error) {
+	schema, err := parseAvroSchema()
+	if err != nil {
+		return nil, err
+	}
+	return &AvroEncoder{schema: schema}, nil
+}
+
+// Encode returns the avro encoded byte array of the change stream event.
+func (e *AvroEncoder) Encode(c ChangeEvent) ([]byte, error) {
+	b, err := avro.Marshal(e.schema, c.avro())
+	if err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// ContentType returns the media type of Avro.
+func (e *AvroEncoder) ContentType() string {
+	return "application/avro"
+}
+
+// Avro returns the avro encoded byte array of the change stream event.
+func (c ChangeEvent) Avro() ([]byte, error) {
+	e, err := NewAvroEncoder()
+	if err != nil {
+		return nil, err
+	}
+	return e.Encode(c)
+}
+
+// avro converts the change event to the Avro representation.
+func (c ChangeEvent) avro() avroChangeEvent {
+	a := avroChangeEvent{
+		ID:            c.ID,
+		OperationType: c.OperationType,
+		ClusterTime: avroTimestamp{
+			T: int64(c.ClusterTime.T),
+			I: int64(c.ClusterTime.I),
+		},
+		WallTime:                 c.WallTime,
+		Namespace:                c.Namespace,
+		To:                       c.To,
+		DocumentKey:              optionalBytes(c.DocumentKey),
+		FullDocument:             optionalBytes(c.FullDocument),
+		FullDocumentBeforeChange: optionalBytes(c.FullDocumentBeforeChange),
+		TxnNumber:                c.TxnNumber,
+		LSID:                     optionalBytes(c.LSID),
+		OperationDescription:     optionalBytes(c.OperationDescription),
+		StateBeforeChange:        optionalBytes(c.StateBeforeChange),
+	}
+	if c.CollectionUUID != nil {
+		a.CollectionUUID = optionalBytes(c.CollectionUUID.Data)
+	}
+	if u := c.UpdateDescription; u != nil {
+		a.UpdateDescription = &avroUpdateDescription{
+			UpdatedFields:      u.UpdatedFields,
+			RemovedFields:      u.RemovedFields,
+			TruncatedArrays:    make([]avroTruncatedArray, 0, len(u.TruncatedArrays)),
+			DisambiguatedPaths: optionalBytes(u.DisambiguatedPaths),
+		}
+		if a.UpdateDescription.RemovedFields == nil {
+			a.UpdateDescription.RemovedFields = []string{}
+		}
+		for _, t := range u.TruncatedArrays {
+			a.UpdateDescription.TruncatedArrays = append(a.UpdateDescription.TruncatedArrays, avroTruncatedArray(t))
+		}
+	}
+	return a
+}
+
+// optionalBytes returns nil for an empty value so that it is encoded as null.
+func optionalBytes(b []byte) *[]byte {
+	if len(b) == 0 {
+		return nil
+	}
+	return &b
+}
@@ -1,61 +1,189 @@
 package model
 
 import (
-	_ "embed"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
+	"time"
 
-	"github.com/hamba/avro/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-//go:embed schema/change_stream.avsc
-var avroSchema string
+// SchemaVersion is the version of the published change event schema. It is
+// increased when the fields of ChangeEvent change incompatibly.
+const SchemaVersion = "1"
+
+// OperationTypeSnapshot is the operation type of the synthetic events that carry
+// the documents read by a snapshot of a collection, as opposed to a change.
+const OperationTypeSnapshot = "snapshot"
 
 type (
 	// ChangeEvent is a struct that represents a change stream event.
+	//
+	// The fields follow the change event documents of MongoDB, and the documents
+	// in the event are kept as raw BSON so that their values are not lost. They are
+	// written as relaxed Extended JSON by MarshalJSON.
 	ChangeEvent struct {
-		ID                string             `avro:"_id" bson:"_id" json:"_id"`
-		OperationType     string             `avro:"operationType" bson:"operation_type" json:"operation_type"`
-		FullDocument      []byte             `avro:"fullDocument" bson:"full_document" json:"full_document"`
-		DocumentKey       string             `avro:"documentKey" bson:"document_key" json:"document_key"`
-		UpdateDescription *UpdateDescription `avro:"updateDescription" bson:"update_description" json:"update_description"`
-		Namespace         Namespace          `avro:"ns" bson:"namespace" json:"namespace"`
-		To                *Namespace         `avro:"to" bson:"to" json:"to"`
+		// ID is the resume token of the event, empty for snapshot events.
+		ID            bson.Raw            `bson:"_id,omitempty" json:"_id"`
+		OperationType string              `bson:"operationType" json:"operation_type"`
+		ClusterTime   primitive.Timestamp `bson:"clusterTime" json:"cluster_time"`
+		// WallTime is the server time of the event, available since MongoDB 6.0.
+		WallTime                 *time.Time `bson:"wallTime,omitempty" json:"wall_time,omitempty"`
+		Namespace                Namespace  `bson:"ns,omitempty" json:"namespace"`
+		To                       *Namespace `bson:"to,omitempty" json:"to,omitempty"`
+		DocumentKey              bson.Raw   `bson:"documentKey,omitempty" json:"document_key,omitempty"`
+		FullDocument             bson.Raw   `bson:"fullDocument,omitempty" json:"full_document,omitempty"`
+		FullDocumentBeforeChange bson.Raw   `bson:"fullDocumentBeforeChange,omitempty" json:"full_document_before_change,omitempty"`
+		// UpdateDescription is set for update events.
+		UpdateDescription *UpdateDescription `bson:"updateDescription,omitempty" json:"update_description,omitempty"`
+		// TxnNumber and LSID are set for events in a transaction.
+		TxnNumber *int64   `bson:"txnNumber,omitempty" json:"txn_number,omitempty"`
+		LSID      bson.Raw `bson:"lsid,omitempty" json:"lsid,omitempty"`
+		// CollectionUUID and OperationDescription are set for expanded events,
+		// and StateBeforeChange is set for modify events.
+		CollectionUUID       *primitive.Binary `bson:"collectionUUID,omitempty" json:"collection_uuid,omitempty"`
+		OperationDescription bson.Raw          `bson:"operationDescription,omitempty" json:"operation_description,omitempty"`
+		StateBeforeChange    bson.Raw          `bson:"stateBeforeChange,omitempty" json:"state_before_change,omitempty"`
 	}
 
 	// UpdateDescription is a struct that represents an update description of change stream event.
 	UpdateDescription struct {
-		UpdatedFields string `avro:"updatedFields" bson:"updated_fields" json:"updated_fields"`
-		RemovedFields string `avro:"removedFields" bson:"removed_fields" json:"removed_fields"`
+		UpdatedFields   bson.Raw         `bson:"updatedFields" json:"updated_fields"`
+		RemovedFields   []string         `bson:"removedFields" json:"removed_fields"`
+		TruncatedArrays []TruncatedArray `bson:"truncatedArrays,omitempty" json:"truncated_arrays,omitempty"`
+		// DisambiguatedPaths is set when a path of the updated fields is ambiguous,
+		// available since MongoDB 6.1.
+		DisambiguatedPaths bson.Raw `bson:"disambiguatedPaths,omitempty" json:"disambiguated_paths,omitempty"`
+	}
+
+	// TruncatedArray is an array truncated by an update.
+	TruncatedArray struct {
+		Field   string `bson:"field" json:"field"`
+		NewSize int32  `bson:"newSize" json:"new_size"`
 	}
 
 	// Namespace is a struct that represents a namespace of change stream event.
 	Namespace struct {
-		DB   string `avro:"db" bson:"db" json:"db"`
-		Coll string `avro:"coll" bson:"coll" json:"coll"`
+		DB   string `bson:"db" json:"db" avro:"db"`
+		Coll string `bson:"coll,omitempty" json:"coll,omitempty" avro:"coll"`
 	}
 )
 
-// Avro returns the avro encoded byte array of the change stream event.
-func (c ChangeEvent) Avro() ([]byte, error) {
-	schema, err := avro.Parse(avroSchema)
+// MarshalJSON encodes the change event as JSON, with the BSON values such as the
+// documents and the cluster time written as relaxed Extended JSON.
+func (c ChangeEvent) MarshalJSON() ([]byte, error) {
+	v := struct {
+		ID                       json.RawMessage    `json:"_id"`
+		OperationType            string             `json:"operation_type"`
+		ClusterTime              json.RawMessage    `json:"cluster_time"`
+		WallTime                 *time.Time         `json:"wall_time,omitempty"`
+		Namespace                Namespace          `json:"namespace"`
+		To                       *Namespace         `json:"to,omitempty"`
+		DocumentKey              json.RawMessage    `json:"document_key,omitempty"`
+		FullDocument             json.RawMessage    `json:"full_document,omitempty"`
+		FullDocumentBeforeChange json.RawMessage    `json:"full_document_before_change,omitempty"`
+		UpdateDescription        *UpdateDescription `json:"update_description,omitempty"`
+		TxnNumber                *int64             `json:"txn_number,omitempty"`
+		LSID                     json.RawMessage    `json:"lsid,omitempty"`
+		CollectionUUID           json.RawMessage    `json:"collection_uuid,omitempty"`
+		OperationDescription     json.RawMessage    `json:"operation_description,omitempty"`
+		StateBeforeChange        json.RawMessage    `json:"state_before_change,omitempty"`
+	}{
+		OperationType:     c.OperationType,
+		ClusterTime:       timestampJSON(c.ClusterTime),
+		WallTime:          c.WallTime,
+		Namespace:         c.Namespace,
+		To:                c.To,
+		UpdateDescription: c.UpdateDescription,
+		TxnNumber:         c.TxnNumber,
+	}
+	if c.CollectionUUID != nil {
+		v.CollectionUUID = binaryJSON(*c.CollectionUUID)
+	}
+	for _, f := range []struct {
+		dst *json.RawMessage
+		src bson.Raw
+	}{
+		{&v.ID, c.ID},
+		{&v.DocumentKey, c.DocumentKey},
+		{&v.FullDocument, c.FullDocument},
+		{&v.FullDocumentBeforeChange, c.FullDocumentBeforeChange},
+		{&v.LSID, c.LSID},
+		{&v.OperationDescription, c.OperationDescription},
+		{&v.StateBeforeChange, c.StateBeforeChange},
+	} {
+		b, err := documentJSON(f.src)
+		if err != nil {
+			return nil, err
+		}
+		*f.dst = b
+	}
+	return json.Marshal(v)
+}
+
+// MarshalJSON encodes the update description as JSON, with the documents written
+// as relaxed Extended JSON.
+func (u UpdateDescription) MarshalJSON() ([]byte, error) {
+	updated, err := documentJSON(u.UpdatedFields)
 	if err != nil {
 		return nil, err
 	}
-
-	b, err := avro.Marshal(schema, c)
+	disambiguated, err := documentJSON(u.DisambiguatedPaths)
 	if err != nil {
 		return nil, err
 	}
+	return json.Marshal(struct {
+		UpdatedFields      json.RawMessage  `json:"updated_fields"`
+		RemovedFields      []string         `json:"removed_fields"`
+		TruncatedArrays    []TruncatedArray `json:"truncated_arrays,omitempty"`
+		DisambiguatedPaths json.RawMessage  `json:"disambiguated_paths,omitempty"`
+	}{
+		UpdatedFields:      updated,
+		RemovedFields:      u.RemovedFields,
+		TruncatedArrays:    u.TruncatedArrays,
+		DisambiguatedPaths: disambiguated,
+	})
+}
 
+// documentJSON returns the document as relaxed Extended JSON, nil for an empty document.
+func documentJSON(doc bson.Raw) (json.RawMessage, error) {
+	if len(doc) == 0 {
+		return nil, nil
+	}
+	b, err := bson.MarshalExtJSON(doc, false, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal document to extended json: %w", err)
+	}
 	return b, nil
 }
 
+// timestampJSON returns the timestamp as Extended JSON.
+func timestampJSON(ts primitive.Timestamp) json.RawMessage {
+	return json.RawMessage(fmt.Sprintf(`{"$timestamp":{"t":%d,"i":%d}}`, ts.T, ts.I))
+}
+
+// binaryJSON returns the binary as Extended JSON.
+func binaryJSON(b primitive.Binary) json.RawMessage {
+	return json.RawMessage(fmt.Sprintf(`{"$binary":{"base64":"%s","subType":"%s"}}`,
+		base64.StdEncoding.EncodeToString(b.Data), hex.EncodeToString([]byte{b.Subtype})))
+}
+
 // JSON returns the json encoded byte array of the change stream event.
 func (c ChangeEvent) JSON() ([]byte, error) {
-	b, err := json.Marshal(c)
-	if err != nil {
-		return nil, err
-	}
+	return NewJSONEncoder().Encode(c)
+}
 
-	return b, nil
+// ExtJSON returns the MongoDB Extended JSON v2 encoded byte array of the change stream event.
+// The event keeps the field names of MongoDB, and the canonical mode preserves the BSON types
+// of every value while the relaxed mode uses native JSON numbers and ISO-8601 dates.
+func (c ChangeEvent) ExtJSON(canonical bool) ([]byte, error) {
+	return NewExtJSONEncoder(canonical).Encode(c)
+}
+
+// IsZero reports whether the namespace is empty, e.g. for invalidate events.
+func (n Namespace) IsZero() bool {
+	return n.DB == "" && n.Coll == ""
 }
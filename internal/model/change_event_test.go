@@ -1,51 +1,81 @@
 package model
 
 import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/hamba/avro/v2"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
 )
 
-func TestChangeEvent_Avro(t *testing.T) {
-	t.Parallel()
+// update regenerates the golden files with `go test ./internal/model -update`.
+var update = flag.Bool("update", false, "update golden files")
 
-	patterns := []struct {
-		name string
-		in   ChangeEvent
-		out  []byte
-	}{
-		{
-			name: "success",
-			in: ChangeEvent{
-				ID:            "3dade3fb-189a-4d22-9c62-c759069da1c8",
-				OperationType: "updated",
-				FullDocument:  []byte("aabbccddeeffgg"),
-				DocumentKey:   "96c316ca-39a4-4e5c-b7d6-716b869b5c08",
-				UpdateDescription: &UpdateDescription{
-					UpdatedFields: "aabbccddeeffgg",
-					RemovedFields: "aabbccddeeffgg",
-				},
-				Namespace: Namespace{
-					DB:   "database",
-					Coll: "collection",
-				},
-				To: &Namespace{
-					DB:   "database",
-					Coll: "collection",
-				},
-			},
-			out: []byte{0x48, 0x33, 0x64, 0x61, 0x64, 0x65, 0x33, 0x66, 0x62, 0x2d, 0x31, 0x38, 0x39, 0x61, 0x2d, 0x34, 0x64, 0x32, 0x32, 0x2d, 0x39, 0x63, 0x36, 0x32, 0x2d, 0x63, 0x37, 0x35, 0x39, 0x30, 0x36, 0x39, 0x64, 0x61, 0x31, 0x63, 0x38, 0xe, 0x75, 0x70, 0x64, 0x61, 0x74, 0x65, 0x64, 0x2, 0x1c, 0x61, 0x61, 0x62, 0x62, 0x63, 0x63, 0x64, 0x64, 0x65, 0x65, 0x66, 0x66, 0x67, 0x67, 0x48, 0x39, 0x36, 0x63, 0x33, 0x31, 0x36, 0x63, 0x61, 0x2d, 0x33, 0x39, 0x61, 0x34, 0x2d, 0x34, 0x65, 0x35, 0x63, 0x2d, 0x62, 0x37, 0x64, 0x36, 0x2d, 0x37, 0x31, 0x36, 0x62, 0x38, 0x36, 0x39, 0x62, 0x35, 0x63, 0x30, 0x38, 0x2, 0x1c, 0x61, 0x61, 0x62, 0x62, 0x63, 0x63, 0x64, 0x64, 0x65, 0x65, 0x66, 0x66, 0x67, 0x67, 0x1c, 0x61, 0x61, 0x62, 0x62, 0x63, 0x63, 0x64, 0x64, 0x65, 0x65, 0x66, 0x66, 0x67, 0x67, 0x10, 0x64, 0x61, 0x74, 0x61, 0x62, 0x61, 0x73, 0x65, 0x14, 0x63, 0x6f, 0x6c, 0x6c, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x2, 0x10, 0x64, 0x61, 0x74, 0x61, 0x62, 0x61, 0x73, 0x65, 0x14, 0x63, 0x6f, 0x6c, 0x6c, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e},
-		},
+// loadEvents returns the recorded change events in testdata/events keyed by name.
+// The events are stored as canonical extended JSON.
+func loadEvents(t testing.TB) map[string]bson.Raw {
+	t.Helper()
+
+	paths, err := filepath.Glob(filepath.Join("testdata", "events", "*.json"))
+	require.NoError(t, err)
+	require.NotEmpty(t, paths)
+
+	events := make(map[string]bson.Raw, len(paths))
+	for _, p := range paths {
+		if strings.HasSuffix(p, ".golden.json") {
+			continue
+		}
+		b, err := os.ReadFile(p)
+		require.NoError(t, err)
+
+		var raw bson.Raw
+		require.NoError(t, bson.UnmarshalExtJSON(b, true, &raw), p)
+		events[strings.TrimSuffix(filepath.Base(p), ".json")] = raw
 	}
+	return events
+}
 
-	for _, tt := range patterns {
-		tt := tt
-		t.Run(tt.name, func(t *testing.T) {
+// assertGolden compares the indented JSON with the golden file.
+func assertGolden(t *testing.T, golden string, got []byte) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	require.NoError(t, json.Indent(&buf, got, "", "  "))
+	buf.WriteByte('\n')
+
+	if *update {
+		require.NoError(t, os.WriteFile(golden, buf.Bytes(), 0o644))
+	}
+	want, err := os.ReadFile(golden)
+	require.NoError(t, err)
+	assert.Equal(t, string(want), buf.String())
+}
+
+func TestChangeEvent_Decode(t *testing.T) {
+	t.Parallel()
+
+	for name, raw := range loadEvents(t) {
+		name, raw := name, raw
+		t.Run(name, func(t *testing.T) {
 			t.Parallel()
 
-			got, err := tt.in.Avro()
-			assert.NoError(t, err)
-			assert.Equal(t, tt.out, got)
+			var got ChangeEvent
+			require.NoError(t, bson.Unmarshal(raw, &got))
+
+			// every field of the event must survive decoding.
+			b, err := bson.Marshal(got)
+			require.NoError(t, err)
+			var want, decoded bson.M
+			require.NoError(t, bson.Unmarshal(raw, &want))
+			require.NoError(t, bson.Unmarshal(b, &decoded))
+			assert.Equal(t, want, decoded)
 		})
 	}
 }
@@ -53,43 +83,101 @@ func TestChangeEvent_Avro(t *testing.T) {
 func TestChangeEvent_JSON(t *testing.T) {
 	t.Parallel()
 
+	for name, raw := range loadEvents(t) {
+		name, raw := name, raw
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var ev ChangeEvent
+			require.NoError(t, bson.Unmarshal(raw, &ev))
+
+			got, err := ev.JSON()
+			require.NoError(t, err)
+			assertGolden(t, filepath.Join("testdata", "events", name+".golden.json"), got)
+		})
+	}
+}
+
+func TestChangeEvent_ExtJSON(t *testing.T) {
+	t.Parallel()
+
 	patterns := []struct {
-		name string
-		in   ChangeEvent
-		out  []byte
+		name      string
+		canonical bool
 	}{
 		{
-			name: "success",
-			in: ChangeEvent{
-				ID:            "3dade3fb-189a-4d22-9c62-c759069da1c8",
-				OperationType: "updated",
-				FullDocument:  []byte("aabbccddeeffgg"),
-				DocumentKey:   "96c316ca-39a4-4e5c-b7d6-716b869b5c08",
-				UpdateDescription: &UpdateDescription{
-					UpdatedFields: "aabbccddeeffgg",
-					RemovedFields: "aabbccddeeffgg",
-				},
-				Namespace: Namespace{
-					DB:   "database",
-					Coll: "collection",
-				},
-				To: &Namespace{
-					DB:   "database",
-					Coll: "collection",
-				},
-			},
-			out: []byte(`{"_id":"3dade3fb-189a-4d22-9c62-c759069da1c8","operation_type":"updated","full_document":"YWFiYmNjZGRlZWZmZ2c=","document_key":"96c316ca-39a4-4e5c-b7d6-716b869b5c08","update_description":{"updated_fields":"aabbccddeeffgg","removed_fields":"aabbccddeeffgg"},"namespace":{"db":"database","coll":"collection"},"to":{"db":"database","coll":"collection"}}`),
+			name:      "canonical",
+			canonical: true,
+		},
+		{
+			name:      "relaxed",
+			canonical: false,
 		},
 	}
 
+	events := loadEvents(t)
 	for _, tt := range patterns {
 		tt := tt
-		t.Run(tt.name, func(t *testing.T) {
+		for name, raw := range events {
+			name, raw := name, raw
+			t.Run(tt.name+"/"+name, func(t *testing.T) {
+				t.Parallel()
+
+				var ev ChangeEvent
+				require.NoError(t, bson.Unmarshal(raw, &ev))
+
+				got, err := ev.ExtJSON(tt.canonical)
+				require.NoError(t, err)
+				assertGolden(t, filepath.Join("testdata", "events", name+"."+tt.name+".golden.json"), got)
+
+				if !tt.canonical {
+					return
+				}
+				// the canonical format round-trips to the recorded event.
+				var decoded bson.Raw
+				require.NoError(t, bson.UnmarshalExtJSON(got, true, &decoded))
+				var want, gotM bson.M
+				require.NoError(t, bson.Unmarshal(raw, &want))
+				require.NoError(t, bson.Unmarshal(decoded, &gotM))
+				assert.Equal(t, want, gotM)
+			})
+		}
+	}
+}
+
+func TestChangeEvent_Avro(t *testing.T) {
+	t.Parallel()
+
+	schema, err := avro.Parse(avroSchema)
+	require.NoError(t, err)
+
+	for name, raw := range loadEvents(t) {
+		name, raw := name, raw
+		t.Run(name, func(t *testing.T) {
 			t.Parallel()
 
-			got, err := tt.in.JSON()
-			assert.NoError(t, err)
-			assert.Equal(t, tt.out, got)
+			var ev ChangeEvent
+			require.NoError(t, bson.Unmarshal(raw, &ev))
+
+			b, err := ev.Avro()
+			require.NoError(t, err)
+
+			var got avroChangeEvent
+			require.NoError(t, avro.Unmarshal(schema, b, &got))
+			// the empty id of snapshot events is decoded as empty bytes.
+			if len(got.ID) == 0 {
+				got.ID = nil
+			}
+			// empty arrays are decoded as nil slices.
+			if u := got.UpdateDescription; u != nil {
+				if u.RemovedFields == nil {
+					u.RemovedFields = []string{}
+				}
+				if u.TruncatedArrays == nil {
+					u.TruncatedArrays = []avroTruncatedArray{}
+				}
+			}
+			assert.Equal(t, ev.avro(), got)
 		})
 	}
 }
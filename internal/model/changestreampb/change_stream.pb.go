@@ -0,0 +1,779 @@
+// Protocol Buffers schema of the change stream events published by mongo-streamer.
+// Field numbers are stable; new fields are only appended.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.33.0
+// 	protoc        (unknown)
+// source: change_stream.proto
+
+package changestreampb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	structpb "google.golang.org/protobuf/types/known/structpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// ChangeEvent is a change stream event of MongoDB.
+type ChangeEvent struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// id is the resume token of the event as raw BSON.
+	Id            []byte       `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	OperationType string       `protobuf:"bytes,2,opt,name=operation_type,json=operationType,proto3" json:"operation_type,omitempty"`
+	ClusterTime   *ClusterTime `protobuf:"bytes,3,opt,name=cluster_time,json=clusterTime,proto3" json:"cluster_time,omitempty"`
+	// wall_time is set since MongoDB 6.0.
+	WallTime *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=wall_time,json=wallTime,proto3" json:"wall_time,omitempty"`
+	Ns       *Namespace             `protobuf:"bytes,5,opt,name=ns,proto3" json:"ns,omitempty"`
+	// to is set for rename events.
+	To                       *Namespace `protobuf:"bytes,6,opt,name=to,proto3" json:"to,omitempty"`
+	DocumentKey              *Document  `protobuf:"bytes,7,opt,name=document_key,json=documentKey,proto3" json:"document_key,omitempty"`
+	FullDocument             *Document  `protobuf:"bytes,8,opt,name=full_document,json=fullDocument,proto3" json:"full_document,omitempty"`
+	FullDocumentBeforeChange *Document  `protobuf:"bytes,9,opt,name=full_document_before_change,json=fullDocumentBeforeChange,proto3" json:"full_document_before_change,omitempty"`
+	// update_description is set for update events.
+	UpdateDescription *UpdateDescription `protobuf:"bytes,10,opt,name=update_description,json=updateDescription,proto3" json:"update_description,omitempty"`
+	// txn_number and lsid are set for events in a transaction.
+	TxnNumber *int64    `protobuf:"varint,11,opt,name=txn_number,json=txnNumber,proto3,oneof" json:"txn_number,omitempty"`
+	Lsid      *Document `protobuf:"bytes,12,opt,name=lsid,proto3" json:"lsid,omitempty"`
+	// collection_uuid and operation_description are set for expanded events,
+	// and state_before_change is set for modify events.
+	CollectionUuid       []byte    `protobuf:"bytes,13,opt,name=collection_uuid,json=collectionUuid,proto3" json:"collection_uuid,omitempty"`
+	OperationDescription *Document `protobuf:"bytes,14,opt,name=operation_description,json=operationDescription,proto3" json:"operation_description,omitempty"`
+	StateBeforeChange    *Document `protobuf:"bytes,15,opt,name=state_before_change,json=stateBeforeChange,proto3" json:"state_before_change,omitempty"`
+}
+
+func (x *ChangeEvent) Reset() {
+	*x = ChangeEvent{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_change_stream_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ChangeEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChangeEvent) ProtoMessage() {}
+
+func (x *ChangeEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_change_stream_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChangeEvent.ProtoReflect.Descriptor instead.
+func (*ChangeEvent) Descriptor() ([]byte, []int) {
+	return file_change_stream_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ChangeEvent) GetId() []byte {
+	if x != nil {
+		return x.Id
+	}
+	return nil
+}
+
+func (x *ChangeEvent) GetOperationType() string {
+	if x != nil {
+		return x.OperationType
+	}
+	return ""
+}
+
+func (x *ChangeEvent) GetClusterTime() *ClusterTime {
+	if x != nil {
+		return x.ClusterTime
+	}
+	return nil
+}
+
+func (x *ChangeEvent) GetWallTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.WallTime
+	}
+	return nil
+}
+
+func (x *ChangeEvent) GetNs() *Namespace {
+	if x != nil {
+		return x.Ns
+	}
+	return nil
+}
+
+func (x *ChangeEvent) GetTo() *Namespace {
+	if x != nil {
+		return x.To
+	}
+	return nil
+}
+
+func (x *ChangeEvent) GetDocumentKey() *Document {
+	if x != nil {
+		return x.DocumentKey
+	}
+	return nil
+}
+
+func (x *ChangeEvent) GetFullDocument() *Document {
+	if x != nil {
+		return x.FullDocument
+	}
+	return nil
+}
+
+func (x *ChangeEvent) GetFullDocumentBeforeChange() *Document {
+	if x != nil {
+		return x.FullDocumentBeforeChange
+	}
+	return nil
+}
+
+func (x *ChangeEvent) GetUpdateDescription() *UpdateDescription {
+	if x != nil {
+		return x.UpdateDescription
+	}
+	return nil
+}
+
+func (x *ChangeEvent) GetTxnNumber() int64 {
+	if x != nil && x.TxnNumber != nil {
+		return *x.TxnNumber
+	}
+	return 0
+}
+
+func (x *ChangeEvent) GetLsid() *Document {
+	if x != nil {
+		return x.Lsid
+	}
+	return nil
+}
+
+func (x *ChangeEvent) GetCollectionUuid() []byte {
+	if x != nil {
+		return x.CollectionUuid
+	}
+	return nil
+}
+
+func (x *ChangeEvent) GetOperationDescription() *Document {
+	if x != nil {
+		return x.OperationDescription
+	}
+	return nil
+}
+
+func (x *ChangeEvent) GetStateBeforeChange() *Document {
+	if x != nil {
+		return x.StateBeforeChange
+	}
+	return nil
+}
+
+// Document is a BSON document, encoded as raw BSON or as a Struct of the
+// relaxed Extended JSON representation depending on the configuration.
+type Document struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Types that are assignable to Value:
+	//	*Document_Bson
+	//	*Document_Struct
+	Value isDocument_Value `protobuf_oneof:"value"`
+}
+
+func (x *Document) Reset() {
+	*x = Document{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_change_stream_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Document) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Document) ProtoMessage() {}
+
+func (x *Document) ProtoReflect() protoreflect.Message {
+	mi := &file_change_stream_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Document.ProtoReflect.Descriptor instead.
+func (*Document) Descriptor() ([]byte, []int) {
+	return file_change_stream_proto_rawDescGZIP(), []int{1}
+}
+
+func (m *Document) GetValue() isDocument_Value {
+	if m != nil {
+		return m.Value
+	}
+	return nil
+}
+
+func (x *Document) GetBson() []byte {
+	if x, ok := x.GetValue().(*Document_Bson); ok {
+		return x.Bson
+	}
+	return nil
+}
+
+func (x *Document) GetStruct() *structpb.Struct {
+	if x, ok := x.GetValue().(*Document_Struct); ok {
+		return x.Struct
+	}
+	return nil
+}
+
+type isDocument_Value interface {
+	isDocument_Value()
+}
+
+type Document_Bson struct {
+	Bson []byte `protobuf:"bytes,1,opt,name=bson,proto3,oneof"`
+}
+
+type Document_Struct struct {
+	Struct *structpb.Struct `protobuf:"bytes,2,opt,name=struct,proto3,oneof"`
+}
+
+func (*Document_Bson) isDocument_Value() {}
+
+func (*Document_Struct) isDocument_Value() {}
+
+// ClusterTime is the BSON timestamp of the oplog entry.
+type ClusterTime struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	T uint32 `protobuf:"varint,1,opt,name=t,proto3" json:"t,omitempty"`
+	I uint32 `protobuf:"varint,2,opt,name=i,proto3" json:"i,omitempty"`
+}
+
+func (x *ClusterTime) Reset() {
+	*x = ClusterTime{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_change_stream_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ClusterTime) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ClusterTime) ProtoMessage() {}
+
+func (x *ClusterTime) ProtoReflect() protoreflect.Message {
+	mi := &file_change_stream_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ClusterTime.ProtoReflect.Descriptor instead.
+func (*ClusterTime) Descriptor() ([]byte, []int) {
+	return file_change_stream_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ClusterTime) GetT() uint32 {
+	if x != nil {
+		return x.T
+	}
+	return 0
+}
+
+func (x *ClusterTime) GetI() uint32 {
+	if x != nil {
+		return x.I
+	}
+	return 0
+}
+
+type Namespace struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Db   string `protobuf:"bytes,1,opt,name=db,proto3" json:"db,omitempty"`
+	Coll string `protobuf:"bytes,2,opt,name=coll,proto3" json:"coll,omitempty"`
+}
+
+func (x *Namespace) Reset() {
+	*x = Namespace{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_change_stream_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Namespace) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Namespace) ProtoMessage() {}
+
+func (x *Namespace) ProtoReflect() protoreflect.Message {
+	mi := &file_change_stream_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Namespace.ProtoReflect.Descriptor instead.
+func (*Namespace) Descriptor() ([]byte, []int) {
+	return file_change_stream_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Namespace) GetDb() string {
+	if x != nil {
+		return x.Db
+	}
+	return ""
+}
+
+func (x *Namespace) GetColl() string {
+	if x != nil {
+		return x.Coll
+	}
+	return ""
+}
+
+type UpdateDescription struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	UpdatedFields   *Document         `protobuf:"bytes,1,opt,name=updated_fields,json=updatedFields,proto3" json:"updated_fields,omitempty"`
+	RemovedFields   []string          `protobuf:"bytes,2,rep,name=removed_fields,json=removedFields,proto3" json:"removed_fields,omitempty"`
+	TruncatedArrays []*TruncatedArray `protobuf:"bytes,3,rep,name=truncated_arrays,json=truncatedArrays,proto3" json:"truncated_arrays,omitempty"`
+	// disambiguated_paths is set since MongoDB 6.1.
+	DisambiguatedPaths *Document `protobuf:"bytes,4,opt,name=disambiguated_paths,json=disambiguatedPaths,proto3" json:"disambiguated_paths,omitempty"`
+}
+
+func (x *UpdateDescription) Reset() {
+	*x = UpdateDescription{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_change_stream_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *UpdateDescription) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateDescription) ProtoMessage() {}
+
+func (x *UpdateDescription) ProtoReflect() protoreflect.Message {
+	mi := &file_change_stream_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateDescription.ProtoReflect.Descriptor instead.
+func (*UpdateDescription) Descriptor() ([]byte, []int) {
+	return file_change_stream_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *UpdateDescription) GetUpdatedFields() *Document {
+	if x != nil {
+		return x.UpdatedFields
+	}
+	return nil
+}
+
+func (x *UpdateDescription) GetRemovedFields() []string {
+	if x != nil {
+		return x.RemovedFields
+	}
+	return nil
+}
+
+func (x *UpdateDescription) GetTruncatedArrays() []*TruncatedArray {
+	if x != nil {
+		return x.TruncatedArrays
+	}
+	return nil
+}
+
+func (x *UpdateDescription) GetDisambiguatedPaths() *Document {
+	if x != nil {
+		return x.DisambiguatedPaths
+	}
+	return nil
+}
+
+type TruncatedArray struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Field   string `protobuf:"bytes,1,opt,name=field,proto3" json:"field,omitempty"`
+	NewSize int32  `protobuf:"varint,2,opt,name=new_size,json=newSize,proto3" json:"new_size,omitempty"`
+}
+
+func (x *TruncatedArray) Reset() {
+	*x = TruncatedArray{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_change_stream_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *TruncatedArray) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TruncatedArray) ProtoMessage() {}
+
+func (x *TruncatedArray) ProtoReflect() protoreflect.Message {
+	mi := &file_change_stream_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TruncatedArray.ProtoReflect.Descriptor instead.
+func (*TruncatedArray) Descriptor() ([]byte, []int) {
+	return file_change_stream_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *TruncatedArray) GetField() string {
+	if x != nil {
+		return x.Field
+	}
+	return ""
+}
+
+func (x *TruncatedArray) GetNewSize() int32 {
+	if x != nil {
+		return x.NewSize
+	}
+	return 0
+}
+
+var File_change_stream_proto protoreflect.FileDescriptor
+
+var file_change_stream_proto_rawDesc = []byte{
+	0x0a, 0x13, 0x63, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x5f, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x2e,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x1d, 0x6d, 0x6f, 0x6e, 0x67, 0x6f, 0x73, 0x74, 0x72, 0x65,
+	0x61, 0x6d, 0x65, 0x72, 0x2e, 0x63, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x73, 0x74, 0x72, 0x65, 0x61,
+	0x6d, 0x2e, 0x76, 0x31, 0x1a, 0x1c, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x62, 0x75, 0x66, 0x2f, 0x73, 0x74, 0x72, 0x75, 0x63, 0x74, 0x2e, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x1a, 0x1f, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x62, 0x75, 0x66, 0x2f, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x2e, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x22, 0xf3, 0x07, 0x0a, 0x0b, 0x43, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x45, 0x76,
+	0x65, 0x6e, 0x74, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52,
+	0x02, 0x69, 0x64, 0x12, 0x25, 0x0a, 0x0e, 0x6f, 0x70, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e,
+	0x5f, 0x74, 0x79, 0x70, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0d, 0x6f, 0x70, 0x65,
+	0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x54, 0x79, 0x70, 0x65, 0x12, 0x4d, 0x0a, 0x0c, 0x63, 0x6c,
+	0x75, 0x73, 0x74, 0x65, 0x72, 0x5f, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b,
+	0x32, 0x2a, 0x2e, 0x6d, 0x6f, 0x6e, 0x67, 0x6f, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x65, 0x72,
+	0x2e, 0x63, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x2e, 0x76, 0x31,
+	0x2e, 0x43, 0x6c, 0x75, 0x73, 0x74, 0x65, 0x72, 0x54, 0x69, 0x6d, 0x65, 0x52, 0x0b, 0x63, 0x6c,
+	0x75, 0x73, 0x74, 0x65, 0x72, 0x54, 0x69, 0x6d, 0x65, 0x12, 0x37, 0x0a, 0x09, 0x77, 0x61, 0x6c,
+	0x6c, 0x5f, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67,
+	0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x54,
+	0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x52, 0x08, 0x77, 0x61, 0x6c, 0x6c, 0x54, 0x69,
+	0x6d, 0x65, 0x12, 0x38, 0x0a, 0x02, 0x6e, 0x73, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x28,
+	0x2e, 0x6d, 0x6f, 0x6e, 0x67, 0x6f, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x65, 0x72, 0x2e, 0x63,
+	0x68, 0x61, 0x6e, 0x67, 0x65, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x2e, 0x76, 0x31, 0x2e, 0x4e,
+	0x61, 0x6d, 0x65, 0x73, 0x70, 0x61, 0x63, 0x65, 0x52, 0x02, 0x6e, 0x73, 0x12, 0x38, 0x0a, 0x02,
+	0x74, 0x6f, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x28, 0x2e, 0x6d, 0x6f, 0x6e, 0x67, 0x6f,
+	0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x65, 0x72, 0x2e, 0x63, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x73,
+	0x74, 0x72, 0x65, 0x61, 0x6d, 0x2e, 0x76, 0x31, 0x2e, 0x4e, 0x61, 0x6d, 0x65, 0x73, 0x70, 0x61,
+	0x63, 0x65, 0x52, 0x02, 0x74, 0x6f, 0x12, 0x4a, 0x0a, 0x0c, 0x64, 0x6f, 0x63, 0x75, 0x6d, 0x65,
+	0x6e, 0x74, 0x5f, 0x6b, 0x65, 0x79, 0x18, 0x07, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x27, 0x2e, 0x6d,
+	0x6f, 0x6e, 0x67, 0x6f, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x65, 0x72, 0x2e, 0x63, 0x68, 0x61,
+	0x6e, 0x67, 0x65, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x2e, 0x76, 0x31, 0x2e, 0x44, 0x6f, 0x63,
+	0x75, 0x6d, 0x65, 0x6e, 0x74, 0x52, 0x0b, 0x64, 0x6f, 0x63, 0x75, 0x6d, 0x65, 0x6e, 0x74, 0x4b,
+	0x65, 0x79, 0x12, 0x4c, 0x0a, 0x0d, 0x66, 0x75, 0x6c, 0x6c, 0x5f, 0x64, 0x6f, 0x63, 0x75, 0x6d,
+	0x65, 0x6e, 0x74, 0x18, 0x08, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x27, 0x2e, 0x6d, 0x6f, 0x6e, 0x67,
+	0x6f, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x65, 0x72, 0x2e, 0x63, 0x68, 0x61, 0x6e, 0x67, 0x65,
+	0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x2e, 0x76, 0x31, 0x2e, 0x44, 0x6f, 0x63, 0x75, 0x6d, 0x65,
+	0x6e, 0x74, 0x52, 0x0c, 0x66, 0x75, 0x6c, 0x6c, 0x44, 0x6f, 0x63, 0x75, 0x6d, 0x65, 0x6e, 0x74,
+	0x12, 0x66, 0x0a, 0x1b, 0x66, 0x75, 0x6c, 0x6c, 0x5f, 0x64, 0x6f, 0x63, 0x75, 0x6d, 0x65, 0x6e,
+	0x74, 0x5f, 0x62, 0x65, 0x66, 0x6f, 0x72, 0x65, 0x5f, 0x63, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x18,
+	0x09, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x27, 0x2e, 0x6d, 0x6f, 0x6e, 0x67, 0x6f, 0x73, 0x74, 0x72,
+	0x65, 0x61, 0x6d, 0x65, 0x72, 0x2e, 0x63, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x73, 0x74, 0x72, 0x65,
+	0x61, 0x6d, 0x2e, 0x76, 0x31, 0x2e, 0x44, 0x6f, 0x63, 0x75, 0x6d, 0x65, 0x6e, 0x74, 0x52, 0x18,
+	0x66, 0x75, 0x6c, 0x6c, 0x44, 0x6f, 0x63, 0x75, 0x6d, 0x65, 0x6e, 0x74, 0x42, 0x65, 0x66, 0x6f,
+	0x72, 0x65, 0x43, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x12, 0x5f, 0x0a, 0x12, 0x75, 0x70, 0x64, 0x61,
+	0x74, 0x65, 0x5f, 0x64, 0x65, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x0a,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x30, 0x2e, 0x6d, 0x6f, 0x6e, 0x67, 0x6f, 0x73, 0x74, 0x72, 0x65,
+	0x61, 0x6d, 0x65, 0x72, 0x2e, 0x63, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x73, 0x74, 0x72, 0x65, 0x61,
+	0x6d, 0x2e, 0x76, 0x31, 0x2e, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x44, 0x65, 0x73, 0x63, 0x72,
+	0x69, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x11, 0x75, 0x70, 0x64, 0x61, 0x74, 0x65, 0x44, 0x65,
+	0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x22, 0x0a, 0x0a, 0x74, 0x78, 0x6e,
+	0x5f, 0x6e, 0x75, 0x6d, 0x62, 0x65, 0x72, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x03, 0x48, 0x00, 0x52,
+	0x09, 0x74, 0x78, 0x6e, 0x4e, 0x75, 0x6d, 0x62, 0x65, 0x72, 0x88, 0x01, 0x01, 0x12, 0x3b, 0x0a,
+	0x04, 0x6c, 0x73, 0x69, 0x64, 0x18, 0x0c, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x27, 0x2e, 0x6d, 0x6f,
+	0x6e, 0x67, 0x6f, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x65, 0x72, 0x2e, 0x63, 0x68, 0x61, 0x6e,
+	0x67, 0x65, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x2e, 0x76, 0x31, 0x2e, 0x44, 0x6f, 0x63, 0x75,
+	0x6d, 0x65, 0x6e, 0x74, 0x52, 0x04, 0x6c, 0x73, 0x69, 0x64, 0x12, 0x27, 0x0a, 0x0f, 0x63, 0x6f,
+	0x6c, 0x6c, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x5f, 0x75, 0x75, 0x69, 0x64, 0x18, 0x0d, 0x20,
+	0x01, 0x28, 0x0c, 0x52, 0x0e, 0x63, 0x6f, 0x6c, 0x6c, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x55,
+	0x75, 0x69, 0x64, 0x12, 0x5c, 0x0a, 0x15, 0x6f, 0x70, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e,
+	0x5f, 0x64, 0x65, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x0e, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x27, 0x2e, 0x6d, 0x6f, 0x6e, 0x67, 0x6f, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d,
+	0x65, 0x72, 0x2e, 0x63, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x2e,
+	0x76, 0x31, 0x2e, 0x44, 0x6f, 0x63, 0x75, 0x6d, 0x65, 0x6e, 0x74, 0x52, 0x14, 0x6f, 0x70, 0x65,
+	0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x44, 0x65, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x69, 0x6f,
+	0x6e, 0x12, 0x57, 0x0a, 0x13, 0x73, 0x74, 0x61, 0x74, 0x65, 0x5f, 0x62, 0x65, 0x66, 0x6f, 0x72,
+	0x65, 0x5f, 0x63, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x18, 0x0f, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x27,
+	0x2e, 0x6d, 0x6f, 0x6e, 0x67, 0x6f, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x65, 0x72, 0x2e, 0x63,
+	0x68, 0x61, 0x6e, 0x67, 0x65, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x2e, 0x76, 0x31, 0x2e, 0x44,
+	0x6f, 0x63, 0x75, 0x6d, 0x65, 0x6e, 0x74, 0x52, 0x11, 0x73, 0x74, 0x61, 0x74, 0x65, 0x42, 0x65,
+	0x66, 0x6f, 0x72, 0x65, 0x43, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x42, 0x0d, 0x0a, 0x0b, 0x5f, 0x74,
+	0x78, 0x6e, 0x5f, 0x6e, 0x75, 0x6d, 0x62, 0x65, 0x72, 0x22, 0x5c, 0x0a, 0x08, 0x44, 0x6f, 0x63,
+	0x75, 0x6d, 0x65, 0x6e, 0x74, 0x12, 0x14, 0x0a, 0x04, 0x62, 0x73, 0x6f, 0x6e, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x0c, 0x48, 0x00, 0x52, 0x04, 0x62, 0x73, 0x6f, 0x6e, 0x12, 0x31, 0x0a, 0x06, 0x73,
+	0x74, 0x72, 0x75, 0x63, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x17, 0x2e, 0x67, 0x6f,
+	0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x53, 0x74,
+	0x72, 0x75, 0x63, 0x74, 0x48, 0x00, 0x52, 0x06, 0x73, 0x74, 0x72, 0x75, 0x63, 0x74, 0x42, 0x07,
+	0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x22, 0x29, 0x0a, 0x0b, 0x43, 0x6c, 0x75, 0x73, 0x74,
+	0x65, 0x72, 0x54, 0x69, 0x6d, 0x65, 0x12, 0x0c, 0x0a, 0x01, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x0d, 0x52, 0x01, 0x74, 0x12, 0x0c, 0x0a, 0x01, 0x69, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52,
+	0x01, 0x69, 0x22, 0x2f, 0x0a, 0x09, 0x4e, 0x61, 0x6d, 0x65, 0x73, 0x70, 0x61, 0x63, 0x65, 0x12,
+	0x0e, 0x0a, 0x02, 0x64, 0x62, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x64, 0x62, 0x12,
+	0x12, 0x0a, 0x04, 0x63, 0x6f, 0x6c, 0x6c, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x63,
+	0x6f, 0x6c, 0x6c, 0x22, 0xbe, 0x02, 0x0a, 0x11, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x44, 0x65,
+	0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x4e, 0x0a, 0x0e, 0x75, 0x70, 0x64,
+	0x61, 0x74, 0x65, 0x64, 0x5f, 0x66, 0x69, 0x65, 0x6c, 0x64, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x0b, 0x32, 0x27, 0x2e, 0x6d, 0x6f, 0x6e, 0x67, 0x6f, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x65,
+	0x72, 0x2e, 0x63, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x2e, 0x76,
+	0x31, 0x2e, 0x44, 0x6f, 0x63, 0x75, 0x6d, 0x65, 0x6e, 0x74, 0x52, 0x0d, 0x75, 0x70, 0x64, 0x61,
+	0x74, 0x65, 0x64, 0x46, 0x69, 0x65, 0x6c, 0x64, 0x73, 0x12, 0x25, 0x0a, 0x0e, 0x72, 0x65, 0x6d,
+	0x6f, 0x76, 0x65, 0x64, 0x5f, 0x66, 0x69, 0x65, 0x6c, 0x64, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28,
+	0x09, 0x52, 0x0d, 0x72, 0x65, 0x6d, 0x6f, 0x76, 0x65, 0x64, 0x46, 0x69, 0x65, 0x6c, 0x64, 0x73,
+	0x12, 0x58, 0x0a, 0x10, 0x74, 0x72, 0x75, 0x6e, 0x63, 0x61, 0x74, 0x65, 0x64, 0x5f, 0x61, 0x72,
+	0x72, 0x61, 0x79, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x2d, 0x2e, 0x6d, 0x6f, 0x6e,
+	0x67, 0x6f, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x65, 0x72, 0x2e, 0x63, 0x68, 0x61, 0x6e, 0x67,
+	0x65, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x2e, 0x76, 0x31, 0x2e, 0x54, 0x72, 0x75, 0x6e, 0x63,
+	0x61, 0x74, 0x65, 0x64, 0x41, 0x72, 0x72, 0x61, 0x79, 0x52, 0x0f, 0x74, 0x72, 0x75, 0x6e, 0x63,
+	0x61, 0x74, 0x65, 0x64, 0x41, 0x72, 0x72, 0x61, 0x79, 0x73, 0x12, 0x58, 0x0a, 0x13, 0x64, 0x69,
+	0x73, 0x61, 0x6d, 0x62, 0x69, 0x67, 0x75, 0x61, 0x74, 0x65, 0x64, 0x5f, 0x70, 0x61, 0x74, 0x68,
+	0x73, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x27, 0x2e, 0x6d, 0x6f, 0x6e, 0x67, 0x6f, 0x73,
+	0x74, 0x72, 0x65, 0x61, 0x6d, 0x65, 0x72, 0x2e, 0x63, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x73, 0x74,
+	0x72, 0x65, 0x61, 0x6d, 0x2e, 0x76, 0x31, 0x2e, 0x44, 0x6f, 0x63, 0x75, 0x6d, 0x65, 0x6e, 0x74,
+	0x52, 0x12, 0x64, 0x69, 0x73, 0x61, 0x6d, 0x62, 0x69, 0x67, 0x75, 0x61, 0x74, 0x65, 0x64, 0x50,
+	0x61, 0x74, 0x68, 0x73, 0x22, 0x41, 0x0a, 0x0e, 0x54, 0x72, 0x75, 0x6e, 0x63, 0x61, 0x74, 0x65,
+	0x64, 0x41, 0x72, 0x72, 0x61, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x66, 0x69, 0x65, 0x6c, 0x64, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x66, 0x69, 0x65, 0x6c, 0x64, 0x12, 0x19, 0x0a, 0x08,
+	0x6e, 0x65, 0x77, 0x5f, 0x73, 0x69, 0x7a, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x07,
+	0x6e, 0x65, 0x77, 0x53, 0x69, 0x7a, 0x65, 0x42, 0x3e, 0x5a, 0x3c, 0x67, 0x69, 0x74, 0x68, 0x75,
+	0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x75, 0x63, 0x70, 0x72, 0x2f, 0x6d, 0x6f, 0x6e, 0x67, 0x6f,
+	0x2d, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x65, 0x72, 0x2f, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x6e,
+	0x61, 0x6c, 0x2f, 0x6d, 0x6f, 0x64, 0x65, 0x6c, 0x2f, 0x63, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x73,
+	0x74, 0x72, 0x65, 0x61, 0x6d, 0x70, 0x62, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_change_stream_proto_rawDescOnce sync.Once
+	file_change_stream_proto_rawDescData = file_change_stream_proto_rawDesc
+)
+
+func file_change_stream_proto_rawDescGZIP() []byte {
+	file_change_stream_proto_rawDescOnce.Do(func() {
+		file_change_stream_proto_rawDescData = protoimpl.X.CompressGZIP(file_change_stream_proto_rawDescData)
+	})
+	return file_change_stream_proto_rawDescData
+}
+
+var file_change_stream_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_change_stream_proto_goTypes = []interface{}{
+	(*ChangeEvent)(nil),           // 0: mongostreamer.changestream.v1.ChangeEvent
+	(*Document)(nil),              // 1: mongostreamer.changestream.v1.Document
+	(*ClusterTime)(nil),           // 2: mongostreamer.changestream.v1.ClusterTime
+	(*Namespace)(nil),             // 3: mongostreamer.changestream.v1.Namespace
+	(*UpdateDescription)(nil),     // 4: mongostreamer.changestream.v1.UpdateDescription
+	(*TruncatedArray)(nil),        // 5: mongostreamer.changestream.v1.TruncatedArray
+	(*timestamppb.Timestamp)(nil), // 6: google.protobuf.Timestamp
+	(*structpb.Struct)(nil),       // 7: google.protobuf.Struct
+}
+var file_change_stream_proto_depIdxs = []int32{
+	2,  // 0: mongostreamer.changestream.v1.ChangeEvent.cluster_time:type_name -> mongostreamer.changestream.v1.ClusterTime
+	6,  // 1: mongostreamer.changestream.v1.ChangeEvent.wall_time:type_name -> google.protobuf.Timestamp
+	3,  // 2: mongostreamer.changestream.v1.ChangeEvent.ns:type_name -> mongostreamer.changestream.v1.Namespace
+	3,  // 3: mongostreamer.changestream.v1.ChangeEvent.to:type_name -> mongostreamer.changestream.v1.Namespace
+	1,  // 4: mongostreamer.changestream.v1.ChangeEvent.document_key:type_name -> mongostreamer.changestream.v1.Document
+	1,  // 5: mongostreamer.changestream.v1.ChangeEvent.full_document:type_name -> mongostreamer.changestream.v1.Document
+	1,  // 6: mongostreamer.changestream.v1.ChangeEvent.full_document_before_change:type_name -> mongostreamer.changestream.v1.Document
+	4,  // 7: mongostreamer.changestream.v1.ChangeEvent.update_description:type_name -> mongostreamer.changestream.v1.UpdateDescription
+	1,  // 8: mongostreamer.changestream.v1.ChangeEvent.lsid:type_name -> mongostreamer.changestream.v1.Document
+	1,  // 9: mongostreamer.changestream.v1.ChangeEvent.operation_description:type_name -> mongostreamer.changestream.v1.Document
+	1,  // 10: mongostreamer.changestream.v1.ChangeEvent.state_before_change:type_name -> mongostreamer.changestream.v1.Document
+	7,  // 11: mongostreamer.changestream.v1.Document.struct:type_name -> google.protobuf.Struct
+	1,  // 12: mongostreamer.changestream.v1.UpdateDescription.updated_fields:type_name -> mongostreamer.changestream.v1.Document
+	5,  // 13: mongostreamer.changestream.v1.UpdateDescription.truncated_arrays:type_name -> mongostreamer.changestream.v1.TruncatedArray
+	1,  // 14: mongostreamer.changestream.v1.UpdateDescription.disambiguated_paths:type_name -> mongostreamer.changestream.v1.Document
+	15, // [15:15] is the sub-list for method output_type
+	15, // [15:15] is the sub-list for method input_type
+	15, // [15:15] is the sub-list for extension type_name
+	15, // [15:15] is the sub-list for extension extendee
+	0,  // [0:15] is the sub-list for field type_name
+}
+
+func init() { file_change_stream_proto_init() }
+func file_change_stream_proto_init() {
+	if File_change_stream_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_change_stream_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ChangeEvent); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_change_stream_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Document); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_change_stream_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ClusterTime); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_change_stream_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Namespace); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_change_stream_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*UpdateDescription); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_change_stream_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*TruncatedArray); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	file_change_stream_proto_msgTypes[0].OneofWrappers = []interface{}{}
+	file_change_stream_proto_msgTypes[1].OneofWrappers = []interface{}{
+		(*Document_Bson)(nil),
+		(*Document_Struct)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_change_stream_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   6,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_change_stream_proto_goTypes,
+		DependencyIndexes: file_change_stream_proto_depIdxs,
+		MessageInfos:      file_change_stream_proto_msgTypes,
+	}.Build()
+	File_change_stream_proto = out.File
+	file_change_stream_proto_rawDesc = nil
+	file_change_stream_proto_goTypes = nil
+	file_change_stream_proto_depIdxs = nil
+}
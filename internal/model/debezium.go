@@ -0,0 +1,186 @@
+package model
+
+import (
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/ucpr/mongo-streamer/pkg/stamp"
+)
+
+// ErrDebeziumUnsupportedOperation is returned for events that the Debezium MongoDB
+// connector does not emit, e.g. drop or rename. The handler skips them without publishing.
+var ErrDebeziumUnsupportedOperation = errors.New("model: operation is not supported by the debezium format")
+
+// Debezium operation codes.
+const (
+	debeziumOpCreate = "c"
+	debeziumOpUpdate = "u"
+	debeziumOpDelete = "d"
+	debeziumOpRead   = "r"
+)
+
+type (
+	// debeziumEvent is the payload of an event of the Debezium MongoDB connector.
+	// Documents are encoded as relaxed Extended JSON strings like the connector.
+	debeziumEvent struct {
+		Before *string        `json:"before"`
+		After  *string        `json:"after"`
+		Patch  *string        `json:"patch"`
+		Filter *string        `json:"filter"`
+		Source debeziumSource `json:"source"`
+		Op     string         `json:"op"`
+		TsMs   int64          `json:"ts_ms"`
+	}
+
+	// debeziumSource is the source metadata of the event.
+	debeziumSource struct {
+		Version    string  `json:"version"`
+		Connector  string  `json:"connector"`
+		Name       string  `json:"name"`
+		TsMs       int64   `json:"ts_ms"`
+		Snapshot   string  `json:"snapshot"`
+		DB         string  `json:"db"`
+		Collection string  `json:"collection"`
+		Ord        uint32  `json:"ord"`
+		LSID       *string `json:"lsid"`
+		TxnNumber  *int64  `json:"txnNumber"`
+		WallTime   *int64  `json:"wallTime"`
+	}
+)
+
+// DebeziumEncoder encodes change stream events as the payload of Debezium MongoDB connector events.
+type DebeziumEncoder struct {
+	name string
+	now  func() time.Time
+}
+
+// NewDebeziumEncoder creates a new Debezium encoder, name is the logical name of the connector.
+func NewDebeziumEncoder(name string) *DebeziumEncoder {
+	return &DebeziumEncoder{name: name, now: time.Now}
+}
+
+// Encode returns the Debezium payload of the change stream event.
+func (d *DebeziumEncoder) Encode(c ChangeEvent) ([]byte, error) {
+	e, err := c.debezium(d.name, d.now())
+	if err != nil {
+		return nil, err
+	}
+	return encodeJSON(e)
+}
+
+// ContentType returns the media type of JSON.
+func (d *DebeziumEncoder) ContentType() string {
+	return "application/json"
+}
+
+// Debezium returns the change stream event as the payload of a Debezium MongoDB connector event.
+// name is the logical name of the connector and now is the time the event is processed.
+func (c ChangeEvent) Debezium(name string, now time.Time) ([]byte, error) {
+	d := &DebeziumEncoder{name: name, now: func() time.Time { return now }}
+	return d.Encode(c)
+}
+
+// debezium converts the change event to the Debezium payload.
+func (c ChangeEvent) debezium(name string, now time.Time) (debeziumEvent, error) {
+	e := debeziumEvent{
+		Source: debeziumSource{
+			Version:    stamp.BuildVersion,
+			Connector:  "mongodb",
+			Name:       name,
+			TsMs:       int64(c.ClusterTime.T) * 1000,
+			Snapshot:   "false",
+			DB:         c.Namespace.DB,
+			Collection: c.Namespace.Coll,
+			Ord:        c.ClusterTime.I,
+			TxnNumber:  c.TxnNumber,
+		},
+		TsMs: now.UnixMilli(),
+	}
+	docs := []struct {
+		dst **string
+		doc bson.Raw
+	}{
+		{dst: &e.Before, doc: c.FullDocumentBeforeChange},
+		{dst: &e.After, doc: c.FullDocument},
+		{dst: &e.Filter, doc: c.DocumentKey},
+		{dst: &e.Source.LSID, doc: c.LSID},
+	}
+	for _, d := range docs {
+		s, err := relaxedExtJSON(d.doc)
+		if err != nil {
+			return debeziumEvent{}, err
+		}
+		*d.dst = s
+	}
+	if c.WallTime != nil {
+		ms := c.WallTime.UnixMilli()
+		e.Source.WallTime = &ms
+	}
+
+	switch c.OperationType {
+	case "insert":
+		e.Op = debeziumOpCreate
+		// inserts have no filter in the connector
+		e.Filter = nil
+	case "update":
+		e.Op = debeziumOpUpdate
+		patch, err := debeziumPatch(c.UpdateDescription)
+		if err != nil {
+			return debeziumEvent{}, err
+		}
+		e.Patch = patch
+	case "replace":
+		e.Op = debeziumOpUpdate
+	case "delete":
+		e.Op = debeziumOpDelete
+	case OperationTypeSnapshot:
+		e.Op = debeziumOpRead
+		e.Source.Snapshot = "true"
+		e.Filter = nil
+	default:
+		return debeziumEvent{}, ErrDebeziumUnsupportedOperation
+	}
+
+	return e, nil
+}
+
+// debeziumPatch returns the update description as an update document with
+// $set and $unset operators.
+func debeziumPatch(u *UpdateDescription) (*string, error) {
+	if u == nil {
+		return nil, nil
+	}
+
+	patch := bson.D{}
+	if len(u.UpdatedFields) > 0 {
+		patch = append(patch, bson.E{Key: "$set", Value: u.UpdatedFields})
+	}
+	if len(u.RemovedFields) > 0 {
+		unset := make(bson.D, 0, len(u.RemovedFields))
+		for _, f := range u.RemovedFields {
+			unset = append(unset, bson.E{Key: f, Value: true})
+		}
+		patch = append(patch, bson.E{Key: "$unset", Value: unset})
+	}
+
+	b, err := bson.Marshal(patch)
+	if err != nil {
+		return nil, err
+	}
+	return relaxedExtJSON(b)
+}
+
+// relaxedExtJSON returns the document as a relaxed Extended JSON string, or nil if it is empty.
+func relaxedExtJSON(doc bson.Raw) (*string, error) {
+	if len(doc) == 0 {
+		return nil, nil
+	}
+	b, err := bson.MarshalExtJSON(doc, false, false)
+	if err != nil {
+		return nil, err
+	}
+	s := string(b)
+	return &s, nil
+}
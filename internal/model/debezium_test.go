@@ -0,0 +1,68 @@
+package model
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestChangeEvent_Debezium(t *testing.T) {
+	t.Parallel()
+
+	now := time.UnixMilli(1705555660000)
+	events := loadEvents(t)
+
+	patterns := []struct {
+		name  string
+		event string
+		err   error
+	}{
+		{name: "insert", event: "insert"},
+		{name: "update", event: "update"},
+		{name: "replace", event: "replace"},
+		{name: "delete", event: "delete"},
+		{name: "update in transaction", event: "update_in_transaction"},
+		{name: "snapshot", event: "snapshot"},
+		{name: "drop", event: "drop", err: ErrDebeziumUnsupportedOperation},
+		{name: "rename", event: "rename", err: ErrDebeziumUnsupportedOperation},
+		{name: "drop database", event: "drop_database", err: ErrDebeziumUnsupportedOperation},
+		{name: "invalidate", event: "invalidate", err: ErrDebeziumUnsupportedOperation},
+		{name: "create indexes", event: "create_indexes", err: ErrDebeziumUnsupportedOperation},
+	}
+
+	for _, tt := range patterns {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			raw, ok := events[tt.event]
+			require.True(t, ok)
+			var ev ChangeEvent
+			require.NoError(t, bson.Unmarshal(raw, &ev))
+
+			got, err := ev.Debezium("fulfillment", now)
+			assert.ErrorIs(t, err, tt.err)
+			if tt.err != nil {
+				return
+			}
+
+			assertGolden(t, filepath.Join("testdata", "debezium", tt.event+".golden.json"), got)
+
+			// the documents are valid Extended JSON.
+			var payload debeziumEvent
+			require.NoError(t, json.Unmarshal(got, &payload))
+			for _, s := range []*string{payload.Before, payload.After, payload.Patch, payload.Filter, payload.Source.LSID} {
+				if s == nil {
+					continue
+				}
+				var doc bson.Raw
+				assert.NoError(t, bson.UnmarshalExtJSON([]byte(*s), false, &doc))
+			}
+		})
+	}
+}
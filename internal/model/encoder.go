@@ -0,0 +1,118 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Encoder encodes change stream events into messages of a publish format.
+// An encoder is created once per stream and is safe for concurrent use.
+type Encoder interface {
+	// Encode returns the encoded change stream event. The returned slice is
+	// owned by the caller.
+	Encode(c ChangeEvent) ([]byte, error)
+	// ContentType returns the media type of the encoded events.
+	ContentType() string
+}
+
+// Ensure that the encoders implement Encoder.
+//
+//nolint:gochecknoglobals
+var (
+	_ Encoder = (*JSONEncoder)(nil)
+	_ Encoder = (*ExtJSONEncoder)(nil)
+	_ Encoder = (*AvroEncoder)(nil)
+	_ Encoder = (*DebeziumEncoder)(nil)
+	_ Encoder = (*ProtobufEncoder)(nil)
+)
+
+const (
+	// initialBufferSize is the capacity of a new pooled buffer.
+	initialBufferSize = 1 << 10
+	// maxPooledBufferSize is the capacity above which a buffer is not returned
+	// to the pool, so that a large event does not pin memory.
+	maxPooledBufferSize = 1 << 16
+)
+
+//nolint:gochecknoglobals
+var bufferPool = sync.Pool{
+	New: func() any {
+		return bytes.NewBuffer(make([]byte, 0, initialBufferSize))
+	},
+}
+
+// encodeWithBuffer runs encode with a pooled buffer and returns a copy of the result.
+func encodeWithBuffer(encode func(buf *bytes.Buffer) error) ([]byte, error) {
+	buf, _ := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer func() {
+		if buf.Cap() <= maxPooledBufferSize {
+			bufferPool.Put(buf)
+		}
+	}()
+
+	if err := encode(buf); err != nil {
+		return nil, err
+	}
+	return bytes.Clone(buf.Bytes()), nil
+}
+
+// encodeJSON returns the json encoding of v like json.Marshal.
+func encodeJSON(v any) ([]byte, error) {
+	return encodeWithBuffer(func(buf *bytes.Buffer) error {
+		if err := json.NewEncoder(buf).Encode(v); err != nil {
+			return err
+		}
+		// Encode terminates the value with a newline.
+		buf.Truncate(buf.Len() - 1)
+		return nil
+	})
+}
+
+// JSONEncoder encodes change stream events as JSON.
+type JSONEncoder struct{}
+
+// NewJSONEncoder creates a new JSON encoder.
+func NewJSONEncoder() *JSONEncoder {
+	return &JSONEncoder{}
+}
+
+// Encode returns the json encoded byte array of the change stream event.
+func (e *JSONEncoder) Encode(c ChangeEvent) ([]byte, error) {
+	return encodeJSON(c)
+}
+
+// ContentType returns the media type of JSON.
+func (e *JSONEncoder) ContentType() string {
+	return "application/json"
+}
+
+// ExtJSONEncoder encodes change stream events as MongoDB Extended JSON v2.
+type ExtJSONEncoder struct {
+	canonical bool
+}
+
+// NewExtJSONEncoder creates a new Extended JSON encoder of the canonical or relaxed mode.
+func NewExtJSONEncoder(canonical bool) *ExtJSONEncoder {
+	return &ExtJSONEncoder{canonical: canonical}
+}
+
+// Encode returns the Extended JSON encoded byte array of the change stream event.
+func (e *ExtJSONEncoder) Encode(c ChangeEvent) ([]byte, error) {
+	return encodeWithBuffer(func(buf *bytes.Buffer) error {
+		b, err := bson.MarshalExtJSONAppend(buf.AvailableBuffer(), c, e.canonical, false)
+		if err != nil {
+			return err
+		}
+		buf.Write(b)
+		return nil
+	})
+}
+
+// ContentType returns the media type of JSON.
+func (e *ExtJSONEncoder) ContentType() string {
+	return "application/json"
+}
@@ -0,0 +1,128 @@
+package model
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hamba/avro/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func testEncoders(t testing.TB) map[string]Encoder {
+	t.Helper()
+
+	avroEncoder, err := NewAvroEncoder()
+	require.NoError(t, err)
+	debeziumEncoder := NewDebeziumEncoder("fulfillment")
+	debeziumEncoder.now = func() time.Time { return time.UnixMilli(1705555660000) }
+
+	return map[string]Encoder{
+		"json":              NewJSONEncoder(),
+		"avro":              avroEncoder,
+		"canonical_extjson": NewExtJSONEncoder(true),
+		"relaxed_extjson":   NewExtJSONEncoder(false),
+		"debezium":          debeziumEncoder,
+		"protobuf":          NewProtobufEncoder(false),
+		"protobuf_struct":   NewProtobufEncoder(true),
+	}
+}
+
+func TestEncoder_Concurrent(t *testing.T) {
+	t.Parallel()
+
+	events := make([]ChangeEvent, 0)
+	for _, raw := range loadEvents(t) {
+		var ev ChangeEvent
+		require.NoError(t, bson.Unmarshal(raw, &ev))
+		if ev.OperationType == "insert" || ev.OperationType == "update" || ev.OperationType == "delete" {
+			events = append(events, ev)
+		}
+	}
+
+	for name, enc := range testEncoders(t) {
+		name, enc := name, enc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			want := make([][]byte, len(events))
+			for i, ev := range events {
+				b, err := enc.Encode(ev)
+				require.NoError(t, err)
+				want[i] = b
+			}
+
+			// the encoded events are not overwritten by pooled buffers
+			var wg sync.WaitGroup
+			got := make([][][]byte, 8)
+			for g := range got {
+				g := g
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for _, ev := range events {
+						b, err := enc.Encode(ev)
+						assert.NoError(t, err)
+						got[g] = append(got[g], b)
+					}
+				}()
+			}
+			wg.Wait()
+			for _, g := range got {
+				assert.Equal(t, want, g)
+			}
+		})
+	}
+}
+
+func TestEncoder_ContentType(t *testing.T) {
+	t.Parallel()
+
+	want := map[string]string{
+		"json":              "application/json",
+		"avro":              "application/avro",
+		"canonical_extjson": "application/json",
+		"relaxed_extjson":   "application/json",
+		"debezium":          "application/json",
+		"protobuf":          "application/protobuf",
+		"protobuf_struct":   "application/protobuf",
+	}
+	for name, enc := range testEncoders(t) {
+		assert.Equal(t, want[name], enc.ContentType(), name)
+	}
+}
+
+func BenchmarkEncoder(b *testing.B) {
+	var ev ChangeEvent
+	if err := bson.Unmarshal(loadEvents(b)["update"], &ev); err != nil {
+		b.Fatal(err)
+	}
+
+	for name, enc := range testEncoders(b) {
+		enc := enc
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := enc.Encode(ev); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+
+	// the schema used to be parsed for every event
+	b.Run("avro_parse_per_event", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			schema, err := avro.Parse(avroSchema)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if _, err := avro.Marshal(schema, ev.avro()); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
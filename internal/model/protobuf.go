@@ -0,0 +1,161 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/ucpr/mongo-streamer/internal/model/changestreampb"
+)
+
+// ProtobufEncoder encodes change stream events as the ChangeEvent message of
+// schema/change_stream.proto.
+type ProtobufEncoder struct {
+	structDocuments bool
+}
+
+// NewProtobufEncoder creates a new protocol buffers encoder. Documents are carried
+// as raw BSON, or as google.protobuf.Struct of their relaxed Extended JSON if
+// structDocuments is true. Struct numbers are doubles, so large integers may lose precision.
+func NewProtobufEncoder(structDocuments bool) *ProtobufEncoder {
+	return &ProtobufEncoder{structDocuments: structDocuments}
+}
+
+// Encode returns the protocol buffers encoded byte array of the change stream event.
+func (p *ProtobufEncoder) Encode(c ChangeEvent) ([]byte, error) {
+	return encodeWithBuffer(func(buf *bytes.Buffer) error {
+		msg, err := p.changeEvent(c)
+		if err != nil {
+			return err
+		}
+		b, err := proto.MarshalOptions{Deterministic: true}.MarshalAppend(buf.AvailableBuffer(), msg)
+		if err != nil {
+			return err
+		}
+		buf.Write(b)
+		return nil
+	})
+}
+
+// ContentType returns the media type of protocol buffers.
+func (p *ProtobufEncoder) ContentType() string {
+	return "application/protobuf"
+}
+
+// Protobuf returns the protocol buffers encoded byte array of the change stream event,
+// see NewProtobufEncoder.
+func (c ChangeEvent) Protobuf(structDocuments bool) ([]byte, error) {
+	return NewProtobufEncoder(structDocuments).Encode(c)
+}
+
+// changeEvent returns the ChangeEvent message of the change stream event.
+func (p *ProtobufEncoder) changeEvent(c ChangeEvent) (*changestreampb.ChangeEvent, error) {
+	msg := &changestreampb.ChangeEvent{
+		Id:            c.ID,
+		OperationType: c.OperationType,
+		ClusterTime:   &changestreampb.ClusterTime{T: c.ClusterTime.T, I: c.ClusterTime.I},
+		TxnNumber:     c.TxnNumber,
+	}
+	if c.WallTime != nil {
+		msg.WallTime = timestamppb.New(*c.WallTime)
+	}
+	if !c.Namespace.IsZero() {
+		msg.Ns = protoNamespace(c.Namespace)
+	}
+	if c.To != nil {
+		msg.To = protoNamespace(*c.To)
+	}
+	if c.CollectionUUID != nil {
+		msg.CollectionUuid = c.CollectionUUID.Data
+	}
+
+	docs := []struct {
+		dst **changestreampb.Document
+		doc bson.Raw
+	}{
+		{dst: &msg.DocumentKey, doc: c.DocumentKey},
+		{dst: &msg.FullDocument, doc: c.FullDocument},
+		{dst: &msg.FullDocumentBeforeChange, doc: c.FullDocumentBeforeChange},
+		{dst: &msg.Lsid, doc: c.LSID},
+		{dst: &msg.OperationDescription, doc: c.OperationDescription},
+		{dst: &msg.StateBeforeChange, doc: c.StateBeforeChange},
+	}
+	for _, d := range docs {
+		doc, err := p.document(d.doc)
+		if err != nil {
+			return nil, err
+		}
+		*d.dst = doc
+	}
+
+	if u := c.UpdateDescription; u != nil {
+		ud, err := p.updateDescription(*u)
+		if err != nil {
+			return nil, err
+		}
+		msg.UpdateDescription = ud
+	}
+
+	return msg, nil
+}
+
+// document returns the Document message of the BSON document, or nil if it is empty.
+func (p *ProtobufEncoder) document(doc bson.Raw) (*changestreampb.Document, error) {
+	if len(doc) == 0 {
+		return nil, nil
+	}
+	if !p.structDocuments {
+		return &changestreampb.Document{Value: &changestreampb.Document_Bson{Bson: doc}}, nil
+	}
+
+	s, err := protoStruct(doc)
+	if err != nil {
+		return nil, err
+	}
+	return &changestreampb.Document{Value: &changestreampb.Document_Struct{Struct: s}}, nil
+}
+
+func (p *ProtobufEncoder) updateDescription(u UpdateDescription) (*changestreampb.UpdateDescription, error) {
+	updated, err := p.document(u.UpdatedFields)
+	if err != nil {
+		return nil, err
+	}
+	paths, err := p.document(u.DisambiguatedPaths)
+	if err != nil {
+		return nil, err
+	}
+
+	ud := &changestreampb.UpdateDescription{
+		UpdatedFields:      updated,
+		RemovedFields:      u.RemovedFields,
+		DisambiguatedPaths: paths,
+	}
+	for _, t := range u.TruncatedArrays {
+		ud.TruncatedArrays = append(ud.TruncatedArrays, &changestreampb.TruncatedArray{
+			Field:   t.Field,
+			NewSize: t.NewSize,
+		})
+	}
+	return ud, nil
+}
+
+// protoStruct returns the google.protobuf.Struct of the relaxed Extended JSON of the document.
+func protoStruct(doc bson.Raw) (*structpb.Struct, error) {
+	j, err := bson.MarshalExtJSON(doc, false, false)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]any
+	if err := json.Unmarshal(j, &m); err != nil {
+		return nil, err
+	}
+	return structpb.NewStruct(m)
+}
+
+func protoNamespace(ns Namespace) *changestreampb.Namespace {
+	return &changestreampb.Namespace{Db: ns.DB, Coll: ns.Coll}
+}
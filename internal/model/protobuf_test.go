@@ -0,0 +1,70 @@
+package model
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/ucpr/mongo-streamer/internal/model/changestreampb"
+)
+
+func TestChangeEvent_Protobuf(t *testing.T) {
+	t.Parallel()
+
+	for name, raw := range loadEvents(t) {
+		name, raw := name, raw
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var ev ChangeEvent
+			require.NoError(t, bson.Unmarshal(raw, &ev))
+
+			b, err := ev.Protobuf(false)
+			require.NoError(t, err)
+
+			// the encoded event is decoded with the schema without unknown fields.
+			var msg changestreampb.ChangeEvent
+			require.NoError(t, proto.Unmarshal(b, &msg))
+			assert.Empty(t, msg.ProtoReflect().GetUnknown())
+
+			got, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(&msg)
+			require.NoError(t, err)
+			assertGolden(t, filepath.Join("testdata", "events", name+".protobuf.golden.json"), got)
+		})
+	}
+}
+
+func TestChangeEvent_Protobuf_Struct(t *testing.T) {
+	t.Parallel()
+
+	events := loadEvents(t)
+
+	var ev ChangeEvent
+	require.NoError(t, bson.Unmarshal(events["update"], &ev))
+
+	b, err := ev.Protobuf(true)
+	require.NoError(t, err)
+
+	var msg changestreampb.ChangeEvent
+	require.NoError(t, proto.Unmarshal(b, &msg))
+
+	// documents are carried as structs of their relaxed extended json.
+	got := msg.GetFullDocument().GetStruct()
+	require.NotNil(t, got)
+
+	want, err := structpb.NewStruct(map[string]any{
+		"_id":   map[string]any{"$oid": "65a8b6c0f1e2d3c4b5a69788"},
+		"text":  "Hello, World!",
+		"count": 2,
+		"tags":  []any{"a"},
+		"a":     map[string]any{"0": 1},
+	})
+	require.NoError(t, err)
+	assert.True(t, proto.Equal(want, got))
+}
@@ -0,0 +1,140 @@
+package mongo
+
+import (
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// changeStreamStages are the aggregation stages allowed in a change stream pipeline.
+//
+//nolint:gochecknoglobals
+var changeStreamStages = map[string]struct{}{
+	"$addFields":   {},
+	"$match":       {},
+	"$project":     {},
+	"$redact":      {},
+	"$replaceRoot": {},
+	"$replaceWith": {},
+	"$set":         {},
+	"$unset":       {},
+}
+
+// ParsePipeline parses an aggregation pipeline to apply to the change stream
+// from an Extended JSON array of stages, e.g. `[{"$match": {"operationType": "insert"}}]`.
+// An empty string is an empty pipeline.
+//
+// Only the stages allowed in a change stream are accepted, and the stages must
+// not modify _id, which is the resume token of the event.
+func ParsePipeline(s string) (mongo.Pipeline, error) {
+	if s == "" {
+		return mongo.Pipeline{}, nil
+	}
+
+	var stages []bson.D
+	if err := bson.UnmarshalExtJSON([]byte(`{"stages":`+s+`}`), false, &struct {
+		Stages *[]bson.D `bson:"stages"`
+	}{Stages: &stages}); err != nil {
+		return nil, fmt.Errorf("invalid pipeline: %w", err)
+	}
+
+	pipeline := make(mongo.Pipeline, 0, len(stages))
+	for i, stage := range stages {
+		if err := validateStage(stage); err != nil {
+			return nil, fmt.Errorf("invalid pipeline stage %d: %w", i, err)
+		}
+		pipeline = append(pipeline, stage)
+	}
+	return pipeline, nil
+}
+
+// validateStage validates a stage of a change stream pipeline.
+func validateStage(stage bson.D) error {
+	if len(stage) != 1 {
+		return fmt.Errorf("a stage must have exactly one field, got %d", len(stage))
+	}
+	name, spec := stage[0].Key, stage[0].Value
+	if _, ok := changeStreamStages[name]; !ok {
+		return fmt.Errorf("stage %s is not allowed in a change stream", name)
+	}
+
+	if modifiesID(name, spec) {
+		return fmt.Errorf("stage %s must not modify _id, the resume token", name)
+	}
+	return nil
+}
+
+// modifiesID reports whether the stage removes or overwrites _id.
+func modifiesID(name string, spec interface{}) bool {
+	switch name {
+	case "$replaceRoot", "$replaceWith":
+		return true
+	case "$addFields", "$set":
+		doc, ok := spec.(bson.D)
+		return ok && hasKey(doc, "_id")
+	case "$project":
+		doc, ok := spec.(bson.D)
+		if !ok {
+			return false
+		}
+		for _, e := range doc {
+			if e.Key != "_id" {
+				continue
+			}
+			switch v := e.Value.(type) {
+			case bool:
+				return !v
+			case int32:
+				return v == 0
+			case int64:
+				return v == 0
+			case float64:
+				return v == 0
+			default:
+				// _id is replaced by an expression
+				return true
+			}
+		}
+		return false
+	case "$unset":
+		switch v := spec.(type) {
+		case string:
+			return v == "_id"
+		case bson.A:
+			for _, f := range v {
+				if f == "_id" {
+					return true
+				}
+			}
+		}
+		return false
+	default:
+		return false
+	}
+}
+
+func hasKey(doc bson.D, key string) bool {
+	for _, e := range doc {
+		if e.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// ExcludeNamespacesStage returns a $match stage that drops the events of the namespaces
+// on the server, or nil if namespaces is empty.
+func ExcludeNamespacesStage(namespaces []Namespace) bson.D {
+	if len(namespaces) == 0 {
+		return nil
+	}
+	nor := make(bson.A, 0, len(namespaces))
+	for _, ns := range namespaces {
+		nor = append(nor, bson.D{
+			{Key: "ns.db", Value: ns.Database},
+			{Key: "ns.coll", Value: ns.Collection},
+		})
+	}
+	return bson.D{{Key: "$match", Value: bson.D{{Key: "$nor", Value: nor}}}}
+}
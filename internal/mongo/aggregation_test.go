@@ -0,0 +1,123 @@
+package mongo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestParsePipeline(t *testing.T) {
+	t.Parallel()
+
+	patterns := []struct {
+		name    string
+		in      string
+		want    mongo.Pipeline
+		wantErr bool
+	}{
+		{
+			name: "empty",
+			in:   "",
+			want: mongo.Pipeline{},
+		},
+		{
+			name: "match and project",
+			in:   `[{"$match": {"operationType": {"$in": ["insert", "update"]}}}, {"$project": {"fullDocument.secret": 0}}]`,
+			want: mongo.Pipeline{
+				{{Key: "$match", Value: bson.D{{Key: "operationType", Value: bson.D{{Key: "$in", Value: bson.A{"insert", "update"}}}}}}},
+				{{Key: "$project", Value: bson.D{{Key: "fullDocument.secret", Value: int32(0)}}}},
+			},
+		},
+		{
+			name: "extended json",
+			in:   `[{"$match": {"clusterTime": {"$gt": {"$timestamp": {"t": 1700000000, "i": 1}}}}}]`,
+			want: mongo.Pipeline{
+				{{Key: "$match", Value: bson.D{{Key: "clusterTime", Value: bson.D{{Key: "$gt", Value: primitive.Timestamp{T: 1700000000, I: 1}}}}}}},
+			},
+		},
+		{
+			name: "add fields",
+			in:   `[{"$addFields": {"source": "mongo-streamer"}}]`,
+			want: mongo.Pipeline{
+				{{Key: "$addFields", Value: bson.D{{Key: "source", Value: "mongo-streamer"}}}},
+			},
+		},
+		{
+			name:    "invalid json",
+			in:      `[{"$match": }]`,
+			wantErr: true,
+		},
+		{
+			name:    "not an array",
+			in:      `{"$match": {}}`,
+			wantErr: true,
+		},
+		{
+			name:    "multiple fields in a stage",
+			in:      `[{"$match": {}, "$project": {}}]`,
+			wantErr: true,
+		},
+		{
+			name:    "stage not allowed in change stream",
+			in:      `[{"$group": {"_id": "$operationType"}}]`,
+			wantErr: true,
+		},
+		{
+			name:    "project out _id",
+			in:      `[{"$project": {"_id": 0}}]`,
+			wantErr: true,
+		},
+		{
+			name:    "unset _id",
+			in:      `[{"$unset": ["fullDocument.secret", "_id"]}]`,
+			wantErr: true,
+		},
+		{
+			name:    "overwrite _id",
+			in:      `[{"$set": {"_id": "id"}}]`,
+			wantErr: true,
+		},
+		{
+			name:    "replace root",
+			in:      `[{"$replaceRoot": {"newRoot": "$fullDocument"}}]`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range patterns {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := ParsePipeline(tt.in)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestExcludeNamespacesStage(t *testing.T) {
+	t.Parallel()
+
+	assert.Nil(t, ExcludeNamespacesStage(nil))
+
+	got := ExcludeNamespacesStage([]Namespace{
+		{Database: "db", Collection: "resume_tokens"},
+		{Database: "mongo_streamer", Collection: "dead_letters"},
+	})
+	want := bson.D{{Key: "$match", Value: bson.D{{Key: "$nor", Value: bson.A{
+		bson.D{{Key: "ns.db", Value: "db"}, {Key: "ns.coll", Value: "resume_tokens"}},
+		bson.D{{Key: "ns.db", Value: "mongo_streamer"}, {Key: "ns.coll", Value: "dead_letters"}},
+	}}}}}
+	assert.Equal(t, want, got)
+	// the stage is accepted in a change stream pipeline
+	assert.NoError(t, validateStage(got))
+}
@@ -2,26 +2,78 @@ package mongo
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
+	"fmt"
 
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 
+	"github.com/ucpr/mongo-streamer/internal/deadletter"
 	mmetric "github.com/ucpr/mongo-streamer/internal/metric/mongo"
 	"github.com/ucpr/mongo-streamer/internal/model"
 	"github.com/ucpr/mongo-streamer/internal/persistent"
+	"github.com/ucpr/mongo-streamer/internal/pubsub"
+	"github.com/ucpr/mongo-streamer/pkg/backoff"
 	"github.com/ucpr/mongo-streamer/pkg/log"
 )
 
 type (
 	// ChangeStream is a struct that represents a change stream.
 	ChangeStream struct {
-		cs           *mongo.ChangeStream
-		handler      ChangeStreamHandler
+		name        string
+		cs          *mongo.ChangeStream
+		handler     ChangeStreamHandler
+		orderingKey OrderingKeyFunc
+		// keys serializes the events of the same ordering key across the change
+		// stream, the snapshots and the re-snapshots
+		keys         *keyLocks
 		tokenManager persistent.StorageBuffer
-		db           string
-		col          string
+		retry        RetryPolicy
+		reconnect    ReconnectPolicy
+		maxInFlight  int
+		deadLetter   deadletter.Sink
+		namespaces   *NamespaceFilter
+
+		// client, target, pipeline and opts are used to reopen the change stream.
+		client   *Client
+		target   Namespace
+		pipeline mongo.Pipeline
+		opts     *options.ChangeStreamOptions
+		// lastToken is the resume token of the terminated cursor, used to reopen
+		// the change stream when no token is saved yet.
+		lastToken bson.Raw
+		// startAt is the cluster time to open the change stream at when no token is saved.
+		startAt *primitive.Timestamp
+		// startAfter is the resume token to open the change stream after when no token is saved.
+		startAfter bson.Raw
+
+		historyLost HistoryLostPolicy
+		// snapshotPending is set when the watched collections are snapshotted before
+		// the change stream is reopened.
+		snapshotPending bool
+		// snapshotStorage stores the progress of the snapshot.
+		snapshotStorage persistent.StorageBuffer
+	}
+
+	// RetryPolicy is a policy to retry handling a change event that failed.
+	RetryPolicy struct {
+		// MaxAttempts is the maximum number of attempts to handle an event,
+		// 0 means the event is retried until it succeeds.
+		MaxAttempts int
+		// Backoff is the backoff between attempts.
+		Backoff backoff.Backoff
+	}
+
+	// ReconnectPolicy is a policy to reopen a change stream that terminated with a resumable error.
+	ReconnectPolicy struct {
+		// MaxAttempts is the maximum number of consecutive attempts to reopen the change stream,
+		// 0 means the change stream is reopened until it succeeds. The attempts are reset
+		// once the reopened change stream receives an event.
+		MaxAttempts int
+		// Backoff is the backoff between attempts.
+		Backoff backoff.Backoff
 	}
 
 	// ChangeStreamOptions is a struct that represents options for change stream.
@@ -32,8 +84,12 @@ type (
 	// ChangeStreamOption is a type of option function for change stream.
 	ChangeStreamOption func(opts *ChangeStreamOptions)
 
-	// ChangeStreamHandler is a type of handler function that handles ChangeStream.
-	ChangeStreamHandler func(ctx context.Context, event model.ChangeEvent) error
+	// ChangeStreamHandler is a type of handler function that starts handling a change event
+	// without waiting for the acknowledgement. The returned result is ready when the event is acknowledged.
+	ChangeStreamHandler func(ctx context.Context, event model.ChangeEvent) (pubsub.PublishResult, error)
+
+	// OrderingKeyFunc returns the ordering key of a change event, empty if the event is not ordered.
+	OrderingKeyFunc func(event model.ChangeEvent) string
 )
 
 // WithBatchSize sets the batch size for ChangeStream.
@@ -45,95 +101,380 @@ func WithBatchSize(batchSize int32) ChangeStreamOption {
 
 // ChangeStreamParams is a struct that represents parameters for creating a ChangeStream.
 type ChangeStreamParams struct {
-	Client     *Client
-	Handler    ChangeStreamHandler
-	Storage    persistent.StorageBuffer
-	Database   string
+	// Name is the name of the stream, used in logs and metrics.
+	Name    string
+	Client  *Client
+	Handler ChangeStreamHandler
+	// OrderingKey returns the ordering key of an event. Events with the same key
+	// are in flight one at a time. If it is nil, the events are not ordered.
+	OrderingKey OrderingKeyFunc
+	Storage     persistent.StorageBuffer
+	Retry       RetryPolicy
+	// Reconnect is the policy to reopen the change stream when it terminates.
+	Reconnect ReconnectPolicy
+	// HistoryLost is the policy when the resume token is no longer in the oplog.
+	HistoryLost HistoryLostPolicy
+	// Start is the position to open the change stream at when no resume token is saved.
+	Start StartPosition
+	// Snapshot is when the watched collections are snapshotted. The initial snapshot
+	// is exclusive with Start unless Start overrides the saved resume token.
+	Snapshot SnapshotMode
+	// SnapshotStorage stores the progress of the snapshots, which are resumed from it.
+	// If it is nil, an interrupted snapshot starts over.
+	SnapshotStorage persistent.StorageBuffer
+	// MaxInFlight is the maximum number of events waiting for the acknowledgement concurrently.
+	MaxInFlight int
+	// DeadLetter stores events that cannot be decoded or handled after retries.
+	// If it is nil, the change stream stops at such an event.
+	DeadLetter deadletter.Sink
+	// Namespaces selects the namespaces to stream, nil streams all namespaces.
+	Namespaces *NamespaceFilter
+	// Pipeline is the aggregation pipeline applied to the change stream on the server.
+	Pipeline mongo.Pipeline
+	// FullDocument is the mode to return the full document of update events.
+	// The server default is used if it is empty.
+	FullDocument options.FullDocument
+	// FullDocumentBeforeChange is the mode to return the pre-image of the document.
+	// The server default is used if it is empty.
+	FullDocumentBeforeChange options.FullDocument
+	// Database is the database to watch. All databases are watched if it is empty.
+	Database string
+	// Collection is the collection to watch. All collections of Database are
+	// watched if it is empty.
 	Collection string
 }
 
 // NewChangeStream creates a new change stream instance.
+// It watches the collection, the database, or the whole deployment, depending on
+// which of Database and Collection are set.
 func NewChangeStream(ctx context.Context, params ChangeStreamParams, opts ...ChangeStreamOption) (*ChangeStream, error) {
+	if params.Database == "" && params.Collection != "" {
+		return nil, errors.New("collection is set without database")
+	}
 	chopts := &options.ChangeStreamOptions{}
 	for _, opt := range opts {
 		opt(&ChangeStreamOptions{chopts})
 	}
+	if params.FullDocument != "" {
+		chopts.SetFullDocument(params.FullDocument)
+	}
+	if params.FullDocumentBeforeChange != "" {
+		chopts.SetFullDocumentBeforeChange(params.FullDocumentBeforeChange)
+	}
 
-	rt, err := params.Storage.Get()
-	if err != nil {
+	pipeline := params.Pipeline
+	if pipeline == nil {
+		pipeline = mongo.Pipeline{}
+	}
+	target := Namespace{Database: params.Database, Collection: params.Collection}
+	if err := params.Client.checkPreAndPostImages(ctx, target, params.Namespaces, params.FullDocument, params.FullDocumentBeforeChange); err != nil {
 		return nil, err
 	}
-	if rt != "" {
-		chopts.SetResumeAfter(rt)
+
+	cs := &ChangeStream{
+		name:            params.Name,
+		handler:         params.Handler,
+		orderingKey:     params.OrderingKey,
+		keys:            newKeyLocks(),
+		tokenManager:    params.Storage,
+		retry:           params.Retry,
+		reconnect:       params.Reconnect,
+		maxInFlight:     params.MaxInFlight,
+		deadLetter:      params.DeadLetter,
+		namespaces:      params.Namespaces,
+		client:          params.Client,
+		target:          target,
+		pipeline:        pipeline,
+		opts:            chopts,
+		historyLost:     params.HistoryLost,
+		startAt:         params.Start.OperationTime,
+		startAfter:      params.Start.After,
+		snapshotStorage: params.SnapshotStorage,
+	}
+	if cs.snapshotStorage == nil {
+		cs.snapshotStorage = discardBuffer{}
+	}
+	if params.Start.Override && !params.Start.IsZero() {
+		log.Warn("Discard the saved resume token to start the change stream at the start position",
+			log.Fstring("stream", params.Name),
+		)
+		if err := discardCheckpoint(cs.tokenManager); err != nil {
+			return nil, err
+		}
+		if err := discardCheckpoint(cs.snapshotStorage); err != nil {
+			return nil, err
+		}
+	}
+	if params.Snapshot == SnapshotInitial {
+		pending, err := cs.initialSnapshotPending(params.Start)
+		if err != nil {
+			return nil, err
+		}
+		if pending {
+			// Run opens the change stream after the snapshot
+			cs.snapshotPending = true
+			return cs, nil
+		}
+	}
+	if err := cs.open(ctx, false); err != nil {
+		if ClassifyError(err) != ErrorClassHistoryLost {
+			return nil, err
+		}
+		// Run reopens the change stream according to the policy
+		if err := cs.recoverHistoryLost(err); err != nil {
+			return nil, err
+		}
 	}
+	return cs, nil
+}
 
-	// TODO: refactor
-	pipeline := mongo.Pipeline{}
-	db, col := params.Database, params.Collection
-	collection := params.Client.cli.Database(db).Collection(col)
-	changeStream, err := collection.Watch(ctx, pipeline, chopts)
+// open opens the change stream from the saved resume token, or after startAfter or
+// at startAt if no token is saved. The change stream is reopened with startAfter,
+// which also starts after an invalidate event.
+func (c *ChangeStream) open(ctx context.Context, reopen bool) error {
+	opts := *c.opts
+	rt, err := c.tokenManager.Get()
 	if err != nil {
-		// if resume token is not found, reset resume token and retry
-		if errors.Is(err, mongo.ErrMissingResumeToken) {
-			log.Warn("Resume token is not found, reset resume token and retry", log.Fstring("db", db), log.Fstring("col", col))
-			chopts.SetResumeAfter(nil)
-			if err := params.Storage.Clear(); err != nil {
-				return nil, err
-			}
-
-			changeStream, err = collection.Watch(ctx, pipeline, chopts)
-			if err != nil {
-				return nil, err
-			}
-		} else {
-			return nil, err
+		return err
+	}
+	var token bson.Raw
+	if rt != "" {
+		if token, err = parseResumeToken(rt); err != nil {
+			return err
 		}
+	} else if reopen {
+		token = c.lastToken
+	}
+	switch {
+	case len(token) > 0 && reopen:
+		opts.SetStartAfter(token)
+	case len(token) > 0:
+		opts.SetResumeAfter(token)
+	case len(c.startAfter) > 0:
+		opts.SetStartAfter(c.startAfter)
+	case c.startAt != nil:
+		opts.SetStartAtOperationTime(c.startAt)
 	}
 
-	cs := &ChangeStream{
-		cs:           changeStream,
-		handler:      params.Handler,
-		tokenManager: params.Storage,
-		db:           db,
-		col:          col,
+	changeStream, err := c.client.watch(ctx, c.target, c.pipeline, &opts)
+	if err != nil {
+		return err
 	}
-	return cs, nil
+	c.cs = changeStream
+	return nil
 }
 
 // Run starts watching change stream.
-func (c *ChangeStream) Run(ctx context.Context) {
-	for c.cs.Next(ctx) {
-		mmetric.ReceiveChangeStream(c.db, c.col)
+//
+// Events are published concurrently up to the max in-flight limit and the resume
+// token is only saved up to the highest contiguous acknowledged event, so the saved
+// token never advances past an event that has not been acknowledged. A failed event
+// is retried according to the retry policy. An event that cannot be decoded or handled
+// is stored to the dead letter queue, or Run returns an error when no dead letter
+// queue is configured, leaving the token at the last acknowledged event.
+//
+// When the change stream terminates, e.g. on a network error or a primary step-down,
+// it is reopened from the last saved token according to the reconnect policy. Run
+// returns an error when the change stream cannot be resumed, see ClassifyError.
+func (c *ChangeStream) Run(ctx context.Context) error {
+	for attempt := 0; ; {
+		var (
+			received bool
+			err      error
+		)
+		if c.cs == nil {
+			err = c.reopen(ctx)
+		}
+		if err == nil {
+			received, err = c.runOnce(ctx)
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
 
-		var streamObject model.ChangeEvent
-		if err := c.cs.Decode(&streamObject); err != nil {
-			mmetric.HandleChangeEventFailed(c.db, c.col)
-			log.Error("Failed to decode steream object", log.Ferror(err))
+		var terr *terminationError
+		if err != nil && !errors.As(err, &terr) {
+			// the pipeline stopped at an event that cannot be handled
+			return err
+		}
+		// the cursor is exhausted after an invalidate event, which is reopened with startAfter
+		class, reason := ErrorClassResumable, "invalidated"
+		if err == nil {
+			err = errChangeStreamInvalidated
+		} else {
+			class = ClassifyError(err)
+			reason = class.String()
+		}
+		mmetric.TerminateChangeStream(c.name, reason)
+
+		switch class {
+		case ErrorClassResumable:
+		case ErrorClassHistoryLost:
+			// the change stream is reopened from now without backoff
+			if err := c.recoverHistoryLost(err); err != nil {
+				return err
+			}
+			mmetric.RestartChangeStream(c.name, reason)
 			continue
+		default:
+			return fmt.Errorf("change stream terminated with %s error: %w", reason, err)
 		}
 
-		// marshal stream object to json
-		jb, err := json.Marshal(streamObject)
+		if received {
+			attempt = 0
+		}
+		attempt++
+		if c.reconnect.MaxAttempts > 0 && attempt > c.reconnect.MaxAttempts {
+			return fmt.Errorf("change stream is not reopened after %d attempts: %w", c.reconnect.MaxAttempts, err)
+		}
+		d := c.reconnect.Backoff.Duration(attempt)
+		log.Warn("Change stream terminated, reopen it",
+			log.Fstring("stream", c.name),
+			log.Fstring("reason", reason),
+			log.Fint("attempt", attempt),
+			log.Fduration("backoff", d),
+			log.Ferror(err),
+		)
+		if err := backoff.Sleep(ctx, d); err != nil {
+			return nil
+		}
+		mmetric.RestartChangeStream(c.name, reason)
+	}
+}
+
+// runOnce runs the change stream until it terminates and closes it. It reports
+// whether any event was received.
+func (c *ChangeStream) runOnce(ctx context.Context) (bool, error) {
+	p := newPipeline(pipelineParams{
+		Stream:      c.name,
+		Handler:     c.handler,
+		OrderingKey: c.orderingKey,
+		Keys:        c.keys,
+		Retry:       c.retry,
+		Tracker:     persistent.NewTracker(c.tokenManager),
+		DeadLetter:  c.deadLetter,
+		MaxInFlight: c.maxInFlight,
+	})
+	received, err := c.run(ctx, p)
+	// wait for in-flight events so that their checkpoints are committed
+	if werr := p.wait(); werr != nil {
+		err = werr
+	}
+
+	// the events before the token of the cursor are acknowledged unless the pipeline stopped
+	c.lastToken = c.cs.ResumeToken()
+	if cerr := c.cs.Close(context.WithoutCancel(ctx)); cerr != nil {
+		log.Warn("Failed to close change stream", log.Fstring("stream", c.name), log.Ferror(cerr))
+	}
+	c.cs = nil
+	return received, err
+}
+
+// reopen opens the change stream from the last acknowledged event, after the
+// snapshot of the watched collections if it is requested.
+func (c *ChangeStream) reopen(ctx context.Context) error {
+	if err := c.tokenManager.Flush(); err != nil {
+		return storageError(fmt.Errorf("failed to save resume token: %w", err))
+	}
+	if c.snapshotPending {
+		ts, err := c.snapshot(ctx)
 		if err != nil {
-			// skip for metrics retention use
-			log.Error("Failed to marshal stream object to json", log.Ferror(err))
+			return err
 		}
-		mmetric.ReceiveBytes(c.db, c.col, len(jb))
+		c.startAt = &ts
+		c.snapshotPending = false
+	}
+	if err := c.open(ctx, true); err != nil {
+		return &terminationError{err: err}
+	}
+	log.Info("Change stream reopened", log.Fstring("stream", c.name))
+	return nil
+}
+
+// errChangeStreamInvalidated is reported when the change stream ends without an error.
+var errChangeStreamInvalidated = errors.New("change stream is invalidated")
+
+// terminationError is an error of the change stream cursor, as opposed to an error
+// of the pipeline.
+type terminationError struct {
+	err error
+}
 
-		if err := c.handler(context.Background(), streamObject); err != nil {
-			mmetric.HandleChangeEventFailed(c.db, c.col)
-			log.Error("Failed to handle change stream", log.Ferror(err))
-			// TODO: If handle fails, the process is repeated again
+func (e *terminationError) Error() string {
+	return e.err.Error()
+}
+
+func (e *terminationError) Unwrap() error {
+	return e.err
+}
+
+// storageError wraps an error of the checkpoint storage in a terminationError, so that
+// the change stream is reopened if it is resumable, unless another instance has taken
+// over the stream.
+func storageError(err error) error {
+	if err == nil || errors.Is(err, persistent.ErrConflict) {
+		return err
+	}
+	return &terminationError{err: err}
+}
+
+// run reads events from the change stream and dispatches them to the pipeline.
+func (c *ChangeStream) run(ctx context.Context, p *pipeline) (bool, error) {
+	received := false
+	for c.cs.Next(ctx) {
+		received = true
+		// copy the current event as the cursor reuses the buffer
+		raw := make(bson.Raw, len(c.cs.Current))
+		copy(raw, c.cs.Current)
+
+		ns := namespaceOf(raw)
+		if !c.namespaces.Match(ns) {
+			// the resume token still advances past events that are not streamed
+			if err := p.skip(c.checkpoint()); err != nil {
+				log.Error("Failed to save resume token", log.Ferror(err))
+			}
 			continue
 		}
-		mmetric.HandleChangeEventSuccess(c.db, c.col)
+		mmetric.ReceiveChangeStream(c.name, ns.Database, ns.Collection)
+		mmetric.ReceiveBytes(c.name, ns.Database, ns.Collection, len(raw))
 
-		// save resume token
-		if err := c.tokenManager.Set(c.resumeToken()); err != nil {
-			log.Error("Failed to save resume token", log.Ferror(err))
+		var streamObject model.ChangeEvent
+		if err := bson.Unmarshal(raw, &streamObject); err != nil {
+			err = fmt.Errorf("failed to decode change event: %w", err)
+			if err := p.reject(ctx, raw, ns, c.checkpoint(), err); err != nil {
+				return received, err
+			}
 			continue
 		}
+
+		if err := p.dispatch(ctx, streamObject, raw, ns, c.checkpoint()); err != nil {
+			return received, err
+		}
+	}
+	if err := c.cs.Err(); err != nil {
+		return received, &terminationError{err: err}
+	}
+	return received, nil
+}
+
+// parseResumeToken parses the resume token saved by resumeToken.
+func parseResumeToken(s string) (bson.Raw, error) {
+	var token bson.Raw
+	if err := bson.UnmarshalExtJSON([]byte(s), false, &token); err != nil {
+		return nil, fmt.Errorf("failed to parse resume token: %w", err)
 	}
+	return token, nil
+}
+
+// checkpoint returns the checkpoint of the current event of the change stream.
+func (c *ChangeStream) checkpoint() persistent.Checkpoint {
+	cp := persistent.Checkpoint{
+		Token: c.resumeToken(),
+	}
+	if t, i, ok := c.cs.Current.Lookup("clusterTime").TimestampOK(); ok {
+		cp.ClusterTime = primitive.Timestamp{T: t, I: i}
+	}
+	return cp
 }
 
 // resumeToken returns the resume token of the change stream.
@@ -141,7 +482,10 @@ func (c *ChangeStream) resumeToken() string {
 	return c.cs.ResumeToken().String()
 }
 
-// Close closes the change stream cursor.
+// Close closes the change stream cursor if it is open.
 func (c *ChangeStream) Close(ctx context.Context) error {
+	if c.cs == nil {
+		return nil
+	}
 	return c.cs.Close(ctx)
 }
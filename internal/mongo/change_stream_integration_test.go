@@ -0,0 +1,335 @@
+//go:build integration
+
+package mongo
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/ucpr/mongo-streamer/internal/config"
+	"github.com/ucpr/mongo-streamer/internal/model"
+	"github.com/ucpr/mongo-streamer/internal/persistent"
+	"github.com/ucpr/mongo-streamer/internal/pubsub"
+	"github.com/ucpr/mongo-streamer/pkg/backoff"
+)
+
+//nolint:paralleltest
+func TestChangeStream_Run_Reconnect(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	cli, err := NewClient(ctx, &config.MongoDB{URI: testMongoURI})
+	require.NoError(t, err)
+	defer cli.Disconnect(ctx)
+
+	const database = "test_reconnect"
+	coll := cli.Database(database).Collection("events")
+	require.NoError(t, cli.Database(database).Drop(ctx))
+
+	fs, err := persistent.NewFileWriter(filepath.Join(t.TempDir(), "token"))
+	require.NoError(t, err)
+	buf, err := persistent.NewBuffer(1, time.Second, fs)
+	require.NoError(t, err)
+
+	events := make(chan model.ChangeEvent, 10)
+	cs, err := NewChangeStream(ctx, ChangeStreamParams{
+		Name:    "reconnect",
+		Client:  cli,
+		Handler: testChannelHandler(events),
+		Storage: buf,
+		Reconnect: ReconnectPolicy{
+			MaxAttempts: 3,
+			Backoff:     backoff.Backoff{Initial: 10 * time.Millisecond},
+		},
+		Database:   database,
+		Collection: "events",
+	})
+	require.NoError(t, err)
+
+	// fail the next getMore and the resume of the driver, so that the change stream terminates
+	setFailPoint(ctx, t, cli, 2, bson.D{
+		{Key: "failCommands", Value: bson.A{"getMore", "aggregate"}},
+		{Key: "errorCode", Value: 91},
+		{Key: "errorLabels", Value: bson.A{"ResumableChangeStreamError"}},
+	})
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cs.Run(ctx)
+	}()
+
+	_, err = coll.InsertOne(ctx, bson.M{"_id": 1})
+	require.NoError(t, err)
+
+	select {
+	case event := <-events:
+		assert.Equal(t, "insert", event.OperationType)
+	case err := <-done:
+		t.Fatalf("change stream stopped: %v", err)
+	case <-ctx.Done():
+		t.Fatal("change stream is not reopened")
+	}
+
+	cancel()
+	assert.NoError(t, <-done)
+	assert.NoError(t, cs.Close(context.Background()))
+}
+
+//nolint:paralleltest
+func TestChangeStream_Run_Resnapshot(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	cli, err := NewClient(ctx, &config.MongoDB{URI: testMongoURI})
+	require.NoError(t, err)
+	defer cli.Disconnect(ctx)
+
+	const database = "test_resnapshot"
+	coll := cli.Database(database).Collection("events")
+	require.NoError(t, cli.Database(database).Drop(ctx))
+	_, err = coll.InsertMany(ctx, []interface{}{bson.M{"_id": 2}, bson.M{"_id": 1}})
+	require.NoError(t, err)
+
+	fs, err := persistent.NewFileWriter(filepath.Join(t.TempDir(), "token"))
+	require.NoError(t, err)
+	buf, err := persistent.NewBuffer(1, time.Second, fs)
+	require.NoError(t, err)
+
+	// the resume token is no longer in the oplog when the change stream is opened
+	setFailPoint(ctx, t, cli, 1, bson.D{
+		{Key: "failCommands", Value: bson.A{"aggregate"}},
+		{Key: "errorCode", Value: 286},
+	})
+
+	events := make(chan model.ChangeEvent, 10)
+	cs, err := NewChangeStream(ctx, ChangeStreamParams{
+		Name:        "resnapshot",
+		Client:      cli,
+		Handler:     testChannelHandler(events),
+		Storage:     buf,
+		HistoryLost: HistoryLostResnapshot,
+		Database:    database,
+		Collection:  "events",
+	})
+	require.NoError(t, err)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cs.Run(ctx)
+	}()
+
+	// the documents are published in _id order, then the changes
+	want := []struct {
+		op string
+		id int32
+	}{
+		{op: model.OperationTypeSnapshot, id: 1},
+		{op: model.OperationTypeSnapshot, id: 2},
+		{op: "insert", id: 3},
+	}
+	for i, w := range want {
+		if i == 2 {
+			_, err = coll.InsertOne(ctx, bson.M{"_id": 3})
+			require.NoError(t, err)
+		}
+		select {
+		case event := <-events:
+			assert.Equal(t, w.op, event.OperationType)
+			assert.Equal(t, w.id, event.DocumentKey.Lookup("_id").Int32())
+		case err := <-done:
+			t.Fatalf("change stream stopped: %v", err)
+		case <-ctx.Done():
+			t.Fatal("event is not published")
+		}
+	}
+
+	cancel()
+	assert.NoError(t, <-done)
+	assert.NoError(t, cs.Close(context.Background()))
+}
+
+//nolint:paralleltest
+func TestChangeStream_Run_StartAtOperationTime(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	cli, err := NewClient(ctx, &config.MongoDB{URI: testMongoURI})
+	require.NoError(t, err)
+	defer cli.Disconnect(ctx)
+
+	const database = "test_start_at"
+	coll := cli.Database(database).Collection("events")
+	require.NoError(t, cli.Database(database).Drop(ctx))
+
+	// the change before the change stream is opened is replayed from the operation time
+	ts, err := cli.operationTime(ctx)
+	require.NoError(t, err)
+	_, err = coll.InsertOne(ctx, bson.M{"_id": 1})
+	require.NoError(t, err)
+
+	fs, err := persistent.NewFileWriter(filepath.Join(t.TempDir(), "token"))
+	require.NoError(t, err)
+	buf, err := persistent.NewBuffer(1, time.Second, fs)
+	require.NoError(t, err)
+
+	events := make(chan model.ChangeEvent, 10)
+	cs, err := NewChangeStream(ctx, ChangeStreamParams{
+		Name:       "start_at",
+		Client:     cli,
+		Handler:    testChannelHandler(events),
+		Storage:    buf,
+		Start:      StartPosition{OperationTime: &ts, Override: true},
+		Database:   database,
+		Collection: "events",
+	})
+	require.NoError(t, err)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cs.Run(ctx)
+	}()
+
+	select {
+	case event := <-events:
+		assert.Equal(t, "insert", event.OperationType)
+		assert.Equal(t, int32(1), event.DocumentKey.Lookup("_id").Int32())
+	case err := <-done:
+		t.Fatalf("change stream stopped: %v", err)
+	case <-ctx.Done():
+		t.Fatal("change is not replayed")
+	}
+
+	cancel()
+	assert.NoError(t, <-done)
+	assert.NoError(t, cs.Close(context.Background()))
+}
+
+//nolint:paralleltest
+func TestChangeStream_Run_InitialSnapshot(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	cli, err := NewClient(ctx, &config.MongoDB{URI: testMongoURI})
+	require.NoError(t, err)
+	defer cli.Disconnect(ctx)
+
+	const database = "test_initial_snapshot"
+	coll := cli.Database(database).Collection("events")
+	require.NoError(t, cli.Database(database).Drop(ctx))
+	_, err = coll.InsertMany(ctx, []interface{}{bson.M{"_id": 3}, bson.M{"_id": 1}, bson.M{"_id": 2}})
+	require.NoError(t, err)
+
+	// the snapshot was interrupted after the document of _id 1
+	ts, err := cli.operationTime(ctx)
+	require.NoError(t, err)
+	typ, data, err := bson.MarshalValue(int32(1))
+	require.NoError(t, err)
+	dir := t.TempDir()
+	sfs, err := persistent.NewFileWriter(filepath.Join(dir, "snapshot"))
+	require.NoError(t, err)
+	require.NoError(t, sfs.Write(snapshotProgress{
+		ClusterTime: ts,
+		Database:    database,
+		Collection:  "events",
+		After:       &bson.RawValue{Type: typ, Value: data},
+	}.String()))
+	snapshots, err := persistent.NewBuffer(1, time.Second, sfs)
+	require.NoError(t, err)
+
+	fs, err := persistent.NewFileWriter(filepath.Join(dir, "token"))
+	require.NoError(t, err)
+	buf, err := persistent.NewBuffer(1, time.Second, fs)
+	require.NoError(t, err)
+
+	events := make(chan model.ChangeEvent, 10)
+	cs, err := NewChangeStream(ctx, ChangeStreamParams{
+		Name:            "initial_snapshot",
+		Client:          cli,
+		Handler:         testChannelHandler(events),
+		Storage:         buf,
+		Snapshot:        SnapshotInitial,
+		SnapshotStorage: snapshots,
+		Database:        database,
+		Collection:      "events",
+	})
+	require.NoError(t, err)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cs.Run(ctx)
+	}()
+
+	// the snapshot resumes after the checkpoint, then the changes are streamed
+	want := []struct {
+		op string
+		id int32
+	}{
+		{op: model.OperationTypeSnapshot, id: 2},
+		{op: model.OperationTypeSnapshot, id: 3},
+		{op: "insert", id: 4},
+	}
+	for i, w := range want {
+		if i == 2 {
+			_, err = coll.InsertOne(ctx, bson.M{"_id": 4})
+			require.NoError(t, err)
+		}
+		select {
+		case event := <-events:
+			assert.Equal(t, w.op, event.OperationType)
+			assert.Equal(t, w.id, event.DocumentKey.Lookup("_id").Int32())
+		case err := <-done:
+			t.Fatalf("change stream stopped: %v", err)
+		case <-ctx.Done():
+			t.Fatal("event is not published")
+		}
+	}
+
+	cancel()
+	assert.NoError(t, <-done)
+	assert.NoError(t, cs.Close(context.Background()))
+
+	// the snapshot is done
+	s, err := snapshots.Get()
+	require.NoError(t, err)
+	progress, err := parseSnapshotProgress(s)
+	require.NoError(t, err)
+	assert.True(t, progress.Done)
+	assert.Equal(t, ts, progress.ClusterTime)
+}
+
+// testChannelHandler returns a handler that sends the events to the channel and acknowledges them.
+func testChannelHandler(events chan<- model.ChangeEvent) ChangeStreamHandler {
+	return func(_ context.Context, event model.ChangeEvent) (pubsub.PublishResult, error) {
+		events <- event
+		res := newFakeResult()
+		res.ack(nil)
+		return res, nil
+	}
+}
+
+// setFailPoint makes the next commands of the server fail, or skips the test if
+// the server does not enable test commands.
+func setFailPoint(ctx context.Context, t *testing.T, cli *Client, times int, data bson.D) {
+	t.Helper()
+
+	admin := cli.Database("admin")
+	if err := admin.RunCommand(ctx, bson.D{
+		{Key: "configureFailPoint", Value: "failCommand"},
+		{Key: "mode", Value: bson.D{{Key: "times", Value: times}}},
+		{Key: "data", Value: data},
+	}).Err(); err != nil {
+		t.Skipf("fail points are not enabled: %v", err)
+	}
+	t.Cleanup(func() {
+		admin.RunCommand(context.Background(), bson.D{
+			{Key: "configureFailPoint", Value: "failCommand"},
+			{Key: "mode", Value: "off"},
+		})
+	})
+}
@@ -0,0 +1,84 @@
+package mongo
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.uber.org/mock/gomock"
+
+	"github.com/ucpr/mongo-streamer/internal/persistent"
+	"github.com/ucpr/mongo-streamer/internal/persistent/mock"
+	"github.com/ucpr/mongo-streamer/pkg/backoff"
+)
+
+func TestParseResumeToken(t *testing.T) {
+	t.Parallel()
+
+	token, err := bson.Marshal(bson.D{{Key: "_data", Value: "8265A0E2B9000000012B022C0100296E5A1004"}})
+	require.NoError(t, err)
+
+	// the token is saved in the format returned by resumeToken
+	got, err := parseResumeToken(bson.Raw(token).String())
+	require.NoError(t, err)
+	assert.Equal(t, bson.Raw(token), got)
+
+	_, err = parseResumeToken("invalid")
+	assert.Error(t, err)
+}
+
+func TestChangeStream_Run_ReopenFlushError(t *testing.T) {
+	t.Parallel()
+
+	netErr := &net.OpError{Op: "dial", Net: "tcp", Err: assert.AnError}
+
+	patterns := []struct {
+		name    string
+		err     error
+		flushes int
+		wantMsg string
+	}{
+		{
+			// the flush is retried as the change stream is reopened
+			name:    "network error",
+			err:     netErr,
+			flushes: 2,
+			wantMsg: "change stream is not reopened after 1 attempts",
+		},
+		{
+			// another instance has taken over the stream
+			name:    "conflict",
+			err:     persistent.ErrConflict,
+			flushes: 1,
+		},
+	}
+
+	for _, tt := range patterns {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			buf := mock.NewMockStorageBuffer(ctrl)
+			buf.EXPECT().Flush().Return(tt.err).Times(tt.flushes)
+
+			c := &ChangeStream{
+				name:         "stream",
+				tokenManager: buf,
+				reconnect: ReconnectPolicy{
+					MaxAttempts: 1,
+					Backoff:     backoff.Backoff{Initial: time.Millisecond},
+				},
+			}
+			err := c.Run(context.Background())
+			assert.ErrorIs(t, err, tt.err)
+			if tt.wantMsg != "" {
+				assert.ErrorContains(t, err, tt.wantMsg)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package mongo
+
+import (
+	"errors"
+	"net"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/auth"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/topology"
+)
+
+// ErrorClass is the class of an error that terminated a change stream, which
+// decides whether the change stream is reopened.
+type ErrorClass int
+
+const (
+	// ErrorClassFatal is an error that the change stream cannot recover from.
+	ErrorClassFatal ErrorClass = iota
+	// ErrorClassResumable is a transient error, e.g. a network error or a primary
+	// step-down, after which the change stream is reopened from the last token.
+	ErrorClassResumable
+	// ErrorClassHistoryLost is an error that the resume token is no longer in the oplog.
+	ErrorClassHistoryLost
+	// ErrorClassAuth is an authentication or authorization error.
+	ErrorClassAuth
+)
+
+// Error codes of the server, see https://www.mongodb.com/docs/manual/reference/error-codes/.
+const (
+	codeHostUnreachable                 = 6
+	codeHostNotFound                    = 7
+	codeUnauthorized                    = 13
+	codeAuthenticationFailed            = 18
+	codeCursorNotFound                  = 43
+	codeStaleShardVersion               = 63
+	codeNetworkTimeout                  = 89
+	codeShutdownInProgress              = 91
+	codeFailedToSatisfyReadPreference   = 133
+	codeCappedPositionLost              = 136
+	codeStaleEpoch                      = 150
+	codePrimarySteppedDown              = 189
+	codeRetryChangeStream               = 234
+	codeExceededTimeLimit               = 262
+	codeChangeStreamFatalError          = 280
+	codeChangeStreamHistoryLost         = 286
+	codeSocketException                 = 9001
+	codeNotWritablePrimary              = 10107
+	codeInterruptedAtShutdown           = 11600
+	codeInterruptedDueToReplStateChange = 11602
+	codeStaleConfig                     = 13388
+	codeNotPrimaryNoSecondaryOk         = 13435
+	codeNotPrimaryOrSecondary           = 13436
+)
+
+// resumableErrorLabel is the label of the server errors that a change stream can resume from.
+const resumableErrorLabel = "ResumableChangeStreamError"
+
+// resumableCodes are the codes of the resumable errors of the servers that do not label them.
+//
+//nolint:gochecknoglobals
+var resumableCodes = []int{
+	codeHostUnreachable,
+	codeHostNotFound,
+	codeCursorNotFound,
+	codeStaleShardVersion,
+	codeNetworkTimeout,
+	codeShutdownInProgress,
+	codeFailedToSatisfyReadPreference,
+	codeStaleEpoch,
+	codePrimarySteppedDown,
+	codeRetryChangeStream,
+	codeExceededTimeLimit,
+	codeSocketException,
+	codeNotWritablePrimary,
+	codeInterruptedAtShutdown,
+	codeInterruptedDueToReplStateChange,
+	codeStaleConfig,
+	codeNotPrimaryNoSecondaryOk,
+	codeNotPrimaryOrSecondary,
+}
+
+// String returns the name of the class, used in logs and metrics.
+func (c ErrorClass) String() string {
+	switch c {
+	case ErrorClassResumable:
+		return "resumable"
+	case ErrorClassHistoryLost:
+		return "history_lost"
+	case ErrorClassAuth:
+		return "auth"
+	default:
+		return "fatal"
+	}
+}
+
+// ClassifyError returns the class of the error returned by a change stream.
+func ClassifyError(err error) ErrorClass {
+	if err == nil {
+		return ErrorClassFatal
+	}
+
+	// authentication errors are wrapped in connection errors, which are network errors
+	var aerr *auth.Error
+	if errors.As(err, &aerr) {
+		return ErrorClassAuth
+	}
+
+	var serr mongo.ServerError
+	if errors.As(err, &serr) {
+		switch {
+		case serr.HasErrorCode(codeChangeStreamHistoryLost),
+			serr.HasErrorCode(codeCappedPositionLost),
+			// servers before 4.4 report lost history as a fatal error
+			serr.HasErrorCodeWithMessage(codeChangeStreamFatalError, "resume point may no longer be in the oplog"):
+			return ErrorClassHistoryLost
+		case serr.HasErrorCode(codeUnauthorized), serr.HasErrorCode(codeAuthenticationFailed):
+			return ErrorClassAuth
+		case serr.HasErrorLabel(resumableErrorLabel):
+			return ErrorClassResumable
+		}
+		for _, code := range resumableCodes {
+			if serr.HasErrorCode(code) {
+				return ErrorClassResumable
+			}
+		}
+	}
+
+	var (
+		sserr topology.ServerSelectionError
+		nerr  net.Error
+	)
+	switch {
+	// network errors of the checkpoint storage, e.g. Redis, are also transient
+	case mongo.IsNetworkError(err), mongo.IsTimeout(err), errors.As(err, &sserr), errors.As(err, &nerr):
+		return ErrorClassResumable
+	default:
+		return ErrorClassFatal
+	}
+}
@@ -0,0 +1,101 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/topology"
+)
+
+func TestClassifyError(t *testing.T) {
+	t.Parallel()
+
+	patterns := []struct {
+		name string
+		err  error
+		want ErrorClass
+	}{
+		{
+			name: "resumable label",
+			err:  mongo.CommandError{Code: 1, Labels: []string{"ResumableChangeStreamError"}},
+			want: ErrorClassResumable,
+		},
+		{
+			name: "primary stepped down",
+			err:  mongo.CommandError{Code: 189, Name: "PrimarySteppedDown"},
+			want: ErrorClassResumable,
+		},
+		{
+			name: "network error",
+			err:  mongo.CommandError{Labels: []string{"NetworkError"}},
+			want: ErrorClassResumable,
+		},
+		{
+			name: "server selection error",
+			err:  fmt.Errorf("failed to watch: %w", topology.ServerSelectionError{Wrapped: assert.AnError}),
+			want: ErrorClassResumable,
+		},
+		{
+			name: "timeout",
+			err:  context.DeadlineExceeded,
+			want: ErrorClassResumable,
+		},
+		{
+			name: "storage network error",
+			err:  fmt.Errorf("failed to save resume token: %w", &net.OpError{Op: "dial", Net: "tcp", Err: assert.AnError}),
+			want: ErrorClassResumable,
+		},
+		{
+			name: "history lost",
+			err:  mongo.CommandError{Code: 286, Name: "ChangeStreamHistoryLost"},
+			want: ErrorClassHistoryLost,
+		},
+		{
+			name: "history lost before 4.4",
+			err:  mongo.CommandError{Code: 280, Message: "resume of change stream was not possible, as the resume point may no longer be in the oplog"},
+			want: ErrorClassHistoryLost,
+		},
+		{
+			name: "history lost with resumable label",
+			err:  mongo.CommandError{Code: 286, Labels: []string{"ResumableChangeStreamError"}},
+			want: ErrorClassHistoryLost,
+		},
+		{
+			name: "unauthorized",
+			err:  mongo.CommandError{Code: 13, Name: "Unauthorized"},
+			want: ErrorClassAuth,
+		},
+		{
+			name: "authentication failed",
+			err:  mongo.CommandError{Code: 18, Name: "AuthenticationFailed"},
+			want: ErrorClassAuth,
+		},
+		{
+			name: "fatal server error",
+			err:  mongo.CommandError{Code: 280, Name: "ChangeStreamFatalError"},
+			want: ErrorClassFatal,
+		},
+		{
+			name: "unknown error",
+			err:  assert.AnError,
+			want: ErrorClassFatal,
+		},
+		{
+			name: "nil",
+			err:  nil,
+			want: ErrorClassFatal,
+		},
+	}
+
+	for _, tt := range patterns {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, ClassifyError(tt.err))
+		})
+	}
+}
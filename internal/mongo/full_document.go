@@ -0,0 +1,144 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/ucpr/mongo-streamer/pkg/log"
+)
+
+// ErrPreAndPostImagesDisabled is returned when a pre- or post-image is required
+// for a collection that does not have changeStreamPreAndPostImages enabled.
+var ErrPreAndPostImagesDisabled = errors.New("changeStreamPreAndPostImages is not enabled")
+
+// systemDatabases are the databases that a deployment level change stream does not watch.
+//
+//nolint:gochecknoglobals
+var systemDatabases = map[string]struct{}{
+	"admin":  {},
+	"config": {},
+	"local":  {},
+}
+
+// ParseFullDocument parses the fullDocument mode of the change stream.
+// An empty string leaves the mode to the server default.
+func ParseFullDocument(s string) (options.FullDocument, error) {
+	switch fd := options.FullDocument(s); fd {
+	case "", options.Default, options.UpdateLookup, options.WhenAvailable, options.Required:
+		return fd, nil
+	default:
+		return "", fmt.Errorf("invalid full document mode: %s", s)
+	}
+}
+
+// ParseFullDocumentBeforeChange parses the fullDocumentBeforeChange mode of the change stream.
+// An empty string leaves the mode to the server default.
+func ParseFullDocumentBeforeChange(s string) (options.FullDocument, error) {
+	switch fd := options.FullDocument(s); fd {
+	case "", options.Off, options.WhenAvailable, options.Required:
+		return fd, nil
+	default:
+		return "", fmt.Errorf("invalid full document before change mode: %s", s)
+	}
+}
+
+// usesPreAndPostImages reports whether the mode reads pre- or post-images.
+func usesPreAndPostImages(fd options.FullDocument) bool {
+	return fd == options.WhenAvailable || fd == options.Required
+}
+
+// checkPreAndPostImages checks that the watched collections have
+// changeStreamPreAndPostImages enabled when the change stream reads pre- or post-images.
+// It fails if the images are required and only warns if they are read when available.
+func (c *Client) checkPreAndPostImages(ctx context.Context, target Namespace, filter *NamespaceFilter, fullDocument, beforeChange options.FullDocument) error {
+	if !usesPreAndPostImages(fullDocument) && !usesPreAndPostImages(beforeChange) {
+		return nil
+	}
+
+	disabled, err := c.collectionsWithoutPreAndPostImages(ctx, target, filter)
+	if err != nil {
+		return fmt.Errorf("failed to check changeStreamPreAndPostImages: %w", err)
+	}
+	if len(disabled) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(disabled))
+	for _, ns := range disabled {
+		names = append(names, ns.String())
+	}
+	if fullDocument == options.Required || beforeChange == options.Required {
+		return fmt.Errorf("%w on %s: enable it with {collMod: <collection>, changeStreamPreAndPostImages: {enabled: true}}",
+			ErrPreAndPostImagesDisabled, strings.Join(names, ", "))
+	}
+	log.Warn("changeStreamPreAndPostImages is not enabled, full documents of the collections are omitted",
+		log.Fstring("collections", strings.Join(names, ", ")),
+	)
+	return nil
+}
+
+// collectionsWithoutPreAndPostImages returns the watched collections that do not
+// have changeStreamPreAndPostImages enabled.
+func (c *Client) collectionsWithoutPreAndPostImages(ctx context.Context, target Namespace, filter *NamespaceFilter) ([]Namespace, error) {
+	specs, err := c.watchedCollections(ctx, target, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var disabled []Namespace
+	for _, spec := range specs {
+		if enabled, _ := spec.Options.Lookup("changeStreamPreAndPostImages", "enabled").BooleanOK(); !enabled {
+			disabled = append(disabled, spec.Namespace)
+		}
+	}
+	return disabled, nil
+}
+
+// collectionSpec is the specification of a watched collection.
+type collectionSpec struct {
+	Namespace Namespace
+	Options   bson.Raw
+}
+
+// watchedCollections returns the collections that a change stream on the target
+// watches and the filter matches, excluding views and system collections.
+func (c *Client) watchedCollections(ctx context.Context, target Namespace, filter *NamespaceFilter) ([]collectionSpec, error) {
+	dbs := []string{target.Database}
+	if target.Database == "" {
+		names, err := c.cli.ListDatabaseNames(ctx, bson.D{})
+		if err != nil {
+			return nil, err
+		}
+		dbs = dbs[:0]
+		for _, name := range names {
+			if _, ok := systemDatabases[name]; !ok {
+				dbs = append(dbs, name)
+			}
+		}
+	}
+
+	var collections []collectionSpec
+	for _, db := range dbs {
+		query := bson.D{{Key: "type", Value: "collection"}}
+		if target.Collection != "" {
+			query = append(query, bson.E{Key: "name", Value: target.Collection})
+		}
+		specs, err := c.cli.Database(db).ListCollectionSpecifications(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		for _, spec := range specs {
+			ns := Namespace{Database: db, Collection: spec.Name}
+			if strings.HasPrefix(spec.Name, "system.") || !filter.Match(ns) {
+				continue
+			}
+			collections = append(collections, collectionSpec{Namespace: ns, Options: spec.Options})
+		}
+	}
+	return collections, nil
+}
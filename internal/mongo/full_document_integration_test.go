@@ -0,0 +1,52 @@
+//go:build integration
+
+package mongo
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/ucpr/mongo-streamer/internal/config"
+)
+
+const (
+	testMongoURI      = "mongodb://localhost:27017/?directConnection=true"
+	testMongoDatabase = "test_pre_images"
+)
+
+//nolint:paralleltest
+func TestClient_checkPreAndPostImages(t *testing.T) {
+	ctx := context.Background()
+
+	cli, err := NewClient(ctx, &config.MongoDB{URI: testMongoURI})
+	require.NoError(t, err)
+	defer cli.Disconnect(ctx)
+
+	db := cli.Database(testMongoDatabase)
+	require.NoError(t, db.Drop(ctx))
+	require.NoError(t, db.CreateCollection(ctx, "enabled", options.CreateCollection().SetChangeStreamPreAndPostImages(bson.M{"enabled": true})))
+	require.NoError(t, db.CreateCollection(ctx, "disabled"))
+
+	enabled := Namespace{Database: testMongoDatabase, Collection: "enabled"}
+	disabled := Namespace{Database: testMongoDatabase, Collection: "disabled"}
+	database := Namespace{Database: testMongoDatabase}
+
+	// pre- and post-images are not read
+	assert.NoError(t, cli.checkPreAndPostImages(ctx, disabled, nil, options.UpdateLookup, options.Off))
+	// the images are enabled
+	assert.NoError(t, cli.checkPreAndPostImages(ctx, enabled, nil, options.Required, options.Required))
+	// the images are required but disabled
+	assert.ErrorIs(t, cli.checkPreAndPostImages(ctx, disabled, nil, options.Default, options.Required), ErrPreAndPostImagesDisabled)
+	assert.ErrorIs(t, cli.checkPreAndPostImages(ctx, database, nil, options.Required, ""), ErrPreAndPostImagesDisabled)
+	// the images are read when available
+	assert.NoError(t, cli.checkPreAndPostImages(ctx, database, nil, options.WhenAvailable, options.WhenAvailable))
+	// the collection without images is not watched
+	filter, err := NewNamespaceFilter(nil, []string{testMongoDatabase + ".disabled"})
+	require.NoError(t, err)
+	assert.NoError(t, cli.checkPreAndPostImages(ctx, database, filter, options.Required, options.Required))
+}
@@ -0,0 +1,75 @@
+package mongo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestParseFullDocument(t *testing.T) {
+	t.Parallel()
+
+	patterns := []struct {
+		name    string
+		in      string
+		want    options.FullDocument
+		wantErr bool
+	}{
+		{name: "server default", in: "", want: ""},
+		{name: "default", in: "default", want: options.Default},
+		{name: "update lookup", in: "updateLookup", want: options.UpdateLookup},
+		{name: "when available", in: "whenAvailable", want: options.WhenAvailable},
+		{name: "required", in: "required", want: options.Required},
+		{name: "off is only for pre-images", in: "off", wantErr: true},
+		{name: "invalid", in: "always", wantErr: true},
+	}
+
+	for _, tt := range patterns {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := ParseFullDocument(tt.in)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseFullDocumentBeforeChange(t *testing.T) {
+	t.Parallel()
+
+	patterns := []struct {
+		name    string
+		in      string
+		want    options.FullDocument
+		wantErr bool
+	}{
+		{name: "server default", in: "", want: ""},
+		{name: "off", in: "off", want: options.Off},
+		{name: "when available", in: "whenAvailable", want: options.WhenAvailable},
+		{name: "required", in: "required", want: options.Required},
+		{name: "update lookup is only for post-images", in: "updateLookup", wantErr: true},
+		{name: "invalid", in: "always", wantErr: true},
+	}
+
+	for _, tt := range patterns {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := ParseFullDocumentBeforeChange(tt.in)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
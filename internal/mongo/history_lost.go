@@ -0,0 +1,77 @@
+package mongo
+
+import (
+	"errors"
+	"fmt"
+
+	mmetric "github.com/ucpr/mongo-streamer/internal/metric/mongo"
+	"github.com/ucpr/mongo-streamer/pkg/log"
+)
+
+// ErrHistoryLost is returned when the resume token of the change stream is no
+// longer in the oplog and the history lost policy is fail.
+var ErrHistoryLost = errors.New("change stream history is lost")
+
+// HistoryLostPolicy is the policy when the resume token of the change stream is
+// no longer in the oplog.
+type HistoryLostPolicy string
+
+const (
+	// HistoryLostFail stops the change stream.
+	HistoryLostFail HistoryLostPolicy = "fail"
+	// HistoryLostRestart restarts the change stream from now. The events since the
+	// resume token are not streamed.
+	HistoryLostRestart HistoryLostPolicy = "restart"
+	// HistoryLostResnapshot publishes the documents of the watched collections as
+	// snapshot events, then restarts the change stream from the time of the snapshot.
+	HistoryLostResnapshot HistoryLostPolicy = "resnapshot"
+)
+
+// ParseHistoryLostPolicy parses the history lost policy. An empty string is fail.
+func ParseHistoryLostPolicy(s string) (HistoryLostPolicy, error) {
+	switch p := HistoryLostPolicy(s); p {
+	case "":
+		return HistoryLostFail, nil
+	case HistoryLostFail, HistoryLostRestart, HistoryLostResnapshot:
+		return p, nil
+	default:
+		return "", fmt.Errorf("invalid history lost policy: %s", s)
+	}
+}
+
+// recoverHistoryLost applies the history lost policy to the change stream whose
+// resume token is no longer in the oplog. Unless the policy is fail, the resume
+// token and the snapshot progress are cleared so that the change stream is reopened
+// from now, after a new snapshot if the policy is resnapshot.
+func (c *ChangeStream) recoverHistoryLost(cause error) error {
+	policy := c.historyLost
+	if policy == "" {
+		policy = HistoryLostFail
+	}
+	mmetric.LoseHistory(c.name, string(policy))
+
+	switch policy {
+	case HistoryLostRestart:
+		log.Critical("Change stream history is lost, restart from now. The events since the resume token are not streamed",
+			log.Fstring("stream", c.name),
+			log.Ferror(cause),
+		)
+	case HistoryLostResnapshot:
+		log.Critical("Change stream history is lost, re-snapshot the watched collections",
+			log.Fstring("stream", c.name),
+			log.Ferror(cause),
+		)
+		c.snapshotPending = true
+	default:
+		return fmt.Errorf("%w: %w", ErrHistoryLost, cause)
+	}
+
+	c.lastToken = nil
+	c.startAt = nil
+	c.startAfter = nil
+	// the progress of a previous snapshot is older than the history
+	if err := c.snapshotStorage.Clear(); err != nil {
+		return err
+	}
+	return c.tokenManager.Clear()
+}
@@ -0,0 +1,113 @@
+package mongo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/mock/gomock"
+
+	"github.com/ucpr/mongo-streamer/internal/persistent/mock"
+)
+
+func TestParseHistoryLostPolicy(t *testing.T) {
+	t.Parallel()
+
+	patterns := []struct {
+		name    string
+		in      string
+		want    HistoryLostPolicy
+		wantErr bool
+	}{
+		{name: "empty", in: "", want: HistoryLostFail},
+		{name: "fail", in: "fail", want: HistoryLostFail},
+		{name: "restart", in: "restart", want: HistoryLostRestart},
+		{name: "resnapshot", in: "resnapshot", want: HistoryLostResnapshot},
+		{name: "invalid", in: "ignore", wantErr: true},
+	}
+
+	for _, tt := range patterns {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := ParseHistoryLostPolicy(tt.in)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestChangeStream_recoverHistoryLost(t *testing.T) {
+	t.Parallel()
+
+	cause := mongo.CommandError{Code: 286, Name: "ChangeStreamHistoryLost"}
+
+	patterns := []struct {
+		name         string
+		policy       HistoryLostPolicy
+		clear        bool
+		wantSnapshot bool
+		err          error
+	}{
+		{
+			name:   "fail",
+			policy: HistoryLostFail,
+			err:    ErrHistoryLost,
+		},
+		{
+			name:   "restart",
+			policy: HistoryLostRestart,
+			clear:  true,
+		},
+		{
+			name:         "resnapshot",
+			policy:       HistoryLostResnapshot,
+			clear:        true,
+			wantSnapshot: true,
+		},
+	}
+
+	for _, tt := range patterns {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			buf := mock.NewMockStorageBuffer(ctrl)
+			snapshots := mock.NewMockStorageBuffer(ctrl)
+			if tt.clear {
+				buf.EXPECT().Clear().Return(nil).Times(1)
+				snapshots.EXPECT().Clear().Return(nil).Times(1)
+			}
+
+			c := &ChangeStream{
+				name:            "stream",
+				tokenManager:    buf,
+				snapshotStorage: snapshots,
+				historyLost:     tt.policy,
+				lastToken:       bson.Raw{0x05, 0x00, 0x00, 0x00, 0x00},
+				startAt:         &primitive.Timestamp{T: 1},
+				startAfter:      bson.Raw{0x05, 0x00, 0x00, 0x00, 0x00},
+			}
+			err := c.recoverHistoryLost(cause)
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.wantSnapshot, c.snapshotPending)
+			if tt.err == nil {
+				// the change stream is reopened from now, or from the snapshot
+				assert.Nil(t, c.lastToken)
+				assert.Nil(t, c.startAt)
+				assert.Nil(t, c.startAfter)
+			} else {
+				// the cause is kept for the classification
+				assert.Equal(t, ErrorClassHistoryLost, ClassifyError(err))
+			}
+		})
+	}
+}
@@ -60,3 +60,21 @@ func (c *Client) Disconnect(ctx context.Context) error {
 func (c *Client) Collection(name string) *mongo.Collection {
 	return c.cli.Database(c.db).Collection(name)
 }
+
+// Database returns a database from the MongoDB client.
+func (c *Client) Database(name string) *mongo.Database {
+	return c.cli.Database(name)
+}
+
+// watch opens a change stream on the collection, the database, or the whole
+// deployment of the client, depending on which fields of ns are set.
+func (c *Client) watch(ctx context.Context, ns Namespace, pipeline interface{}, opts ...*options.ChangeStreamOptions) (*mongo.ChangeStream, error) {
+	switch {
+	case ns.Collection != "":
+		return c.cli.Database(ns.Database).Collection(ns.Collection).Watch(ctx, pipeline, opts...)
+	case ns.Database != "":
+		return c.cli.Database(ns.Database).Watch(ctx, pipeline, opts...)
+	default:
+		return c.cli.Watch(ctx, pipeline, opts...)
+	}
+}
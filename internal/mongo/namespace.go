@@ -0,0 +1,128 @@
+package mongo
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Namespace is the namespace of a change event.
+type Namespace struct {
+	Database   string
+	Collection string
+}
+
+// String returns the namespace as "<database>.<collection>", or "<database>"
+// for events without a collection such as dropDatabase.
+func (n Namespace) String() string {
+	if n.Collection == "" {
+		return n.Database
+	}
+	return n.Database + "." + n.Collection
+}
+
+// namespaceOf returns the namespace of the raw change event.
+func namespaceOf(raw bson.Raw) Namespace {
+	var ns Namespace
+	if v, err := raw.LookupErr("ns", "db"); err == nil {
+		ns.Database, _ = v.StringValueOK()
+	}
+	if v, err := raw.LookupErr("ns", "coll"); err == nil {
+		ns.Collection, _ = v.StringValueOK()
+	}
+	return ns
+}
+
+// NamespaceFilter selects the namespaces to stream by include and exclude patterns.
+//
+// A pattern is matched against "<database>.<collection>". It is a glob as of
+// path.Match (e.g. "db.logs_*"), or a regular expression when it is enclosed
+// in slashes (e.g. "/^db\.logs_[0-9]+$/"). An event without a collection, such
+// as dropDatabase, matches both "<database>" and "<database>.", so that "db.*"
+// also matches the events of the database itself.
+type NamespaceFilter struct {
+	include []namespaceMatcher
+	exclude []namespaceMatcher
+}
+
+type namespaceMatcher func(ns string) bool
+
+// NewNamespaceFilter creates a filter that matches namespaces matching any of
+// include, or all namespaces if include is empty, and none of exclude.
+func NewNamespaceFilter(include, exclude []string) (*NamespaceFilter, error) {
+	in, err := compileNamespacePatterns(include)
+	if err != nil {
+		return nil, err
+	}
+	ex, err := compileNamespacePatterns(exclude)
+	if err != nil {
+		return nil, err
+	}
+	return &NamespaceFilter{
+		include: in,
+		exclude: ex,
+	}, nil
+}
+
+// Match reports whether the namespace is streamed. A nil filter matches all namespaces.
+func (f *NamespaceFilter) Match(ns Namespace) bool {
+	if f == nil {
+		return true
+	}
+	names := []string{ns.String()}
+	if ns.Collection == "" {
+		names = append(names, ns.Database+".")
+	}
+	if matchNamespace(f.exclude, names) {
+		return false
+	}
+	return len(f.include) == 0 || matchNamespace(f.include, names)
+}
+
+// matchNamespace reports whether any of the matchers matches any of the names.
+func matchNamespace(matchers []namespaceMatcher, names []string) bool {
+	for _, m := range matchers {
+		for _, name := range names {
+			if m(name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func compileNamespacePatterns(patterns []string) ([]namespaceMatcher, error) {
+	matchers := make([]namespaceMatcher, 0, len(patterns))
+	for _, p := range patterns {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		m, err := compileNamespacePattern(p)
+		if err != nil {
+			return nil, err
+		}
+		matchers = append(matchers, m)
+	}
+	return matchers, nil
+}
+
+func compileNamespacePattern(p string) (namespaceMatcher, error) {
+	if len(p) > 1 && strings.HasPrefix(p, "/") && strings.HasSuffix(p, "/") {
+		re, err := regexp.Compile(p[1 : len(p)-1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid namespace pattern %q: %w", p, err)
+		}
+		return re.MatchString, nil
+	}
+	if _, err := path.Match(p, ""); err != nil {
+		return nil, fmt.Errorf("invalid namespace pattern %q: %w", p, err)
+	}
+	return func(ns string) bool {
+		ok, _ := path.Match(p, ns)
+		return ok
+	}, nil
+}
@@ -0,0 +1,123 @@
+package mongo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestNamespaceFilter_Match(t *testing.T) {
+	t.Parallel()
+
+	patterns := []struct {
+		name    string
+		include []string
+		exclude []string
+		ns      Namespace
+		want    bool
+	}{
+		{
+			name: "no patterns",
+			ns:   Namespace{Database: "db", Collection: "col"},
+			want: true,
+		},
+		{
+			name:    "include glob",
+			include: []string{"db.logs_*"},
+			ns:      Namespace{Database: "db", Collection: "logs_2024"},
+			want:    true,
+		},
+		{
+			name:    "not included",
+			include: []string{"db.logs_*"},
+			ns:      Namespace{Database: "db", Collection: "users"},
+			want:    false,
+		},
+		{
+			name:    "include regular expression",
+			include: []string{`/^db\.logs_[0-9]+$/`},
+			ns:      Namespace{Database: "db", Collection: "logs_2024"},
+			want:    true,
+		},
+		{
+			name:    "exclude takes precedence over include",
+			include: []string{"db.*"},
+			exclude: []string{"db.secrets"},
+			ns:      Namespace{Database: "db", Collection: "secrets"},
+			want:    false,
+		},
+		{
+			name:    "exclude only",
+			exclude: []string{"/^other\\./"},
+			ns:      Namespace{Database: "db", Collection: "col"},
+			want:    true,
+		},
+		{
+			name:    "event without collection",
+			include: []string{"db"},
+			ns:      Namespace{Database: "db"},
+			want:    true,
+		},
+		{
+			name:    "exclude event without collection by database glob",
+			exclude: []string{"db.*"},
+			ns:      Namespace{Database: "db"},
+			want:    false,
+		},
+		{
+			name:    "include event without collection by database glob",
+			include: []string{"db.*"},
+			ns:      Namespace{Database: "db"},
+			want:    true,
+		},
+		{
+			name:    "event without collection of another database",
+			exclude: []string{"db.*"},
+			ns:      Namespace{Database: "other"},
+			want:    true,
+		},
+		{
+			name:    "event without collection does not match a collection",
+			include: []string{"db.users"},
+			ns:      Namespace{Database: "db"},
+			want:    false,
+		},
+	}
+
+	for _, tt := range patterns {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			f, err := NewNamespaceFilter(tt.include, tt.exclude)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, f.Match(tt.ns))
+		})
+	}
+}
+
+func TestNewNamespaceFilter_InvalidPattern(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewNamespaceFilter([]string{"db.["}, nil)
+	assert.Error(t, err)
+	_, err = NewNamespaceFilter(nil, []string{"/(/"})
+	assert.Error(t, err)
+}
+
+func TestNamespaceOf(t *testing.T) {
+	t.Parallel()
+
+	raw, err := bson.Marshal(bson.D{
+		{Key: "operationType", Value: "insert"},
+		{Key: "ns", Value: bson.D{{Key: "db", Value: "db"}, {Key: "coll", Value: "col"}}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, Namespace{Database: "db", Collection: "col"}, namespaceOf(raw))
+
+	raw, err = bson.Marshal(bson.D{{Key: "operationType", Value: "invalidate"}})
+	require.NoError(t, err)
+	assert.Equal(t, Namespace{}, namespaceOf(raw))
+}
@@ -0,0 +1,328 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/ucpr/mongo-streamer/internal/deadletter"
+	mmetric "github.com/ucpr/mongo-streamer/internal/metric/mongo"
+	"github.com/ucpr/mongo-streamer/internal/model"
+	"github.com/ucpr/mongo-streamer/internal/persistent"
+	"github.com/ucpr/mongo-streamer/internal/pubsub"
+	"github.com/ucpr/mongo-streamer/pkg/backoff"
+	"github.com/ucpr/mongo-streamer/pkg/log"
+)
+
+const (
+	// defaultMaxInFlight is the default number of events that can be in flight.
+	defaultMaxInFlight = 1
+	// deadLetterTimeout is the timeout to store an event to the dead letter queue.
+	deadLetterTimeout = 30 * time.Second
+)
+
+// errPipelineStopped is returned by dispatch after an event failed permanently.
+var errPipelineStopped = errors.New("pipeline stopped")
+
+// pipeline publishes change events concurrently and commits their checkpoints in order.
+//
+// Events are passed to the handler in the order of the change stream, so that
+// the sink receives them in order, and up to maxInFlight events wait for the
+// acknowledgement concurrently. The checkpoint is committed by the tracker up to
+// the highest contiguous acknowledged event. Events with the same ordering key are
+// in flight one at a time, so that a retried event is not overtaken by a later one.
+type pipeline struct {
+	stream      string
+	handler     ChangeStreamHandler
+	orderingKey OrderingKeyFunc
+	retry       RetryPolicy
+	tracker     *persistent.Tracker
+	deadLetter  deadletter.Sink
+
+	// sem limits the number of in-flight events
+	sem chan struct{}
+	wg  sync.WaitGroup
+
+	// keys serializes the in-flight events of the same ordering key
+	keys *keyLocks
+
+	// stopped is closed when an event failed permanently
+	stopped chan struct{}
+	errOnce sync.Once
+	err     error
+}
+
+// pipelineParams is a struct that represents parameters for creating a pipeline.
+type pipelineParams struct {
+	// Stream is the name of the stream, used in metrics and dead letter entries.
+	Stream  string
+	Handler ChangeStreamHandler
+	// OrderingKey returns the ordering key of an event, nil if the events are not ordered.
+	OrderingKey OrderingKeyFunc
+	// Keys are the locks of the ordering keys, shared by the pipelines that publish
+	// the events of the same documents. A pipeline has its own locks if it is nil.
+	Keys    *keyLocks
+	Retry   RetryPolicy
+	Tracker *persistent.Tracker
+	// DeadLetter stores events that failed permanently, nil stops the pipeline instead.
+	DeadLetter  deadletter.Sink
+	MaxInFlight int
+}
+
+func newPipeline(params pipelineParams) *pipeline {
+	maxInFlight := params.MaxInFlight
+	if maxInFlight < 1 {
+		maxInFlight = defaultMaxInFlight
+	}
+	keys := params.Keys
+	if keys == nil {
+		keys = newKeyLocks()
+	}
+	return &pipeline{
+		stream:      params.Stream,
+		handler:     params.Handler,
+		orderingKey: params.OrderingKey,
+		retry:       params.Retry,
+		tracker:     params.Tracker,
+		deadLetter:  params.DeadLetter,
+		sem:         make(chan struct{}, maxInFlight),
+		keys:        keys,
+		stopped:     make(chan struct{}),
+	}
+}
+
+// dispatch passes the event to the handler and waits for the acknowledgement
+// in the background. It blocks while the number of in-flight events is at the limit,
+// or while an event with the same ordering key is in flight.
+// raw is the raw BSON of the event, which is dead-lettered when the event fails permanently.
+func (p *pipeline) dispatch(ctx context.Context, event model.ChangeEvent, raw bson.Raw, ns Namespace, cp persistent.Checkpoint) error {
+	select {
+	case <-p.stopped:
+		return errPipelineStopped
+	default:
+	}
+	select {
+	case p.sem <- struct{}{}:
+	case <-p.stopped:
+		return errPipelineStopped
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	release, err := p.serialize(ctx, event)
+	if err != nil {
+		<-p.sem
+		return err
+	}
+
+	seq := p.tracker.Track(cp)
+	// the handler is not canceled by ctx so that an in-flight publish completes on shutdown
+	res, err := p.handler(context.Background(), event)
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		defer func() { <-p.sem }()
+		defer release()
+
+		attempts, err := p.await(ctx, event, ns, res, err)
+		if err != nil {
+			if ctx.Err() != nil || p.deadLetter == nil {
+				p.stop(err)
+				return
+			}
+			if err := p.putDeadLetter(raw, ns, cp, err, attempts); err != nil {
+				p.stop(err)
+				return
+			}
+		} else {
+			mmetric.HandleChangeEventSuccess(p.stream, ns.Database, ns.Collection)
+		}
+		if err := p.tracker.Ack(seq); err != nil {
+			if errors.Is(err, persistent.ErrConflict) {
+				// another instance owns the resume token
+				p.stop(err)
+				return
+			}
+			log.Error("Failed to save resume token", log.Ferror(err))
+		}
+	}()
+	return nil
+}
+
+// serialize waits until the in-flight event with the same ordering key as the event
+// is finished, and returns the function to call when the event is finished.
+func (p *pipeline) serialize(ctx context.Context, event model.ChangeEvent) (func(), error) {
+	if p.orderingKey == nil {
+		return func() {}, nil
+	}
+	key := p.orderingKey(event)
+	if key == "" {
+		return func() {}, nil
+	}
+	return p.keys.acquire(ctx, key, p.stopped)
+}
+
+// keyLocks serializes the in-flight events of the same ordering key.
+type keyLocks struct {
+	mu sync.Mutex
+	// keys are the done channels of the last in-flight event of each ordering key
+	keys map[string]chan struct{}
+}
+
+func newKeyLocks() *keyLocks {
+	return &keyLocks{keys: make(map[string]chan struct{})}
+}
+
+// acquire waits until the in-flight event of the key is finished, and returns
+// the function to call when the event of the caller is finished. Events acquire
+// the key in the order of the calls.
+func (l *keyLocks) acquire(ctx context.Context, key string, stopped <-chan struct{}) (func(), error) {
+	done := make(chan struct{})
+	l.mu.Lock()
+	prev := l.keys[key]
+	l.keys[key] = done
+	l.mu.Unlock()
+	release := func() {
+		l.mu.Lock()
+		if l.keys[key] == done {
+			delete(l.keys, key)
+		}
+		l.mu.Unlock()
+		close(done)
+	}
+	if prev == nil {
+		return release, nil
+	}
+	// the key is released after prev even if the caller gives up, so that the
+	// next event does not overtake prev
+	abort := func() {
+		go func() {
+			<-prev
+			release()
+		}()
+	}
+	select {
+	case <-prev:
+		return release, nil
+	case <-stopped:
+		abort()
+		return nil, errPipelineStopped
+	case <-ctx.Done():
+		abort()
+		return nil, ctx.Err()
+	}
+}
+
+// reject dead-letters an event that cannot be dispatched, e.g. it cannot be decoded.
+// It returns cause when no dead letter queue is configured.
+func (p *pipeline) reject(ctx context.Context, raw bson.Raw, ns Namespace, cp persistent.Checkpoint, cause error) error {
+	mmetric.HandleChangeEventFailed(p.stream, ns.Database, ns.Collection)
+	if p.deadLetter == nil {
+		return cause
+	}
+	select {
+	case p.sem <- struct{}{}:
+		defer func() { <-p.sem }()
+	case <-p.stopped:
+		return errPipelineStopped
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	seq := p.tracker.Track(cp)
+	if err := p.putDeadLetter(raw, ns, cp, cause, 1); err != nil {
+		return err
+	}
+	return p.tracker.Ack(seq)
+}
+
+// putDeadLetter stores the event to the dead letter queue.
+func (p *pipeline) putDeadLetter(raw bson.Raw, ns Namespace, cp persistent.Checkpoint, cause error, attempts int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), deadLetterTimeout)
+	defer cancel()
+
+	err := p.deadLetter.Put(ctx, deadletter.Entry{
+		Stream:     p.stream,
+		Event:      raw,
+		Token:      cp.Token,
+		Error:      cause.Error(),
+		Attempts:   attempts,
+		Database:   ns.Database,
+		Collection: ns.Collection,
+		CreatedAt:  time.Now().UTC(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to dead-letter change event: %w", err)
+	}
+	mmetric.DeadLetterChangeEvent(p.stream, ns.Database, ns.Collection)
+	log.Warn("Dead-lettered change event",
+		log.Ferror(cause),
+		log.Fstring("stream", p.stream),
+		log.Fint("attempts", attempts),
+		log.Fstring("namespace", ns.String()),
+		log.Fstring("token", cp.Token),
+	)
+	return nil
+}
+
+// await waits for the acknowledgement of the event and retries the event
+// according to the retry policy when it fails, unless the error is permanent.
+// It returns the number of attempts.
+func (p *pipeline) await(ctx context.Context, event model.ChangeEvent, ns Namespace, res pubsub.PublishResult, err error) (int, error) {
+	for attempt := 1; ; attempt++ {
+		if err == nil {
+			_, err = res.Get(context.Background())
+			if err == nil {
+				return attempt, nil
+			}
+		}
+		mmetric.HandleChangeEventFailed(p.stream, ns.Database, ns.Collection)
+		if backoff.IsPermanent(err) {
+			return attempt, fmt.Errorf("failed to handle change event: %w", err)
+		}
+		if p.retry.MaxAttempts > 0 && attempt >= p.retry.MaxAttempts {
+			return attempt, fmt.Errorf("failed to handle change event after %d attempts: %w", attempt, err)
+		}
+
+		delay := p.retry.Backoff.Duration(attempt)
+		log.Warn("Failed to handle change event, retrying",
+			log.Ferror(err),
+			log.Fint("attempt", attempt),
+			log.Fduration("delay", delay),
+		)
+		if err := backoff.Sleep(ctx, delay); err != nil {
+			return attempt, err
+		}
+		res, err = p.handler(context.Background(), event)
+	}
+}
+
+// skip commits the checkpoint of an event that is not streamed, in order with
+// the in-flight events.
+func (p *pipeline) skip(cp persistent.Checkpoint) error {
+	return p.tracker.Ack(p.tracker.Track(cp))
+}
+
+// stop stops the pipeline with the first permanent error.
+func (p *pipeline) stop(err error) {
+	p.errOnce.Do(func() {
+		p.err = err
+		close(p.stopped)
+	})
+}
+
+// wait waits for all in-flight events and returns the error that stopped the pipeline.
+func (p *pipeline) wait() error {
+	p.wg.Wait()
+
+	select {
+	case <-p.stopped:
+		return p.err
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,572 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+	"go.uber.org/mock/gomock"
+
+	"github.com/ucpr/mongo-streamer/internal/app"
+	"github.com/ucpr/mongo-streamer/internal/config"
+	"github.com/ucpr/mongo-streamer/internal/deadletter"
+	"github.com/ucpr/mongo-streamer/internal/model"
+	"github.com/ucpr/mongo-streamer/internal/persistent"
+	pmock "github.com/ucpr/mongo-streamer/internal/persistent/mock"
+	"github.com/ucpr/mongo-streamer/internal/pubsub"
+	"github.com/ucpr/mongo-streamer/internal/pubsub/mock"
+	"github.com/ucpr/mongo-streamer/pkg/backoff"
+)
+
+// fakeResult is a publish result that is acknowledged when ack is called.
+type fakeResult struct {
+	ready chan struct{}
+	err   error
+}
+
+func newFakeResult() *fakeResult {
+	return &fakeResult{ready: make(chan struct{})}
+}
+
+func (r *fakeResult) ack(err error) {
+	r.err = err
+	close(r.ready)
+}
+
+func (r *fakeResult) Ready() <-chan struct{} {
+	return r.ready
+}
+
+func (r *fakeResult) Get(ctx context.Context) (string, error) {
+	select {
+	case <-r.ready:
+		return "id", r.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+var testNamespace = Namespace{Database: "db", Collection: "col"}
+
+func testRetryPolicy(maxAttempts int) RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: maxAttempts,
+		Backoff: backoff.Backoff{
+			Initial:    time.Millisecond,
+			Multiplier: 1,
+		},
+	}
+}
+
+func TestPipeline_CommitInOrder(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	mb := pmock.NewMockStorageBuffer(ctrl)
+	committed := make(chan string, 3)
+	mb.EXPECT().Set(gomock.Any()).DoAndReturn(func(cp persistent.Checkpoint) error {
+		committed <- cp.Token
+		return nil
+	}).AnyTimes()
+
+	results := []*fakeResult{newFakeResult(), newFakeResult(), newFakeResult()}
+	var calls atomic.Int32
+	handler := func(ctx context.Context, event model.ChangeEvent) (pubsub.PublishResult, error) {
+		return results[calls.Add(1)-1], nil
+	}
+
+	p := newPipeline(pipelineParams{
+		Handler:     handler,
+		Retry:       testRetryPolicy(0),
+		Tracker:     persistent.NewTracker(mb),
+		MaxInFlight: 10,
+	})
+	for i := range results {
+		require.NoError(t, p.dispatch(ctx, model.ChangeEvent{}, nil, testNamespace, persistent.Checkpoint{Token: fmt.Sprintf("token-%d", i)}))
+	}
+
+	// acknowledging the last event does not commit it while earlier events are in flight
+	results[2].ack(nil)
+	results[1].ack(nil)
+	select {
+	case token := <-committed:
+		t.Fatalf("unexpected commit: %s", token)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	results[0].ack(nil)
+	require.NoError(t, p.wait())
+	assert.Equal(t, "token-2", <-committed)
+}
+
+func TestPipeline_Skip(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	mb := pmock.NewMockStorageBuffer(ctrl)
+	committed := make(chan string, 2)
+	mb.EXPECT().Set(gomock.Any()).DoAndReturn(func(cp persistent.Checkpoint) error {
+		committed <- cp.Token
+		return nil
+	}).AnyTimes()
+
+	res := newFakeResult()
+	handler := func(ctx context.Context, event model.ChangeEvent) (pubsub.PublishResult, error) {
+		return res, nil
+	}
+	p := newPipeline(pipelineParams{
+		Handler:     handler,
+		Retry:       testRetryPolicy(0),
+		Tracker:     persistent.NewTracker(mb),
+		MaxInFlight: 10,
+	})
+	require.NoError(t, p.dispatch(ctx, model.ChangeEvent{}, nil, testNamespace, persistent.Checkpoint{Token: "token-0"}))
+
+	// a skipped event is not committed while an earlier event is in flight
+	require.NoError(t, p.skip(persistent.Checkpoint{Token: "token-1"}))
+	assert.Empty(t, committed)
+
+	res.ack(nil)
+	require.NoError(t, p.wait())
+	assert.Equal(t, "token-1", <-committed)
+}
+
+func TestPipeline_Retry(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	patterns := []struct {
+		name        string
+		failures    int
+		maxAttempts int
+		wantCalls   int32
+		wantErr     bool
+	}{
+		{
+			name:      "success at first attempt",
+			failures:  0,
+			wantCalls: 1,
+		},
+		{
+			name:      "retry until success",
+			failures:  3,
+			wantCalls: 4,
+		},
+		{
+			name:        "retry until max attempts",
+			failures:    10,
+			maxAttempts: 3,
+			wantCalls:   3,
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range patterns {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			mb := pmock.NewMockStorageBuffer(ctrl)
+			if !tt.wantErr {
+				mb.EXPECT().Set(persistent.Checkpoint{Token: "token"}).Return(nil).Times(1)
+			}
+
+			var calls atomic.Int32
+			handler := func(ctx context.Context, event model.ChangeEvent) (pubsub.PublishResult, error) {
+				res := newFakeResult()
+				if int(calls.Add(1)) <= tt.failures {
+					res.ack(assert.AnError)
+				} else {
+					res.ack(nil)
+				}
+				return res, nil
+			}
+
+			p := newPipeline(pipelineParams{
+				Handler:     handler,
+				Retry:       testRetryPolicy(tt.maxAttempts),
+				Tracker:     persistent.NewTracker(mb),
+				MaxInFlight: 10,
+			})
+			require.NoError(t, p.dispatch(ctx, model.ChangeEvent{}, nil, testNamespace, persistent.Checkpoint{Token: "token"}))
+			err := p.wait()
+			if tt.wantErr {
+				assert.ErrorIs(t, err, assert.AnError)
+				// no more events are accepted after the pipeline stopped
+				assert.ErrorIs(t, p.dispatch(ctx, model.ChangeEvent{}, nil, testNamespace, persistent.Checkpoint{}), errPipelineStopped)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantCalls, calls.Load())
+		})
+	}
+}
+
+func TestPipeline_OrderingKey(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	mb := pmock.NewMockStorageBuffer(ctrl)
+	mb.EXPECT().Set(gomock.Any()).Return(nil).AnyTimes()
+
+	// the first attempt of "a" fails after "b" of the same key is dispatched
+	first := newFakeResult()
+	var (
+		mu    sync.Mutex
+		calls []string
+	)
+	handler := func(ctx context.Context, event model.ChangeEvent) (pubsub.PublishResult, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, event.OperationType)
+		if len(calls) == 1 {
+			return first, nil
+		}
+		res := newFakeResult()
+		res.ack(nil)
+		return res, nil
+	}
+	called := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), calls...)
+	}
+
+	p := newPipeline(pipelineParams{
+		Handler: handler,
+		OrderingKey: func(event model.ChangeEvent) string {
+			return event.Namespace.Coll
+		},
+		Retry:       testRetryPolicy(0),
+		Tracker:     persistent.NewTracker(mb),
+		MaxInFlight: 10,
+	})
+	event := func(id, key string) model.ChangeEvent {
+		return model.ChangeEvent{OperationType: id, Namespace: model.Namespace{DB: "db", Coll: key}}
+	}
+	require.NoError(t, p.dispatch(ctx, event("a", "k"), nil, testNamespace, persistent.Checkpoint{Token: "a"}))
+	// an event of another key is not blocked
+	require.NoError(t, p.dispatch(ctx, event("c", "other"), nil, testNamespace, persistent.Checkpoint{Token: "c"}))
+
+	dispatched := make(chan error, 1)
+	go func() {
+		dispatched <- p.dispatch(ctx, event("b", "k"), nil, testNamespace, persistent.Checkpoint{Token: "b"})
+	}()
+	select {
+	case err := <-dispatched:
+		t.Fatalf("event of the same key is dispatched while the previous one is in flight: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	assert.Equal(t, []string{"a", "c"}, called())
+
+	first.ack(assert.AnError)
+	require.NoError(t, <-dispatched)
+	require.NoError(t, p.wait())
+	// "b" is published after the retry of "a"
+	assert.Equal(t, []string{"a", "c", "a", "b"}, called())
+}
+
+func TestPipeline_Conflict(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	mb := pmock.NewMockStorageBuffer(ctrl)
+	mb.EXPECT().Set(persistent.Checkpoint{Token: "token"}).Return(persistent.ErrConflict).Times(1)
+
+	handler := func(ctx context.Context, event model.ChangeEvent) (pubsub.PublishResult, error) {
+		res := newFakeResult()
+		res.ack(nil)
+		return res, nil
+	}
+	p := newPipeline(pipelineParams{
+		Handler:     handler,
+		Retry:       testRetryPolicy(0),
+		Tracker:     persistent.NewTracker(mb),
+		MaxInFlight: 10,
+	})
+	require.NoError(t, p.dispatch(ctx, model.ChangeEvent{}, nil, testNamespace, persistent.Checkpoint{Token: "token"}))
+	// the pipeline stops when another instance owns the resume token
+	assert.ErrorIs(t, p.wait(), persistent.ErrConflict)
+}
+
+func TestPipeline_SharedKeys(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	mb := pmock.NewMockStorageBuffer(ctrl)
+	mb.EXPECT().Set(gomock.Any()).Return(nil).AnyTimes()
+
+	// the first attempt of the snapshot event fails after the live update is dispatched
+	first := newFakeResult()
+	var (
+		mu    sync.Mutex
+		calls []string
+	)
+	handler := func(ctx context.Context, event model.ChangeEvent) (pubsub.PublishResult, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, event.OperationType)
+		if len(calls) == 1 {
+			return first, nil
+		}
+		res := newFakeResult()
+		res.ack(nil)
+		return res, nil
+	}
+	orderingKey := func(event model.ChangeEvent) string {
+		return event.DocumentKey.String()
+	}
+	keys := newKeyLocks()
+	newTestPipeline := func() *pipeline {
+		return newPipeline(pipelineParams{
+			Handler:     handler,
+			OrderingKey: orderingKey,
+			Keys:        keys,
+			Retry:       testRetryPolicy(0),
+			Tracker:     persistent.NewTracker(mb),
+			MaxInFlight: 10,
+		})
+	}
+	live, snapshot := newTestPipeline(), newTestPipeline()
+
+	key := bson.Raw(bsoncore.NewDocumentBuilder().AppendInt32("_id", 1).Build())
+	require.NoError(t, snapshot.dispatch(ctx, model.ChangeEvent{OperationType: model.OperationTypeSnapshot, DocumentKey: key}, nil, testNamespace, persistent.Checkpoint{}))
+
+	dispatched := make(chan error, 1)
+	go func() {
+		dispatched <- live.dispatch(ctx, model.ChangeEvent{OperationType: "update", DocumentKey: key}, nil, testNamespace, persistent.Checkpoint{Token: "update"})
+	}()
+	select {
+	case err := <-dispatched:
+		t.Fatalf("live event is dispatched while the snapshot event of the document is in flight: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	first.ack(assert.AnError)
+	require.NoError(t, <-dispatched)
+	require.NoError(t, snapshot.wait())
+	require.NoError(t, live.wait())
+
+	mu.Lock()
+	defer mu.Unlock()
+	// the live update is published after the retry of the snapshot event
+	assert.Equal(t, []string{model.OperationTypeSnapshot, model.OperationTypeSnapshot, "update"}, calls)
+}
+
+func TestPipeline_HandlerError(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	mb := pmock.NewMockStorageBuffer(ctrl)
+
+	// events that cannot be published are retried as well
+	handler := func(ctx context.Context, event model.ChangeEvent) (pubsub.PublishResult, error) {
+		return nil, assert.AnError
+	}
+	p := newPipeline(pipelineParams{
+		Handler:     handler,
+		Retry:       testRetryPolicy(2),
+		Tracker:     persistent.NewTracker(mb),
+		MaxInFlight: 10,
+	})
+	require.NoError(t, p.dispatch(ctx, model.ChangeEvent{}, nil, testNamespace, persistent.Checkpoint{Token: "token"}))
+	assert.ErrorIs(t, p.wait(), assert.AnError)
+}
+
+// fakeDeadLetter is a dead letter queue that stores entries in memory.
+type fakeDeadLetter struct {
+	mu      sync.Mutex
+	entries []deadletter.Entry
+	err     error
+}
+
+func (d *fakeDeadLetter) Put(ctx context.Context, entry deadletter.Entry) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.err != nil {
+		return d.err
+	}
+	d.entries = append(d.entries, entry)
+	return nil
+}
+
+func (d *fakeDeadLetter) Close(ctx context.Context) error {
+	return nil
+}
+
+func TestPipeline_DeadLetter(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	raw := bson.Raw(bsoncore.NewDocumentBuilder().AppendString("_id", "id").Build())
+
+	patterns := []struct {
+		name         string
+		handlerErr   error
+		deadLetter   *fakeDeadLetter
+		wantAttempts int
+		wantCommit   bool
+		wantErr      bool
+	}{
+		{
+			name:         "dead-letter after max attempts",
+			handlerErr:   assert.AnError,
+			deadLetter:   &fakeDeadLetter{},
+			wantAttempts: 2,
+			wantCommit:   true,
+		},
+		{
+			name:         "dead-letter permanent error without retry",
+			handlerErr:   backoff.Permanent(assert.AnError),
+			deadLetter:   &fakeDeadLetter{},
+			wantAttempts: 1,
+			wantCommit:   true,
+		},
+		{
+			name:       "stop when dead letter queue fails",
+			handlerErr: assert.AnError,
+			deadLetter: &fakeDeadLetter{err: errors.New("unavailable")},
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range patterns {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			mb := pmock.NewMockStorageBuffer(ctrl)
+			if tt.wantCommit {
+				mb.EXPECT().Set(persistent.Checkpoint{Token: "token"}).Return(nil).Times(1)
+			}
+
+			handler := func(ctx context.Context, event model.ChangeEvent) (pubsub.PublishResult, error) {
+				return nil, tt.handlerErr
+			}
+			p := newPipeline(pipelineParams{
+				Handler:     handler,
+				Retry:       testRetryPolicy(2),
+				Tracker:     persistent.NewTracker(mb),
+				DeadLetter:  tt.deadLetter,
+				MaxInFlight: 10,
+			})
+			require.NoError(t, p.dispatch(ctx, model.ChangeEvent{}, raw, testNamespace, persistent.Checkpoint{Token: "token"}))
+			err := p.wait()
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, tt.deadLetter.entries, 1)
+			entry := tt.deadLetter.entries[0]
+			assert.Equal(t, raw, entry.Event)
+			assert.Equal(t, "token", entry.Token)
+			assert.Equal(t, tt.wantAttempts, entry.Attempts)
+			assert.Contains(t, entry.Error, assert.AnError.Error())
+			assert.Equal(t, "db", entry.Database)
+			assert.Equal(t, "col", entry.Collection)
+		})
+	}
+}
+
+func TestPipeline_Reject(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	raw := bson.Raw(bsoncore.NewDocumentBuilder().AppendString("_id", "id").Build())
+
+	t.Run("dead-letter the event", func(t *testing.T) {
+		t.Parallel()
+
+		ctrl := gomock.NewController(t)
+		mb := pmock.NewMockStorageBuffer(ctrl)
+		mb.EXPECT().Set(persistent.Checkpoint{Token: "token"}).Return(nil).Times(1)
+
+		dl := &fakeDeadLetter{}
+		p := newPipeline(pipelineParams{
+			Tracker:    persistent.NewTracker(mb),
+			DeadLetter: dl,
+		})
+		require.NoError(t, p.reject(ctx, raw, testNamespace, persistent.Checkpoint{Token: "token"}, assert.AnError))
+		require.NoError(t, p.wait())
+		require.Len(t, dl.entries, 1)
+		assert.Equal(t, assert.AnError.Error(), dl.entries[0].Error)
+	})
+
+	t.Run("return the cause without dead letter queue", func(t *testing.T) {
+		t.Parallel()
+
+		ctrl := gomock.NewController(t)
+		mb := pmock.NewMockStorageBuffer(ctrl)
+
+		p := newPipeline(pipelineParams{
+			Tracker: persistent.NewTracker(mb),
+		})
+		assert.ErrorIs(t, p.reject(ctx, raw, testNamespace, persistent.Checkpoint{Token: "token"}, assert.AnError), assert.AnError)
+	})
+}
+
+// benchmarkPublishLatency is the simulated round trip of a publish.
+const benchmarkPublishLatency = time.Millisecond
+
+func benchmarkPipeline(b *testing.B, maxInFlight int) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(b)
+
+	mp := mock.NewMockPublisher(ctrl)
+	mp.EXPECT().AsyncPublish(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, msg pubsub.Message) pubsub.PublishResult {
+		res := newFakeResult()
+		time.AfterFunc(benchmarkPublishLatency, func() { res.ack(nil) })
+		return res
+	}).AnyTimes()
+	mb := pmock.NewMockStorageBuffer(ctrl)
+	mb.EXPECT().Set(gomock.Any()).Return(nil).AnyTimes()
+
+	h, err := app.NewHandler(mp, &config.PubSub{PublishFormat: config.PubSubPublishFormatJSON})
+	require.NoError(b, err)
+	p := newPipeline(pipelineParams{
+		Handler:     h.AsyncEventHandler,
+		Retry:       testRetryPolicy(0),
+		Tracker:     persistent.NewTracker(mb),
+		MaxInFlight: maxInFlight,
+	})
+	event := model.ChangeEvent{
+		ID:            bson.Raw(bsoncore.NewDocumentBuilder().AppendString("_data", "id").Build()),
+		OperationType: "insert",
+		FullDocument:  bson.Raw(bsoncore.NewDocumentBuilder().AppendString("_id", "id").AppendString("text", "Hello, World!").Build()),
+		DocumentKey:   bson.Raw(bsoncore.NewDocumentBuilder().AppendString("_id", "id").Build()),
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := p.dispatch(ctx, event, nil, testNamespace, persistent.Checkpoint{Token: "token"}); err != nil {
+			b.Fatal(err)
+		}
+	}
+	if err := p.wait(); err != nil {
+		b.Fatal(err)
+	}
+}
+
+func BenchmarkPipeline(b *testing.B) {
+	for _, n := range []int{1, 10, 100} {
+		n := n
+		b.Run(fmt.Sprintf("max_in_flight=%d", n), func(b *testing.B) {
+			benchmarkPipeline(b, n)
+		})
+	}
+}
@@ -0,0 +1,320 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/ucpr/mongo-streamer/internal/persistent"
+	"github.com/ucpr/mongo-streamer/pkg/log"
+)
+
+var (
+	// ErrStreamNotFound is returned when a re-snapshot is requested for an unknown stream.
+	ErrStreamNotFound = errors.New("stream is not found")
+	// ErrNamespaceNotWatched is returned when a re-snapshot is requested for a
+	// collection that the stream does not watch.
+	ErrNamespaceNotWatched = errors.New("namespace is not watched by the stream")
+	// ErrResnapshotRunning is returned when a re-snapshot of the stream is already running.
+	ErrResnapshotRunning = errors.New("re-snapshot of the stream is already running")
+)
+
+// maxFinishedResnapshots is the number of finished re-snapshots whose status is kept.
+const maxFinishedResnapshots = 100
+
+// ResnapshotState is the state of a re-snapshot.
+type ResnapshotState string
+
+const (
+	// ResnapshotRunning is the state while the documents are sent.
+	ResnapshotRunning ResnapshotState = "running"
+	// ResnapshotSucceeded is the state after all the documents are sent.
+	ResnapshotSucceeded ResnapshotState = "succeeded"
+	// ResnapshotFailed is the state after the re-snapshot stopped with an error.
+	ResnapshotFailed ResnapshotState = "failed"
+)
+
+// ResnapshotRequest is a request to re-send the documents of a collection.
+type ResnapshotRequest struct {
+	// Stream is the name of the stream whose sink the documents are sent to.
+	Stream string
+	// Database and Collection are the collection to read. They default to the
+	// database and the collection watched by the stream.
+	Database   string
+	Collection string
+	// Filter selects the documents to send, all documents are sent if it is nil.
+	Filter bson.Raw
+}
+
+// ResnapshotStatus is the progress of a re-snapshot.
+type ResnapshotStatus struct {
+	ID        string          `json:"id"`
+	Stream    string          `json:"stream"`
+	Namespace string          `json:"namespace"`
+	Filter    string          `json:"filter,omitempty"`
+	State     ResnapshotState `json:"state"`
+	// Total is the number of documents that matched the filter when the re-snapshot started.
+	Total int64 `json:"total"`
+	// Sent is the number of documents passed to the handler. When the re-snapshot
+	// succeeded, they are all acknowledged or dead-lettered.
+	Sent       int64      `json:"sent"`
+	Error      string     `json:"error,omitempty"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// resnapshotJob is a running or finished re-snapshot.
+type resnapshotJob struct {
+	mu     sync.Mutex
+	status ResnapshotStatus
+	sent   atomic.Int64
+}
+
+// current returns the current status of the job.
+func (j *resnapshotJob) current() ResnapshotStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	s := j.status
+	s.Sent = j.sent.Load()
+	return s
+}
+
+// finish marks the job as finished with the error.
+func (j *resnapshotJob) finish(err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	now := time.Now()
+	j.status.FinishedAt = &now
+	j.status.State = ResnapshotSucceeded
+	if err != nil {
+		j.status.State = ResnapshotFailed
+		j.status.Error = err.Error()
+	}
+}
+
+// Resnapshotter re-sends the documents of the watched collections on demand.
+//
+// The documents are read in _id order and sent through the handler of the stream
+// as snapshot events, concurrently with the live change stream. They have their
+// own pipeline, so the resume token of the change stream is not affected, but share
+// the ordering key locks with the change stream, so that a snapshot event and a change
+// of the same document are published one at a time. Their cluster time is the time
+// before the documents are read, so that consumers can order them with the changes.
+type Resnapshotter struct {
+	// ctx is the context of the jobs, canceled by Close
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu      sync.Mutex
+	streams map[string]*ChangeStream
+	jobs    map[string]*resnapshotJob
+	// running are the ids of the running jobs by stream
+	running map[string]string
+	// finished are the ids of the finished jobs, oldest first
+	finished []string
+	seq      int
+}
+
+// NewResnapshotter creates a new Resnapshotter without streams.
+func NewResnapshotter() *Resnapshotter {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Resnapshotter{
+		ctx:     ctx,
+		cancel:  cancel,
+		streams: make(map[string]*ChangeStream),
+		jobs:    make(map[string]*resnapshotJob),
+		running: make(map[string]string),
+	}
+}
+
+// Register makes the change stream available to re-snapshots by its name.
+func (r *Resnapshotter) Register(cs *ChangeStream) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.streams[cs.name] = cs
+}
+
+// Start starts a re-snapshot in the background and returns its status.
+// Only one re-snapshot of a stream runs at a time.
+func (r *Resnapshotter) Start(ctx context.Context, req ResnapshotRequest) (ResnapshotStatus, error) {
+	r.mu.Lock()
+	cs, ok := r.streams[req.Stream]
+	_, running := r.running[req.Stream]
+	r.mu.Unlock()
+	if !ok {
+		return ResnapshotStatus{}, fmt.Errorf("%w: %s", ErrStreamNotFound, req.Stream)
+	}
+	if running {
+		return ResnapshotStatus{}, fmt.Errorf("%w: %s", ErrResnapshotRunning, req.Stream)
+	}
+
+	ns := Namespace{Database: req.Database, Collection: req.Collection}
+	if ns.Database == "" {
+		ns.Database = cs.target.Database
+	}
+	if ns.Collection == "" {
+		ns.Collection = cs.target.Collection
+	}
+	if !cs.watches(ns) {
+		return ResnapshotStatus{}, fmt.Errorf("%w: %s", ErrNamespaceNotWatched, ns)
+	}
+	var filter interface{} = bson.D{}
+	if len(req.Filter) > 0 {
+		filter = req.Filter
+	}
+	total, err := cs.client.Database(ns.Database).Collection(ns.Collection).CountDocuments(ctx, filter)
+	if err != nil {
+		return ResnapshotStatus{}, fmt.Errorf("failed to count documents: %w", err)
+	}
+
+	r.mu.Lock()
+	if _, ok := r.running[req.Stream]; ok {
+		r.mu.Unlock()
+		return ResnapshotStatus{}, fmt.Errorf("%w: %s", ErrResnapshotRunning, req.Stream)
+	}
+	r.seq++
+	job := &resnapshotJob{status: ResnapshotStatus{
+		ID:        strconv.Itoa(r.seq),
+		Stream:    req.Stream,
+		Namespace: ns.String(),
+		State:     ResnapshotRunning,
+		Total:     total,
+		StartedAt: time.Now(),
+	}}
+	if len(req.Filter) > 0 {
+		job.status.Filter = req.Filter.String()
+	}
+	r.jobs[job.status.ID] = job
+	r.running[req.Stream] = job.status.ID
+	r.wg.Add(1)
+	r.mu.Unlock()
+
+	log.Info("Start re-snapshot",
+		log.Fstring("id", job.status.ID),
+		log.Fstring("stream", req.Stream),
+		log.Fstring("namespace", ns.String()),
+		log.Fstring("filter", job.status.Filter),
+	)
+	go func() {
+		defer r.wg.Done()
+
+		err := cs.resnapshot(r.ctx, ns, filter, &job.sent)
+		job.finish(err)
+		r.retire(req.Stream, job.status.ID)
+
+		status := job.current()
+		if err != nil {
+			log.Error("Failed to re-snapshot",
+				log.Fstring("id", status.ID),
+				log.Fstring("stream", status.Stream),
+				log.Fint("sent", int(status.Sent)),
+				log.Ferror(err),
+			)
+			return
+		}
+		log.Info("Finished re-snapshot",
+			log.Fstring("id", status.ID),
+			log.Fstring("stream", status.Stream),
+			log.Fint("sent", int(status.Sent)),
+		)
+	}()
+	return job.current(), nil
+}
+
+// retire removes the finished job from the running jobs, and forgets the oldest
+// finished jobs beyond maxFinishedResnapshots.
+func (r *Resnapshotter) retire(stream, id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.running, stream)
+	r.finished = append(r.finished, id)
+	for len(r.finished) > maxFinishedResnapshots {
+		delete(r.jobs, r.finished[0])
+		r.finished = r.finished[1:]
+	}
+}
+
+// Status returns the status of the re-snapshot of the id.
+func (r *Resnapshotter) Status(id string) (ResnapshotStatus, bool) {
+	r.mu.Lock()
+	job, ok := r.jobs[id]
+	r.mu.Unlock()
+	if !ok {
+		return ResnapshotStatus{}, false
+	}
+	return job.current(), true
+}
+
+// Close cancels the running re-snapshots and waits for them to stop.
+func (r *Resnapshotter) Close(ctx context.Context) error {
+	r.cancel()
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// watches reports whether the change stream watches the namespace.
+func (c *ChangeStream) watches(ns Namespace) bool {
+	if ns.Database == "" || ns.Collection == "" {
+		return false
+	}
+	if c.target.Database != "" && c.target.Database != ns.Database {
+		return false
+	}
+	if c.target.Collection != "" && c.target.Collection != ns.Collection {
+		return false
+	}
+	return c.namespaces.Match(ns)
+}
+
+// resnapshot sends the documents of the collection that match the filter through
+// the handler as snapshot events, and counts the sent documents.
+func (c *ChangeStream) resnapshot(ctx context.Context, ns Namespace, filter interface{}, sent *atomic.Int64) error {
+	ts, err := c.client.operationTime(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read cluster time: %w", err)
+	}
+
+	// the checkpoints are discarded, the change stream saves its own resume token
+	p := newPipeline(pipelineParams{
+		Stream:      c.name,
+		Handler:     c.handler,
+		OrderingKey: c.orderingKey,
+		Keys:        c.keys,
+		Retry:       c.retry,
+		Tracker:     persistent.NewTracker(discardBuffer{}),
+		DeadLetter:  c.deadLetter,
+		MaxInFlight: c.maxInFlight,
+	})
+	cp := persistent.Checkpoint{ClusterTime: ts}
+	_, err = c.scanCollection(ctx, p, ns, ts, filter, func(id *bson.RawValue) persistent.Checkpoint {
+		if id != nil {
+			sent.Add(1)
+		}
+		return cp
+	})
+	// wait for in-flight events so that the sent documents are acknowledged
+	if werr := p.wait(); werr != nil {
+		err = werr
+	}
+	return err
+}
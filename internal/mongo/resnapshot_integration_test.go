@@ -0,0 +1,86 @@
+//go:build integration
+
+package mongo
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/ucpr/mongo-streamer/internal/config"
+	"github.com/ucpr/mongo-streamer/internal/model"
+	"github.com/ucpr/mongo-streamer/internal/persistent"
+)
+
+//nolint:paralleltest
+func TestResnapshotter_Start_Filter(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	cli, err := NewClient(ctx, &config.MongoDB{URI: testMongoURI})
+	require.NoError(t, err)
+	defer cli.Disconnect(ctx)
+
+	const database = "test_resnapshot_filter"
+	coll := cli.Database(database).Collection("users")
+	require.NoError(t, cli.Database(database).Drop(ctx))
+	_, err = coll.InsertMany(ctx, []interface{}{
+		bson.M{"_id": 1, "status": "active"},
+		bson.M{"_id": 2, "status": "inactive"},
+		bson.M{"_id": 3, "status": "active"},
+	})
+	require.NoError(t, err)
+
+	fs, err := persistent.NewFileWriter(filepath.Join(t.TempDir(), "token"))
+	require.NoError(t, err)
+	buf, err := persistent.NewBuffer(1, time.Second, fs)
+	require.NoError(t, err)
+
+	events := make(chan model.ChangeEvent, 10)
+	cs, err := NewChangeStream(ctx, ChangeStreamParams{
+		Name:       "users",
+		Client:     cli,
+		Handler:    testChannelHandler(events),
+		Storage:    buf,
+		Database:   database,
+		Collection: "users",
+	})
+	require.NoError(t, err)
+	defer cs.Close(context.Background())
+
+	r := NewResnapshotter()
+	r.Register(cs)
+	defer r.Close(context.Background())
+
+	filter, err := bson.Marshal(bson.D{{Key: "status", Value: "active"}})
+	require.NoError(t, err)
+	status, err := r.Start(ctx, ResnapshotRequest{Stream: "users", Filter: filter})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), status.Total)
+	assert.Equal(t, database+".users", status.Namespace)
+
+	// the matching documents are sent in _id order
+	for _, id := range []int32{1, 3} {
+		select {
+		case event := <-events:
+			assert.Equal(t, model.OperationTypeSnapshot, event.OperationType)
+			assert.Equal(t, id, event.DocumentKey.Lookup("_id").Int32())
+		case <-ctx.Done():
+			t.Fatal("document is not sent")
+		}
+	}
+	assert.Eventually(t, func() bool {
+		status, _ := r.Status(status.ID)
+		return status.State == ResnapshotSucceeded && status.Sent == 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// the resume token of the change stream is not affected
+	token, err := buf.Get()
+	require.NoError(t, err)
+	assert.Empty(t, token)
+}
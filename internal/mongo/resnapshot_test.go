@@ -0,0 +1,158 @@
+package mongo
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChangeStream_watches(t *testing.T) {
+	t.Parallel()
+
+	filter, err := NewNamespaceFilter(nil, []string{"db.logs_*"})
+	require.NoError(t, err)
+
+	patterns := []struct {
+		name       string
+		target     Namespace
+		namespaces *NamespaceFilter
+		ns         Namespace
+		want       bool
+	}{
+		{
+			name:   "collection",
+			target: Namespace{Database: "db", Collection: "col"},
+			ns:     Namespace{Database: "db", Collection: "col"},
+			want:   true,
+		},
+		{
+			name:   "other collection",
+			target: Namespace{Database: "db", Collection: "col"},
+			ns:     Namespace{Database: "db", Collection: "users"},
+			want:   false,
+		},
+		{
+			name:   "collection of database",
+			target: Namespace{Database: "db"},
+			ns:     Namespace{Database: "db", Collection: "users"},
+			want:   true,
+		},
+		{
+			name:   "other database",
+			target: Namespace{Database: "db"},
+			ns:     Namespace{Database: "app", Collection: "users"},
+			want:   false,
+		},
+		{
+			name:       "excluded collection of deployment",
+			namespaces: filter,
+			ns:         Namespace{Database: "db", Collection: "logs_1"},
+			want:       false,
+		},
+		{
+			name:       "collection of deployment",
+			namespaces: filter,
+			ns:         Namespace{Database: "db", Collection: "users"},
+			want:       true,
+		},
+		{
+			name: "no collection",
+			ns:   Namespace{Database: "db"},
+			want: false,
+		},
+	}
+
+	for _, tt := range patterns {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			c := &ChangeStream{target: tt.target, namespaces: tt.namespaces}
+			assert.Equal(t, tt.want, c.watches(tt.ns))
+		})
+	}
+}
+
+func TestResnapshotter_Start(t *testing.T) {
+	t.Parallel()
+
+	patterns := []struct {
+		name string
+		req  ResnapshotRequest
+		err  error
+	}{
+		{
+			name: "unknown stream",
+			req:  ResnapshotRequest{Stream: "unknown"},
+			err:  ErrStreamNotFound,
+		},
+		{
+			name: "collection is not watched",
+			req:  ResnapshotRequest{Stream: "stream", Collection: "users"},
+			err:  ErrNamespaceNotWatched,
+		},
+	}
+
+	for _, tt := range patterns {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := NewResnapshotter()
+			defer r.Close(context.Background())
+			r.Register(&ChangeStream{name: "stream", target: Namespace{Database: "db", Collection: "col"}})
+
+			_, err := r.Start(context.Background(), tt.req)
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+}
+
+func TestResnapshotter_Status(t *testing.T) {
+	t.Parallel()
+
+	r := NewResnapshotter()
+	defer r.Close(context.Background())
+
+	_, ok := r.Status("1")
+	assert.False(t, ok)
+
+	job := &resnapshotJob{status: ResnapshotStatus{ID: "1", State: ResnapshotRunning}}
+	job.sent.Add(2)
+	r.jobs["1"] = job
+	got, ok := r.Status("1")
+	assert.True(t, ok)
+	assert.Equal(t, ResnapshotRunning, got.State)
+	assert.Equal(t, int64(2), got.Sent)
+	assert.Nil(t, got.FinishedAt)
+
+	job.finish(assert.AnError)
+	got, _ = r.Status("1")
+	assert.Equal(t, ResnapshotFailed, got.State)
+	assert.Equal(t, assert.AnError.Error(), got.Error)
+	assert.NotNil(t, got.FinishedAt)
+}
+
+func TestResnapshotter_retire(t *testing.T) {
+	t.Parallel()
+
+	r := NewResnapshotter()
+	defer r.Close(context.Background())
+
+	for i := 1; i <= maxFinishedResnapshots+1; i++ {
+		id := strconv.Itoa(i)
+		r.jobs[id] = &resnapshotJob{status: ResnapshotStatus{ID: id}}
+		r.running["stream"] = id
+		r.retire("stream", id)
+	}
+	assert.Empty(t, r.running)
+	// the oldest finished job is forgotten
+	assert.Len(t, r.jobs, maxFinishedResnapshots)
+	_, ok := r.Status("1")
+	assert.False(t, ok)
+	_, ok = r.Status(strconv.Itoa(maxFinishedResnapshots + 1))
+	assert.True(t, ok)
+}
@@ -0,0 +1,325 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	mmetric "github.com/ucpr/mongo-streamer/internal/metric/mongo"
+	"github.com/ucpr/mongo-streamer/internal/model"
+	"github.com/ucpr/mongo-streamer/internal/persistent"
+	"github.com/ucpr/mongo-streamer/pkg/log"
+)
+
+// snapshotBatchSize is the batch size of the cursors that read the collections.
+const snapshotBatchSize = 1000
+
+// errOperationTimeUnavailable is returned when the deployment does not report the
+// cluster time, e.g. a standalone server, which has no change streams either.
+var errOperationTimeUnavailable = errors.New("operation time is not available")
+
+// SnapshotMode is when the documents of the watched collections are snapshotted.
+type SnapshotMode string
+
+const (
+	// SnapshotNever streams the changes only.
+	SnapshotNever SnapshotMode = "never"
+	// SnapshotInitial publishes the documents of the watched collections as snapshot
+	// events when no resume token is saved, then opens the change stream at the time
+	// of the snapshot.
+	SnapshotInitial SnapshotMode = "initial"
+)
+
+// ParseSnapshotMode parses the snapshot mode. An empty string is never.
+func ParseSnapshotMode(s string) (SnapshotMode, error) {
+	switch m := SnapshotMode(s); m {
+	case "":
+		return SnapshotNever, nil
+	case SnapshotNever, SnapshotInitial:
+		return m, nil
+	default:
+		return "", fmt.Errorf("invalid snapshot mode: %s", s)
+	}
+}
+
+// snapshotProgress is the checkpoint of a snapshot, saved to the snapshot storage
+// in canonical Extended JSON. The collections are snapshotted in the order of their
+// namespaces, so the collections before Database and Collection are done.
+type snapshotProgress struct {
+	// ClusterTime is the cluster time before the collections are read.
+	ClusterTime primitive.Timestamp `bson:"clusterTime"`
+	Database    string              `bson:"db,omitempty"`
+	Collection  string              `bson:"coll,omitempty"`
+	// After is the _id of the last published document of the collection.
+	After *bson.RawValue `bson:"after,omitempty"`
+	// Done is set when all the collections are snapshotted.
+	Done bool `bson:"done,omitempty"`
+}
+
+// String returns the progress in canonical Extended JSON.
+func (p snapshotProgress) String() string {
+	b, err := bson.MarshalExtJSON(p, true, false)
+	if err != nil {
+		// the fields are always marshalable
+		panic(err)
+	}
+	return string(b)
+}
+
+// before reports whether the collection is done in the progress.
+func (p snapshotProgress) before(ns Namespace) bool {
+	if ns.Database != p.Database {
+		return ns.Database < p.Database
+	}
+	return ns.Collection < p.Collection
+}
+
+// parseSnapshotProgress parses the progress saved by snapshotProgress.String.
+func parseSnapshotProgress(s string) (snapshotProgress, error) {
+	var p snapshotProgress
+	if err := bson.UnmarshalExtJSON([]byte(s), true, &p); err != nil {
+		return snapshotProgress{}, fmt.Errorf("failed to parse snapshot progress: %w", err)
+	}
+	return p, nil
+}
+
+// initialSnapshotPending reports whether the initial snapshot is pending, which is
+// when no resume token is saved. If the snapshot is already completed, snapshot
+// returns its time without reading the collections, so the change stream is opened
+// at the time until a resume token is saved.
+func (c *ChangeStream) initialSnapshotPending(start StartPosition) (bool, error) {
+	if !start.IsZero() {
+		if start.Override {
+			return false, nil
+		}
+		return false, errors.New("initial snapshot and start position are exclusive")
+	}
+	token, err := c.tokenManager.Get()
+	if err != nil {
+		return false, err
+	}
+	return token == "", nil
+}
+
+// snapshot publishes the documents of the watched collections as snapshot events.
+// It returns the cluster time before the collections are read, from which the change
+// stream is opened so that no change during the snapshot is missed.
+//
+// The progress is checkpointed every chunk of acknowledged documents, and an
+// interrupted snapshot resumes after the last checkpoint at the same cluster time.
+func (c *ChangeStream) snapshot(ctx context.Context) (primitive.Timestamp, error) {
+	s, err := c.snapshotStorage.Get()
+	if err != nil {
+		return primitive.Timestamp{}, storageError(err)
+	}
+	var progress snapshotProgress
+	if s != "" {
+		if progress, err = parseSnapshotProgress(s); err != nil {
+			return primitive.Timestamp{}, err
+		}
+		if progress.Done {
+			return progress.ClusterTime, nil
+		}
+		log.Info("Resume snapshot",
+			log.Fstring("stream", c.name),
+			log.Fstring("namespace", Namespace{Database: progress.Database, Collection: progress.Collection}.String()),
+		)
+	} else {
+		ts, err := c.client.operationTime(ctx)
+		if err != nil {
+			return primitive.Timestamp{}, &terminationError{err: fmt.Errorf("failed to read cluster time: %w", err)}
+		}
+		progress = snapshotProgress{ClusterTime: ts}
+	}
+
+	collections, err := c.client.watchedCollections(ctx, c.target, c.namespaces)
+	if err != nil {
+		return primitive.Timestamp{}, &terminationError{err: fmt.Errorf("failed to list collections: %w", err)}
+	}
+	sort.Slice(collections, func(i, j int) bool {
+		a, b := collections[i].Namespace, collections[j].Namespace
+		if a.Database != b.Database {
+			return a.Database < b.Database
+		}
+		return a.Collection < b.Collection
+	})
+
+	// the checkpoints of the snapshot events are the progress, not resume tokens
+	p := newPipeline(pipelineParams{
+		Stream:      c.name,
+		Handler:     c.handler,
+		OrderingKey: c.orderingKey,
+		Keys:        c.keys,
+		Retry:       c.retry,
+		Tracker:     persistent.NewTracker(c.snapshotStorage),
+		DeadLetter:  c.deadLetter,
+		MaxInFlight: c.maxInFlight,
+	})
+	for _, coll := range collections {
+		if progress.before(coll.Namespace) {
+			continue
+		}
+		var after *bson.RawValue
+		if coll.Namespace.Database == progress.Database && coll.Namespace.Collection == progress.Collection {
+			after = progress.After
+		}
+		if err = c.snapshotCollection(ctx, p, coll.Namespace, progress.ClusterTime, after); err != nil {
+			break
+		}
+	}
+	// wait for in-flight events so that the snapshot is published before the changes
+	if werr := p.wait(); werr != nil {
+		err = werr
+	}
+	if err == nil {
+		err = storageError(c.snapshotStorage.Set(persistent.Checkpoint{
+			Token:       snapshotProgress{ClusterTime: progress.ClusterTime, Done: true}.String(),
+			ClusterTime: progress.ClusterTime,
+		}))
+	}
+	if ferr := c.snapshotStorage.Flush(); ferr != nil && err == nil {
+		err = storageError(ferr)
+	}
+	return progress.ClusterTime, err
+}
+
+// snapshotCollection publishes the documents of the collection in _id order, after
+// the _id if it is not nil.
+func (c *ChangeStream) snapshotCollection(ctx context.Context, p *pipeline, ns Namespace, ts primitive.Timestamp, after *bson.RawValue) error {
+	log.Info("Start snapshot of collection", log.Fstring("stream", c.name), log.Fstring("namespace", ns.String()))
+
+	filter := bson.D{}
+	if after != nil {
+		// $expr compares values of different types in the BSON order, as the sort does
+		filter = bson.D{{Key: "$expr", Value: bson.D{
+			{Key: "$gt", Value: bson.A{"$_id", bson.D{{Key: "$literal", Value: *after}}}},
+		}}}
+	}
+	progress := snapshotProgress{ClusterTime: ts, Database: ns.Database, Collection: ns.Collection, After: after}
+	n, err := c.scanCollection(ctx, p, ns, ts, filter, func(id *bson.RawValue) persistent.Checkpoint {
+		if id != nil {
+			progress.After = id
+		}
+		return persistent.Checkpoint{Token: progress.String(), ClusterTime: ts}
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Info("Finished snapshot of collection",
+		log.Fstring("stream", c.name),
+		log.Fstring("namespace", ns.String()),
+		log.Fint("documents", n),
+	)
+	return nil
+}
+
+// scanCollection dispatches the documents of the collection that match the filter
+// as snapshot events in _id order, and returns the number of dispatched documents.
+// checkpoint returns the checkpoint of the document of the _id, which is nil for a
+// document that cannot be dispatched.
+func (c *ChangeStream) scanCollection(
+	ctx context.Context,
+	p *pipeline,
+	ns Namespace,
+	ts primitive.Timestamp,
+	filter interface{},
+	checkpoint func(id *bson.RawValue) persistent.Checkpoint,
+) (int, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "_id", Value: 1}}).
+		SetBatchSize(snapshotBatchSize)
+	cur, err := c.client.Database(ns.Database).Collection(ns.Collection).Find(ctx, filter, opts)
+	if err != nil {
+		return 0, &terminationError{err: err}
+	}
+	defer cur.Close(context.WithoutCancel(ctx))
+
+	n := 0
+	for cur.Next(ctx) {
+		// copy the current document as the cursor reuses the buffer
+		doc := make(bson.Raw, len(cur.Current))
+		copy(doc, cur.Current)
+
+		event, raw, err := snapshotEvent(ns, doc, ts)
+		if err != nil {
+			if err := p.reject(ctx, doc, ns, checkpoint(nil), err); err != nil {
+				return n, err
+			}
+			continue
+		}
+		id := doc.Lookup("_id")
+		if err := p.dispatch(ctx, event, raw, ns, checkpoint(&id)); err != nil {
+			return n, err
+		}
+		mmetric.SnapshotDocument(c.name, ns.Database, ns.Collection)
+		n++
+	}
+	if err := cur.Err(); err != nil {
+		return n, &terminationError{err: err}
+	}
+	return n, nil
+}
+
+// snapshotEvent returns the snapshot event of the document read at the cluster time,
+// and its raw BSON that is dead-lettered when the event fails.
+func snapshotEvent(ns Namespace, doc bson.Raw, ts primitive.Timestamp) (model.ChangeEvent, bson.Raw, error) {
+	id, err := doc.LookupErr("_id")
+	if err != nil {
+		return model.ChangeEvent{}, nil, fmt.Errorf("failed to read _id of document: %w", err)
+	}
+	key, err := bson.Marshal(bson.D{{Key: "_id", Value: id}})
+	if err != nil {
+		return model.ChangeEvent{}, nil, err
+	}
+
+	event := model.ChangeEvent{
+		OperationType: model.OperationTypeSnapshot,
+		ClusterTime:   ts,
+		Namespace:     model.Namespace{DB: ns.Database, Coll: ns.Collection},
+		DocumentKey:   key,
+		FullDocument:  doc,
+	}
+	raw, err := bson.Marshal(event)
+	if err != nil {
+		return model.ChangeEvent{}, nil, err
+	}
+	return event, raw, nil
+}
+
+// operationTime returns the current cluster time of the deployment.
+func (c *Client) operationTime(ctx context.Context) (primitive.Timestamp, error) {
+	sess, err := c.cli.StartSession()
+	if err != nil {
+		return primitive.Timestamp{}, err
+	}
+	defer sess.EndSession(ctx)
+
+	if err := mongo.WithSession(ctx, sess, func(sc mongo.SessionContext) error {
+		return c.cli.Database("admin").RunCommand(sc, bson.D{{Key: "ping", Value: 1}}).Err()
+	}); err != nil {
+		return primitive.Timestamp{}, err
+	}
+	ts := sess.OperationTime()
+	if ts == nil {
+		return primitive.Timestamp{}, errOperationTimeUnavailable
+	}
+	return *ts, nil
+}
+
+// discardBuffer is a StorageBuffer that discards checkpoints, used as the snapshot
+// storage when snapshots are not resumable.
+type discardBuffer struct{}
+
+func (discardBuffer) Watch(context.Context)           {}
+func (discardBuffer) Set(persistent.Checkpoint) error { return nil }
+func (discardBuffer) Get() (string, error)            { return "", nil }
+func (discardBuffer) Flush() error                    { return nil }
+func (discardBuffer) Clear() error                    { return nil }
+func (discardBuffer) Close(context.Context) error     { return nil }
@@ -0,0 +1,187 @@
+package mongo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/mock/gomock"
+
+	"github.com/ucpr/mongo-streamer/internal/model"
+	"github.com/ucpr/mongo-streamer/internal/persistent/mock"
+)
+
+func TestSnapshotEvent(t *testing.T) {
+	t.Parallel()
+
+	ts := primitive.Timestamp{T: 1705555648, I: 1}
+	doc, err := bson.Marshal(bson.D{{Key: "_id", Value: "id"}, {Key: "text", Value: "Hello"}})
+	require.NoError(t, err)
+	key, err := bson.Marshal(bson.D{{Key: "_id", Value: "id"}})
+	require.NoError(t, err)
+
+	event, raw, err := snapshotEvent(testNamespace, doc, ts)
+	require.NoError(t, err)
+	want := model.ChangeEvent{
+		OperationType: model.OperationTypeSnapshot,
+		ClusterTime:   ts,
+		Namespace:     model.Namespace{DB: "db", Coll: "col"},
+		DocumentKey:   key,
+		FullDocument:  doc,
+	}
+	assert.Equal(t, want, event)
+
+	// the raw event is decoded as the event
+	var decoded model.ChangeEvent
+	require.NoError(t, bson.Unmarshal(raw, &decoded))
+	assert.Equal(t, want, decoded)
+	assert.Equal(t, testNamespace, namespaceOf(raw))
+
+	// documents without _id are not snapshotted
+	noID, err := bson.Marshal(bson.D{{Key: "text", Value: "Hello"}})
+	require.NoError(t, err)
+	_, _, err = snapshotEvent(testNamespace, noID, ts)
+	assert.Error(t, err)
+}
+
+func TestParseSnapshotMode(t *testing.T) {
+	t.Parallel()
+
+	patterns := []struct {
+		name    string
+		in      string
+		want    SnapshotMode
+		wantErr bool
+	}{
+		{name: "empty", in: "", want: SnapshotNever},
+		{name: "never", in: "never", want: SnapshotNever},
+		{name: "initial", in: "initial", want: SnapshotInitial},
+		{name: "invalid", in: "always", wantErr: true},
+	}
+
+	for _, tt := range patterns {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := ParseSnapshotMode(tt.in)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSnapshotProgress(t *testing.T) {
+	t.Parallel()
+
+	ts := primitive.Timestamp{T: 1705555648, I: 1}
+	patterns := []struct {
+		name string
+		id   interface{}
+	}{
+		{name: "object id", id: primitive.NewObjectID()},
+		{name: "int64", id: int64(1)},
+		{name: "double", id: 1.0},
+		{name: "string", id: "$id"},
+	}
+
+	for _, tt := range patterns {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			typ, data, err := bson.MarshalValue(tt.id)
+			require.NoError(t, err)
+			want := snapshotProgress{
+				ClusterTime: ts,
+				Database:    "db",
+				Collection:  "col",
+				After:       &bson.RawValue{Type: typ, Value: data},
+			}
+
+			// the type of _id is kept so that the snapshot resumes at the document
+			got, err := parseSnapshotProgress(want.String())
+			require.NoError(t, err)
+			assert.Equal(t, want.ClusterTime, got.ClusterTime)
+			assert.Equal(t, want.Database, got.Database)
+			assert.Equal(t, want.Collection, got.Collection)
+			require.NotNil(t, got.After)
+			assert.True(t, want.After.Equal(*got.After))
+		})
+	}
+
+	_, err := parseSnapshotProgress("{")
+	assert.Error(t, err)
+}
+
+func TestSnapshotProgress_before(t *testing.T) {
+	t.Parallel()
+
+	p := snapshotProgress{Database: "db", Collection: "b"}
+	assert.True(t, p.before(Namespace{Database: "app", Collection: "z"}))
+	assert.True(t, p.before(Namespace{Database: "db", Collection: "a"}))
+	assert.False(t, p.before(Namespace{Database: "db", Collection: "b"}))
+	assert.False(t, p.before(Namespace{Database: "db", Collection: "c"}))
+	assert.False(t, p.before(Namespace{Database: "web", Collection: "a"}))
+
+	// nothing is done when the snapshot starts
+	assert.False(t, snapshotProgress{}.before(Namespace{Database: "app", Collection: "a"}))
+}
+
+func TestChangeStream_initialSnapshotPending(t *testing.T) {
+	t.Parallel()
+
+	patterns := []struct {
+		name    string
+		token   string
+		start   StartPosition
+		want    bool
+		wantErr bool
+	}{
+		{
+			name: "no resume token",
+			want: true,
+		},
+		{
+			name:  "resume token",
+			token: `{"_data": "82"}`,
+			want:  false,
+		},
+		{
+			name:  "start override",
+			start: StartPosition{OperationTime: &primitive.Timestamp{T: 1}, Override: true},
+			want:  false,
+		},
+		{
+			name:    "start position",
+			start:   StartPosition{OperationTime: &primitive.Timestamp{T: 1}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range patterns {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			buf := mock.NewMockStorageBuffer(ctrl)
+			buf.EXPECT().Get().Return(tt.token, nil).AnyTimes()
+
+			c := &ChangeStream{name: "stream", tokenManager: buf}
+			got, err := c.initialSnapshotPending(tt.start)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package mongo
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/ucpr/mongo-streamer/internal/persistent"
+)
+
+// StartPosition is the position to start the change stream at when no resume token is saved.
+type StartPosition struct {
+	// OperationTime starts the change stream at the cluster time.
+	OperationTime *primitive.Timestamp
+	// After starts the change stream after the resume token, which may be of an
+	// invalidate event. It is exclusive with OperationTime.
+	After bson.Raw
+	// Override starts the change stream at the position even if a resume token is
+	// saved, which is discarded.
+	Override bool
+}
+
+// IsZero reports whether no start position is set.
+func (p StartPosition) IsZero() bool {
+	return p.OperationTime == nil && len(p.After) == 0
+}
+
+// ParseStartPosition parses the start position of the change stream, see
+// ParseOperationTime and ParseStartAfter for the formats.
+func ParseStartPosition(operationTime, after string, override bool) (StartPosition, error) {
+	if operationTime != "" && after != "" {
+		return StartPosition{}, errors.New("start at operation time and start after are exclusive")
+	}
+	p := StartPosition{Override: override}
+	if operationTime != "" {
+		ts, err := ParseOperationTime(operationTime)
+		if err != nil {
+			return StartPosition{}, err
+		}
+		p.OperationTime = &ts
+	}
+	if after != "" {
+		token, err := ParseStartAfter(after)
+		if err != nil {
+			return StartPosition{}, err
+		}
+		p.After = token
+	}
+	if override && p.IsZero() {
+		return StartPosition{}, errors.New("start override is set without start position")
+	}
+	return p, nil
+}
+
+// ParseOperationTime parses a cluster time "<seconds>.<increment>" or "<seconds>",
+// or a wall-clock time in RFC 3339, which starts at the first operation of the second.
+func ParseOperationTime(s string) (primitive.Timestamp, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		if t.Unix() < 0 || t.Unix() > int64(^uint32(0)) {
+			return primitive.Timestamp{}, fmt.Errorf("operation time is out of range: %s", s)
+		}
+		return primitive.Timestamp{T: uint32(t.Unix())}, nil
+	}
+
+	sec, inc, ok := strings.Cut(s, ".")
+	t, err := strconv.ParseUint(sec, 10, 32)
+	if err != nil {
+		return primitive.Timestamp{}, fmt.Errorf("invalid operation time: %s", s)
+	}
+	ts := primitive.Timestamp{T: uint32(t)}
+	if ok {
+		i, err := strconv.ParseUint(inc, 10, 32)
+		if err != nil {
+			return primitive.Timestamp{}, fmt.Errorf("invalid operation time: %s", s)
+		}
+		ts.I = uint32(i)
+	}
+	return ts, nil
+}
+
+// ParseStartAfter parses a resume token in Extended JSON, as saved by the change
+// stream, or the hex string of its _data field.
+func ParseStartAfter(s string) (bson.Raw, error) {
+	if strings.HasPrefix(strings.TrimSpace(s), "{") {
+		return parseResumeToken(s)
+	}
+	if _, err := hex.DecodeString(s); err != nil {
+		return nil, fmt.Errorf("invalid resume token: %s", s)
+	}
+	token, err := bson.Marshal(bson.D{{Key: "_data", Value: s}})
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// discardCheckpoint clears the saved checkpoint of the storage. The checkpoint is read
+// first, since a compare-and-delete storage such as Redis only deletes the value it read.
+func discardCheckpoint(st persistent.StorageBuffer) error {
+	if _, err := st.Get(); err != nil {
+		return err
+	}
+	return st.Clear()
+}
@@ -0,0 +1,167 @@
+package mongo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/ucpr/mongo-streamer/internal/persistent"
+)
+
+func TestParseOperationTime(t *testing.T) {
+	t.Parallel()
+
+	patterns := []struct {
+		name    string
+		in      string
+		want    primitive.Timestamp
+		wantErr bool
+	}{
+		{name: "cluster time", in: "1705555648.3", want: primitive.Timestamp{T: 1705555648, I: 3}},
+		{name: "seconds", in: "1705555648", want: primitive.Timestamp{T: 1705555648}},
+		{name: "wall-clock time", in: "2024-01-18T05:27:28Z", want: primitive.Timestamp{T: 1705555648}},
+		{name: "wall-clock time with offset", in: "2024-01-18T14:27:28+09:00", want: primitive.Timestamp{T: 1705555648}},
+		{name: "empty", in: "", wantErr: true},
+		{name: "invalid increment", in: "1705555648.x", wantErr: true},
+		{name: "out of range", in: "4294967296", wantErr: true},
+		{name: "before epoch", in: "1969-12-31T23:59:59Z", wantErr: true},
+	}
+
+	for _, tt := range patterns {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := ParseOperationTime(tt.in)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseStartAfter(t *testing.T) {
+	t.Parallel()
+
+	const data = "8265A8B2C0000000012B022C0100296E5A1004"
+	want, err := bson.Marshal(bson.D{{Key: "_data", Value: data}})
+	assert.NoError(t, err)
+
+	patterns := []struct {
+		name    string
+		in      string
+		wantErr bool
+	}{
+		{name: "extended json", in: `{"_data": "` + data + `"}`},
+		{name: "data", in: data},
+		{name: "invalid json", in: `{"_data": `, wantErr: true},
+		{name: "invalid data", in: "token", wantErr: true},
+	}
+
+	for _, tt := range patterns {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := ParseStartAfter(tt.in)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, bson.Raw(want), got)
+		})
+	}
+}
+
+func TestParseStartPosition(t *testing.T) {
+	t.Parallel()
+
+	patterns := []struct {
+		name          string
+		operationTime string
+		after         string
+		override      bool
+		want          StartPosition
+		wantErr       bool
+	}{
+		{
+			name: "empty",
+			want: StartPosition{},
+		},
+		{
+			name:          "operation time",
+			operationTime: "1705555648.1",
+			override:      true,
+			want:          StartPosition{OperationTime: &primitive.Timestamp{T: 1705555648, I: 1}, Override: true},
+		},
+		{
+			name:  "after",
+			after: "82",
+			want:  StartPosition{After: bson.Raw(bsonDoc(bson.D{{Key: "_data", Value: "82"}}))},
+		},
+		{
+			name:          "both",
+			operationTime: "1705555648",
+			after:         "82",
+			wantErr:       true,
+		},
+		{
+			name:     "override without position",
+			override: true,
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range patterns {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := ParseStartPosition(tt.operationTime, tt.after, tt.override)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func bsonDoc(d bson.D) bson.Raw {
+	raw, err := bson.Marshal(d)
+	if err != nil {
+		panic(err)
+	}
+	return raw
+}
+
+func TestDiscardCheckpoint_Redis(t *testing.T) {
+	t.Parallel()
+
+	srv := miniredis.RunT(t)
+	const key = "mongo-streamer:test.tweets"
+	// the token saved by the previous run
+	require.NoError(t, srv.Set(key, "token"))
+
+	st := persistent.NewRedisWriter(redis.NewClient(&redis.Options{Addr: srv.Addr()}), key, 0)
+	buf, err := persistent.NewBuffer(1, time.Hour, st)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		buf.Close(context.Background())
+	})
+
+	require.NoError(t, discardCheckpoint(buf))
+	assert.False(t, srv.Exists(key))
+}
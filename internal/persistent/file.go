@@ -2,45 +2,118 @@ package persistent
 
 import (
 	"context"
-	"io"
+	"errors"
+	"fmt"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"sync"
 )
 
+const (
+	fileDirPerm  = 0o755
+	fileFilePerm = 0o644
+)
+
+// File is a storage that persists data to a file on the local filesystem.
+//
+// Data is written atomically: it is written to a temporary file in the same
+// directory, synced to disk and then renamed over the target file, so that
+// the file always holds either the previous or the new data.
 type File struct {
-	file *os.File
+	mu   sync.Mutex
+	path string
 }
 
 var _ Storage = (*File)(nil)
 
-func NewFileWriter(filepath string) (*File, error) {
-	// TODO: check dir exists
-	f, err := os.OpenFile(filepath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
-	if err != nil {
-		return nil, err
+// NewFileWriter creates a new file storage. The parent directories of the
+// file are created if they do not exist.
+func NewFileWriter(path string) (*File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), fileDirPerm); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
 	}
 
-	return &File{file: f}, nil
+	return &File{path: path}, nil
 }
 
+// Write replaces the data of the file with s atomically.
 func (w *File) Write(s string) error {
-	_, err := w.file.WriteString(s)
-	return err
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	dir := filepath.Dir(w.path)
+	tmp, err := os.CreateTemp(dir, filepath.Base(w.path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	// remove the temporary file if it is not renamed
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.WriteString(s); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write temporary file: %w", err)
+	}
+	if err := tmp.Chmod(fileFilePerm); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to chmod temporary file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync temporary file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temporary file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), w.path); err != nil {
+		return fmt.Errorf("failed to rename temporary file: %w", err)
+	}
+
+	return syncDir(dir)
 }
 
+// Clear removes the file.
 func (w *File) Clear() error {
-	// clear data by overwriting with an empty string
-	_, err := w.file.WriteString("")
-	return err
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := os.Remove(w.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return syncDir(filepath.Dir(w.path))
 }
 
+// Read returns the data of the file.
+// It returns an empty string if the file does not exist.
 func (w *File) Read() (string, error) {
-	b, err := io.ReadAll(w.file)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	b, err := os.ReadFile(w.path)
 	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return "", nil
+		}
 		return "", err
 	}
 	return string(b), nil
 }
 
+// Close closes the file storage.
 func (w *File) Close(ctx context.Context) error {
-	return w.file.Close()
+	return nil
+}
+
+// syncDir flushes the directory entry so that a rename or remove survives a crash.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("failed to sync directory: %w", err)
+	}
+	return nil
 }
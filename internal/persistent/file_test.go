@@ -0,0 +1,53 @@
+package persistent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFile(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "nested", "dir", "resume_token")
+	f, err := NewFileWriter(path)
+	require.NoError(t, err)
+
+	// read before write returns an empty string
+	got, err := f.Read()
+	require.NoError(t, err)
+	assert.Equal(t, "", got)
+
+	require.NoError(t, f.Write("token-1"))
+	require.NoError(t, f.Write("token-2"))
+	got, err = f.Read()
+	require.NoError(t, err)
+	assert.Equal(t, "token-2", got)
+	require.NoError(t, f.Close(ctx))
+
+	// reopen to check the token survives restarts
+	f, err = NewFileWriter(path)
+	require.NoError(t, err)
+	got, err = f.Read()
+	require.NoError(t, err)
+	assert.Equal(t, "token-2", got)
+
+	// no temporary files are left behind
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	require.NoError(t, f.Clear())
+	got, err = f.Read()
+	require.NoError(t, err)
+	assert.Equal(t, "", got)
+
+	// clear is idempotent
+	require.NoError(t, f.Clear())
+	require.NoError(t, f.Close(ctx))
+}
@@ -13,6 +13,7 @@ import (
 	context "context"
 	reflect "reflect"
 
+	persistent "github.com/ucpr/mongo-streamer/internal/persistent"
 	gomock "go.uber.org/mock/gomock"
 )
 
@@ -96,6 +97,100 @@ func (mr *MockStorageMockRecorder) Write(s any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Write", reflect.TypeOf((*MockStorage)(nil).Write), s)
 }
 
+// MockCheckpointStorage is a mock of CheckpointStorage interface.
+type MockCheckpointStorage struct {
+	ctrl     *gomock.Controller
+	recorder *MockCheckpointStorageMockRecorder
+}
+
+// MockCheckpointStorageMockRecorder is the mock recorder for MockCheckpointStorage.
+type MockCheckpointStorageMockRecorder struct {
+	mock *MockCheckpointStorage
+}
+
+// NewMockCheckpointStorage creates a new mock instance.
+func NewMockCheckpointStorage(ctrl *gomock.Controller) *MockCheckpointStorage {
+	mock := &MockCheckpointStorage{ctrl: ctrl}
+	mock.recorder = &MockCheckpointStorageMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockCheckpointStorage) EXPECT() *MockCheckpointStorageMockRecorder {
+	return m.recorder
+}
+
+// Clear mocks base method.
+func (m *MockCheckpointStorage) Clear() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Clear")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Clear indicates an expected call of Clear.
+func (mr *MockCheckpointStorageMockRecorder) Clear() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Clear", reflect.TypeOf((*MockCheckpointStorage)(nil).Clear))
+}
+
+// Close mocks base method.
+func (m *MockCheckpointStorage) Close(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Close", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Close indicates an expected call of Close.
+func (mr *MockCheckpointStorageMockRecorder) Close(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockCheckpointStorage)(nil).Close), ctx)
+}
+
+// Read mocks base method.
+func (m *MockCheckpointStorage) Read() (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Read")
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Read indicates an expected call of Read.
+func (mr *MockCheckpointStorageMockRecorder) Read() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Read", reflect.TypeOf((*MockCheckpointStorage)(nil).Read))
+}
+
+// Write mocks base method.
+func (m *MockCheckpointStorage) Write(s string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Write", s)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Write indicates an expected call of Write.
+func (mr *MockCheckpointStorageMockRecorder) Write(s any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Write", reflect.TypeOf((*MockCheckpointStorage)(nil).Write), s)
+}
+
+// WriteCheckpoint mocks base method.
+func (m *MockCheckpointStorage) WriteCheckpoint(cp persistent.Checkpoint) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WriteCheckpoint", cp)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WriteCheckpoint indicates an expected call of WriteCheckpoint.
+func (mr *MockCheckpointStorageMockRecorder) WriteCheckpoint(cp any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteCheckpoint", reflect.TypeOf((*MockCheckpointStorage)(nil).WriteCheckpoint), cp)
+}
+
 // MockStorageBuffer is a mock of StorageBuffer interface.
 type MockStorageBuffer struct {
 	ctrl     *gomock.Controller
@@ -177,17 +272,17 @@ func (mr *MockStorageBufferMockRecorder) Get() *gomock.Call {
 }
 
 // Set mocks base method.
-func (m *MockStorageBuffer) Set(s string) error {
+func (m *MockStorageBuffer) Set(cp persistent.Checkpoint) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Set", s)
+	ret := m.ctrl.Call(m, "Set", cp)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Set indicates an expected call of Set.
-func (mr *MockStorageBufferMockRecorder) Set(s any) *gomock.Call {
+func (mr *MockStorageBufferMockRecorder) Set(cp any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Set", reflect.TypeOf((*MockStorageBuffer)(nil).Set), s)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Set", reflect.TypeOf((*MockStorageBuffer)(nil).Set), cp)
 }
 
 // Watch mocks base method.
@@ -0,0 +1,122 @@
+package persistent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// mongoOperationTimeout is the timeout of a single operation against the checkpoint collection.
+	mongoOperationTimeout = 10 * time.Second
+)
+
+// Mongo is a storage that persists checkpoints to a MongoDB collection.
+//
+// Each stream is stored as a single document keyed by the stream id, so that
+// a replacement process with the same stream id resumes from the last checkpoint.
+// Writes are upserts that use the write concern of the collection, which is
+// inherited from the client and the cluster default.
+type Mongo struct {
+	col      *mongo.Collection
+	streamID string
+	host     string
+}
+
+var _ CheckpointStorage = (*Mongo)(nil)
+
+// mongoCheckpoint is the document stored in the checkpoint collection.
+type mongoCheckpoint struct {
+	StreamID    string              `bson:"_id"`
+	Token       string              `bson:"token"`
+	ClusterTime primitive.Timestamp `bson:"cluster_time"`
+	UpdatedAt   time.Time           `bson:"updated_at"`
+	Host        string              `bson:"host"`
+}
+
+// NewMongoWriter creates a new MongoDB storage that stores the checkpoint of
+// the stream identified by streamID to col.
+func NewMongoWriter(col *mongo.Collection, streamID string) (*Mongo, error) {
+	if streamID == "" {
+		return nil, errors.New("stream id must not be empty")
+	}
+	host, err := os.Hostname()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get hostname: %w", err)
+	}
+
+	return &Mongo{
+		col:      col,
+		streamID: streamID,
+		host:     host,
+	}, nil
+}
+
+// Write saves the resume token without checkpoint metadata.
+func (m *Mongo) Write(s string) error {
+	return m.WriteCheckpoint(Checkpoint{Token: s})
+}
+
+// WriteCheckpoint saves the checkpoint with its metadata.
+func (m *Mongo) WriteCheckpoint(cp Checkpoint) error {
+	ctx, cancel := context.WithTimeout(context.Background(), mongoOperationTimeout)
+	defer cancel()
+
+	set := bson.D{
+		{Key: "token", Value: cp.Token},
+		{Key: "updated_at", Value: time.Now().UTC()},
+		{Key: "host", Value: m.host},
+	}
+	if !cp.ClusterTime.IsZero() {
+		set = append(set, bson.E{Key: "cluster_time", Value: cp.ClusterTime})
+	}
+	_, err := m.col.UpdateOne(ctx,
+		bson.D{{Key: "_id", Value: m.streamID}},
+		bson.D{{Key: "$set", Value: set}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to upsert checkpoint: %w", err)
+	}
+	return nil
+}
+
+// Clear removes the checkpoint of the stream.
+func (m *Mongo) Clear() error {
+	ctx, cancel := context.WithTimeout(context.Background(), mongoOperationTimeout)
+	defer cancel()
+
+	if _, err := m.col.DeleteOne(ctx, bson.D{{Key: "_id", Value: m.streamID}}); err != nil {
+		return fmt.Errorf("failed to delete checkpoint: %w", err)
+	}
+	return nil
+}
+
+// Read returns the resume token of the stream.
+// It returns an empty string if no checkpoint is stored.
+func (m *Mongo) Read() (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), mongoOperationTimeout)
+	defer cancel()
+
+	var cp mongoCheckpoint
+	err := m.col.FindOne(ctx, bson.D{{Key: "_id", Value: m.streamID}}).Decode(&cp)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to find checkpoint: %w", err)
+	}
+	return cp.Token, nil
+}
+
+// Close does nothing, the client is owned by the caller.
+func (m *Mongo) Close(ctx context.Context) error {
+	return nil
+}
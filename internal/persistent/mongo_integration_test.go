@@ -0,0 +1,71 @@
+//go:build integration
+
+package persistent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	testMongoURI        = "mongodb://localhost:27017/?directConnection=true"
+	testMongoDatabase   = "test"
+	testMongoCollection = "mongo_streamer_checkpoints"
+)
+
+//nolint:paralleltest
+func TestMongo(t *testing.T) {
+	ctx := context.Background()
+
+	cli, err := mongo.Connect(ctx, options.Client().ApplyURI(testMongoURI))
+	require.NoError(t, err)
+	defer cli.Disconnect(ctx)
+	col := cli.Database(testMongoDatabase).Collection(testMongoCollection)
+	require.NoError(t, col.Drop(ctx))
+
+	st, err := NewMongoWriter(col, "test.tweets")
+	require.NoError(t, err)
+
+	// read before write returns an empty string
+	got, err := st.Read()
+	require.NoError(t, err)
+	assert.Equal(t, "", got)
+
+	cp := Checkpoint{
+		Token:       `{"_data": "token"}`,
+		ClusterTime: primitive.Timestamp{T: 1700000000, I: 1},
+	}
+	require.NoError(t, st.WriteCheckpoint(cp))
+
+	// a replacement process with the same stream id picks up the token
+	st2, err := NewMongoWriter(col, "test.tweets")
+	require.NoError(t, err)
+	got, err = st2.Read()
+	require.NoError(t, err)
+	assert.Equal(t, cp.Token, got)
+
+	var doc mongoCheckpoint
+	require.NoError(t, col.FindOne(ctx, bson.D{{Key: "_id", Value: "test.tweets"}}).Decode(&doc))
+	assert.Equal(t, cp.ClusterTime, doc.ClusterTime)
+	assert.Equal(t, st.host, doc.Host)
+	assert.False(t, doc.UpdatedAt.IsZero())
+
+	// other streams are not affected
+	other, err := NewMongoWriter(col, "test.users")
+	require.NoError(t, err)
+	got, err = other.Read()
+	require.NoError(t, err)
+	assert.Equal(t, "", got)
+
+	require.NoError(t, st.Clear())
+	got, err = st.Read()
+	require.NoError(t, err)
+	assert.Equal(t, "", got)
+}
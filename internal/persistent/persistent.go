@@ -4,9 +4,12 @@ package persistent
 
 import (
 	"context"
+	"errors"
 	"sync"
 	"time"
 
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
 	"github.com/ucpr/mongo-streamer/pkg/log"
 )
 
@@ -22,9 +25,23 @@ type Storage interface {
 	Close(ctx context.Context) error
 }
 
+// CheckpointStorage is a Storage that also persists the metadata of a checkpoint.
+type CheckpointStorage interface {
+	Storage
+	WriteCheckpoint(cp Checkpoint) error
+}
+
+// Checkpoint is a resume token with the metadata of the event it was taken from.
+type Checkpoint struct {
+	// Token is the resume token.
+	Token string
+	// ClusterTime is the cluster time of the event.
+	ClusterTime primitive.Timestamp
+}
+
 type StorageBuffer interface {
 	Watch(ctx context.Context)
-	Set(s string) error
+	Set(cp Checkpoint) error
 	Get() (string, error)
 	Flush() error
 	Clear() error
@@ -34,20 +51,23 @@ type StorageBuffer interface {
 type Buffer struct {
 	sync.Mutex
 
-	// data is the slice of strings that the buffer holds
-	data []string
+	// data is the slice of checkpoints that the buffer holds
+	data []Checkpoint
 	// cap is the maximum number of elements in the buffer
 	cap int
 	// interval is the interval at which the buffer is flushed
 	interval time.Duration
 	// storage is the storage that writes the buffer to the persistent storage
 	storage Storage
+	// conflict is set when another instance modified the saved checkpoint, the buffer
+	// does not write anymore once it is set
+	conflict error
 }
 
 // NewBuffer creates a new buffer with the given capacity
 func NewBuffer(cap int, interval time.Duration, writer Storage) (*Buffer, error) {
 	buf := &Buffer{
-		data:     make([]string, 0, cap),
+		data:     make([]Checkpoint, 0, cap),
 		cap:      cap,
 		interval: interval,
 		storage:  writer,
@@ -65,7 +85,10 @@ func (b *Buffer) Watch(ctx context.Context) {
 		select {
 		case <-ticker.C:
 			if err := b.Flush(); err != nil {
-				log.Info("Failed to flush buffer", err)
+				log.Error("Failed to flush buffer", log.Ferror(err))
+				if errors.Is(err, ErrConflict) {
+					return
+				}
 			}
 		case <-ctx.Done():
 			log.Info("Buffer watcher stopped")
@@ -74,16 +97,20 @@ func (b *Buffer) Watch(ctx context.Context) {
 	}
 }
 
-// Set adds a string to the buffer
-func (b *Buffer) Set(s string) error {
+// Set adds a checkpoint to the buffer. It returns ErrConflict once another
+// instance modified the saved checkpoint.
+func (b *Buffer) Set(cp Checkpoint) error {
 	b.Lock()
 	defer b.Unlock()
 
-	b.data = append(b.data, s)
+	if b.conflict != nil {
+		return b.conflict
+	}
+	b.data = append(b.data, cp)
 
 	// flush when capacity is reached
 	if len(b.data) >= b.cap {
-		b.Flush()
+		return b.flush()
 	}
 	return nil
 }
@@ -107,6 +134,15 @@ func (b *Buffer) Flush() error {
 	b.Lock()
 	defer b.Unlock()
 
+	return b.flush()
+}
+
+// flush writes the buffer to the persistent storage.
+// The caller must hold the lock.
+func (b *Buffer) flush() error {
+	if b.conflict != nil {
+		return b.conflict
+	}
 	// do nothing if buffer is empty
 	if len(b.data) < 1 {
 		return nil
@@ -115,13 +151,25 @@ func (b *Buffer) Flush() error {
 	// get the last element
 	data := b.data[len(b.data)-1]
 
-	// save bufferd data to file
-	if err := b.storage.Write(data); err != nil {
+	// save bufferd data to storage, with its metadata if the storage supports it
+	var err error
+	if cs, ok := b.storage.(CheckpointStorage); ok {
+		err = cs.WriteCheckpoint(data)
+	} else {
+		err = b.storage.Write(data.Token)
+	}
+	if err != nil {
+		// only the last checkpoint is written, so the buffer does not grow while it fails
+		b.data = append(b.data[:0], data)
+		if errors.Is(err, ErrConflict) {
+			b.conflict = err
+			log.Error("Checkpoint was modified by another instance, stop writing", log.Ferror(err))
+		}
 		return err
 	}
 
 	// initialize
-	b.data = make([]string, 0, b.cap)
+	b.data = make([]Checkpoint, 0, b.cap)
 
 	return nil
 }
@@ -135,7 +183,7 @@ func (b *Buffer) Clear() error {
 		return err
 	}
 
-	b.data = make([]string, 0, b.cap)
+	b.data = make([]Checkpoint, 0, b.cap)
 
 	return nil
 }
@@ -145,7 +193,7 @@ func (b *Buffer) Clear() error {
 func (b *Buffer) Close(ctx context.Context) error {
 	defer func() {
 		if err := b.storage.Close(ctx); err != nil {
-			log.Error("Failed to close file", err)
+			log.Error("Failed to close storage", log.Ferror(err))
 		}
 	}()
 	return b.Flush()
@@ -0,0 +1,108 @@
+package persistent_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/mock/gomock"
+
+	"github.com/ucpr/mongo-streamer/internal/persistent"
+	"github.com/ucpr/mongo-streamer/internal/persistent/mock"
+)
+
+func TestBuffer_Set(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	ms := mock.NewMockStorage(ctrl)
+	// only the last token is written when the capacity is reached
+	ms.EXPECT().Write("token-3").Return(nil).Times(1)
+
+	buf, err := persistent.NewBuffer(3, time.Hour, ms)
+	require.NoError(t, err)
+
+	for _, token := range []string{"token-1", "token-2", "token-3"} {
+		require.NoError(t, buf.Set(persistent.Checkpoint{Token: token}))
+	}
+	// buffer is empty after flush
+	assert.NoError(t, buf.Flush())
+}
+
+func TestBuffer_Close(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	ms := mock.NewMockStorage(ctrl)
+	gomock.InOrder(
+		ms.EXPECT().Write("token-2").Return(nil).Times(1),
+		ms.EXPECT().Close(ctx).Return(nil).Times(1),
+	)
+
+	buf, err := persistent.NewBuffer(10, time.Hour, ms)
+	require.NoError(t, err)
+
+	require.NoError(t, buf.Set(persistent.Checkpoint{Token: "token-1"}))
+	require.NoError(t, buf.Set(persistent.Checkpoint{Token: "token-2"}))
+	assert.NoError(t, buf.Close(ctx))
+}
+
+func TestBuffer_Flush_CheckpointStorage(t *testing.T) {
+	t.Parallel()
+
+	cp := persistent.Checkpoint{
+		Token:       "token",
+		ClusterTime: primitive.Timestamp{T: 1700000000, I: 1},
+	}
+	ctrl := gomock.NewController(t)
+	ms := mock.NewMockCheckpointStorage(ctrl)
+	// the checkpoint is written with its metadata
+	ms.EXPECT().WriteCheckpoint(cp).Return(nil).Times(1)
+
+	buf, err := persistent.NewBuffer(10, time.Hour, ms)
+	require.NoError(t, err)
+
+	require.NoError(t, buf.Set(cp))
+	assert.NoError(t, buf.Flush())
+}
+
+func TestBuffer_Flush_Error(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	ms := mock.NewMockStorage(ctrl)
+	// only the last token is kept after a failed flush, and written with the next token
+	gomock.InOrder(
+		ms.EXPECT().Write("token-2").Return(assert.AnError).Times(1),
+		ms.EXPECT().Write("token-3").Return(nil).Times(1),
+	)
+
+	buf, err := persistent.NewBuffer(2, time.Hour, ms)
+	require.NoError(t, err)
+
+	require.NoError(t, buf.Set(persistent.Checkpoint{Token: "token-1"}))
+	assert.ErrorIs(t, buf.Set(persistent.Checkpoint{Token: "token-2"}), assert.AnError)
+	require.NoError(t, buf.Set(persistent.Checkpoint{Token: "token-3"}))
+	// buffer is empty after flush
+	assert.NoError(t, buf.Flush())
+}
+
+func TestBuffer_Flush_Conflict(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	ms := mock.NewMockStorage(ctrl)
+	// the buffer stops writing after another instance modified the token
+	ms.EXPECT().Write("token-1").Return(persistent.ErrConflict).Times(1)
+
+	buf, err := persistent.NewBuffer(1, time.Hour, ms)
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, buf.Set(persistent.Checkpoint{Token: "token-1"}), persistent.ErrConflict)
+	assert.ErrorIs(t, buf.Set(persistent.Checkpoint{Token: "token-2"}), persistent.ErrConflict)
+	assert.ErrorIs(t, buf.Flush(), persistent.ErrConflict)
+}
@@ -0,0 +1,142 @@
+package persistent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// redisOperationTimeout is the timeout of a single operation against Redis.
+	redisOperationTimeout = 5 * time.Second
+)
+
+// ErrConflict is returned when the stored resume token was modified by another instance.
+var ErrConflict = errors.New("persistent: resume token was modified by another instance")
+
+// redisCompareAndSet sets KEYS[1] to ARGV[2] only if its current value is ARGV[1].
+// A missing key is accepted so that an expired token does not block the writer.
+// ARGV[3] is the TTL in milliseconds, 0 means no expiration.
+//
+//nolint:gochecknoglobals
+var redisCompareAndSet = redis.NewScript(`
+local cur = redis.call('GET', KEYS[1])
+if cur and cur ~= ARGV[1] then
+	return 0
+end
+if tonumber(ARGV[3]) > 0 then
+	redis.call('SET', KEYS[1], ARGV[2], 'PX', ARGV[3])
+else
+	redis.call('SET', KEYS[1], ARGV[2])
+end
+return 1
+`)
+
+// redisCompareAndDelete deletes KEYS[1] only if its current value is ARGV[1].
+//
+//nolint:gochecknoglobals
+var redisCompareAndDelete = redis.NewScript(`
+local cur = redis.call('GET', KEYS[1])
+if cur and cur ~= ARGV[1] then
+	return 0
+end
+redis.call('DEL', KEYS[1])
+return 1
+`)
+
+// Redis is a storage that persists resume tokens to Redis.
+//
+// Writes use compare-and-set semantics: a token is only written if the stored
+// token is still the one this instance last read or wrote, so that two instances
+// streaming the same namespace cannot clobber each other's tokens.
+type Redis struct {
+	mu  sync.Mutex
+	cli redis.UniversalClient
+	key string
+	ttl time.Duration
+	// last is the token last read or written by this instance
+	last string
+}
+
+var _ Storage = (*Redis)(nil)
+
+// NewRedisWriter creates a new Redis storage that stores the resume token to key.
+// If ttl is greater than zero, the token expires when it is not updated within ttl.
+// The storage takes ownership of cli and closes it on Close.
+func NewRedisWriter(cli redis.UniversalClient, key string, ttl time.Duration) *Redis {
+	return &Redis{
+		cli: cli,
+		key: key,
+		ttl: ttl,
+	}
+}
+
+// Write saves the resume token if it was not modified by another instance.
+// It returns ErrConflict otherwise.
+func (r *Redis) Write(s string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisOperationTimeout)
+	defer cancel()
+
+	ok, err := redisCompareAndSet.Run(ctx, r.cli, []string{r.key}, r.last, s, r.ttl.Milliseconds()).Bool()
+	if err != nil {
+		return fmt.Errorf("failed to set resume token: %w", err)
+	}
+	if !ok {
+		return ErrConflict
+	}
+	r.last = s
+	return nil
+}
+
+// Clear removes the resume token if it was not modified by another instance.
+// It returns ErrConflict otherwise.
+func (r *Redis) Clear() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisOperationTimeout)
+	defer cancel()
+
+	ok, err := redisCompareAndDelete.Run(ctx, r.cli, []string{r.key}, r.last).Bool()
+	if err != nil {
+		return fmt.Errorf("failed to delete resume token: %w", err)
+	}
+	if !ok {
+		return ErrConflict
+	}
+	r.last = ""
+	return nil
+}
+
+// Read returns the resume token.
+// It returns an empty string if no token is stored.
+func (r *Redis) Read() (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisOperationTimeout)
+	defer cancel()
+
+	s, err := r.cli.Get(ctx, r.key).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			r.last = ""
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to get resume token: %w", err)
+	}
+	r.last = s
+	return s, nil
+}
+
+// Close closes the Redis client.
+func (r *Redis) Close(ctx context.Context) error {
+	return r.cli.Close()
+}
@@ -0,0 +1,94 @@
+package persistent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testRedisKey = "mongo-streamer:test.tweets"
+
+func newTestRedis(t *testing.T, srv *miniredis.Miniredis, ttl time.Duration) *Redis {
+	t.Helper()
+
+	cli := redis.NewClient(&redis.Options{Addr: srv.Addr()})
+	st := NewRedisWriter(cli, testRedisKey, ttl)
+	t.Cleanup(func() {
+		st.Close(context.Background())
+	})
+	return st
+}
+
+func TestRedis(t *testing.T) {
+	t.Parallel()
+
+	srv := miniredis.RunT(t)
+	st := newTestRedis(t, srv, 0)
+
+	// read before write returns an empty string
+	got, err := st.Read()
+	require.NoError(t, err)
+	assert.Equal(t, "", got)
+
+	require.NoError(t, st.Write("token-1"))
+	require.NoError(t, st.Write("token-2"))
+	got, err = st.Read()
+	require.NoError(t, err)
+	assert.Equal(t, "token-2", got)
+	assert.Equal(t, time.Duration(0), srv.TTL(testRedisKey))
+
+	require.NoError(t, st.Clear())
+	got, err = st.Read()
+	require.NoError(t, err)
+	assert.Equal(t, "", got)
+	assert.False(t, srv.Exists(testRedisKey))
+}
+
+func TestRedis_TTL(t *testing.T) {
+	t.Parallel()
+
+	srv := miniredis.RunT(t)
+	st := newTestRedis(t, srv, time.Minute)
+
+	require.NoError(t, st.Write("token-1"))
+	assert.Equal(t, time.Minute, srv.TTL(testRedisKey))
+
+	// the token expires when it is not updated within ttl
+	srv.FastForward(2 * time.Minute)
+	got, err := st.Read()
+	require.NoError(t, err)
+	assert.Equal(t, "", got)
+
+	// writing after expiration is allowed
+	require.NoError(t, st.Write("token-2"))
+}
+
+func TestRedis_CompareAndSet(t *testing.T) {
+	t.Parallel()
+
+	srv := miniredis.RunT(t)
+	a := newTestRedis(t, srv, 0)
+	b := newTestRedis(t, srv, 0)
+
+	require.NoError(t, a.Write("token-1"))
+
+	// both instances start from the same token
+	_, err := a.Read()
+	require.NoError(t, err)
+	_, err = b.Read()
+	require.NoError(t, err)
+
+	require.NoError(t, a.Write("token-a"))
+	// b must not clobber the token written by a
+	assert.ErrorIs(t, b.Write("token-b"), ErrConflict)
+	assert.ErrorIs(t, b.Clear(), ErrConflict)
+
+	got, err := a.Read()
+	require.NoError(t, err)
+	assert.Equal(t, "token-a", got)
+}
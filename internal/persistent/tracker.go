@@ -0,0 +1,78 @@
+package persistent
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Tracker tracks checkpoints of in-flight events and commits them to the buffer in order.
+//
+// Events may be acknowledged out of order, but the committed checkpoint only
+// advances up to the highest contiguous acknowledged event, so the saved resume
+// token never skips an event that has not been acknowledged.
+type Tracker struct {
+	mu sync.Mutex
+
+	// buf is the buffer that committed checkpoints are set to
+	buf StorageBuffer
+	// base is the sequence number of the first element of entries
+	base uint64
+	// entries are the tracked checkpoints that are not committed yet
+	entries []trackerEntry
+}
+
+type trackerEntry struct {
+	cp    Checkpoint
+	acked bool
+}
+
+// NewTracker creates a new tracker that commits checkpoints to buf.
+func NewTracker(buf StorageBuffer) *Tracker {
+	return &Tracker{
+		buf: buf,
+	}
+}
+
+// Track registers the checkpoint of an in-flight event and returns its sequence number.
+// Checkpoints must be tracked in the order of the change stream.
+func (t *Tracker) Track(cp Checkpoint) uint64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	seq := t.base + uint64(len(t.entries))
+	t.entries = append(t.entries, trackerEntry{cp: cp})
+	return seq
+}
+
+// Ack marks the event of seq as acknowledged and commits the checkpoint of the
+// highest contiguous acknowledged event.
+func (t *Tracker) Ack(seq uint64) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if seq < t.base || seq >= t.base+uint64(len(t.entries)) {
+		return fmt.Errorf("persistent: unknown sequence number %d", seq)
+	}
+	t.entries[seq-t.base].acked = true
+
+	n := 0
+	for n < len(t.entries) && t.entries[n].acked {
+		n++
+	}
+	if n == 0 {
+		return nil
+	}
+
+	last := t.entries[n-1].cp
+	t.entries = t.entries[n:]
+	t.base += uint64(n)
+	return t.buf.Set(last)
+}
+
+// InFlight returns the number of tracked events that are not committed yet.
+func (t *Tracker) InFlight() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return len(t.entries)
+}
@@ -0,0 +1,48 @@
+package persistent_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/ucpr/mongo-streamer/internal/persistent"
+	"github.com/ucpr/mongo-streamer/internal/persistent/mock"
+)
+
+func TestTracker_Ack(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	mb := mock.NewMockStorageBuffer(ctrl)
+	gomock.InOrder(
+		mb.EXPECT().Set(persistent.Checkpoint{Token: "token-0"}).Return(nil).Times(1),
+		mb.EXPECT().Set(persistent.Checkpoint{Token: "token-3"}).Return(nil).Times(1),
+		mb.EXPECT().Set(persistent.Checkpoint{Token: "token-4"}).Return(nil).Times(1),
+	)
+
+	tr := persistent.NewTracker(mb)
+	seqs := make([]uint64, 5)
+	for i := range seqs {
+		seqs[i] = tr.Track(persistent.Checkpoint{Token: fmt.Sprintf("token-%d", i)})
+	}
+	assert.Equal(t, 5, tr.InFlight())
+
+	// commit the first event
+	require.NoError(t, tr.Ack(seqs[0]))
+	// events acknowledged after a gap are not committed
+	require.NoError(t, tr.Ack(seqs[2]))
+	require.NoError(t, tr.Ack(seqs[3]))
+	assert.Equal(t, 4, tr.InFlight())
+	// filling the gap commits up to the highest contiguous event
+	require.NoError(t, tr.Ack(seqs[1]))
+	assert.Equal(t, 1, tr.InFlight())
+	require.NoError(t, tr.Ack(seqs[4]))
+	assert.Equal(t, 0, tr.InFlight())
+
+	// unknown or already committed sequence numbers
+	assert.Error(t, tr.Ack(seqs[0]))
+	assert.Error(t, tr.Ack(100))
+}
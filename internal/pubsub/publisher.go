@@ -10,6 +10,7 @@ import (
 	"github.com/google/wire"
 
 	"github.com/ucpr/mongo-streamer/internal/config"
+	"github.com/ucpr/mongo-streamer/pkg/log"
 )
 
 //golint:gochecknoglobals
@@ -28,6 +29,9 @@ type Message struct {
 	Data        []byte
 	Attributes  map[string]string
 	OrderingKey string
+	// Key is the partitioning key of the message.
+	// It is used by sinks that partition messages (e.g. Kafka) and is ignored by Pub/Sub.
+	Key string
 }
 
 // PublishResult is an interface for pubsub.PublishResult.
@@ -39,6 +43,7 @@ type PublishResult interface {
 // Pulisher is an interface for PubSub Publisher.
 type Publisher interface {
 	AsyncPublish(ctx context.Context, msg Message) PublishResult
+	Close() error
 }
 
 // PubSubPublisher is a publisher for Google Cloud Pub/Sub.
@@ -64,6 +69,7 @@ func NewPublisher(ctx context.Context, cfg *config.PubSub) (*PubSubPublisher, er
 	topic.PublishSettings.ByteThreshold = publisherByteThreshold
 	topic.PublishSettings.CountThreshold = publisherCountThreshold
 	topic.PublishSettings.DelayThreshold = publisherDelayThreshold
+	topic.EnableMessageOrdering = cfg.OrderingKey != "" && cfg.OrderingKey != config.OrderingKeyNone
 
 	return &PubSubPublisher{
 		cli:   cli,
@@ -78,10 +84,39 @@ func (p *PubSubPublisher) AsyncPublish(ctx context.Context, msg Message) Publish
 		Attributes:  msg.Attributes,
 		OrderingKey: msg.OrderingKey,
 	})
-	return result
+	if msg.OrderingKey == "" {
+		return result
+	}
+	return &orderedPublishResult{
+		PublishResult: result,
+		topic:         p.topic,
+		orderingKey:   msg.OrderingKey,
+	}
+}
+
+// orderedPublishResult resumes publishing of the ordering key when the message failed.
+// Pub/Sub pauses an ordering key after a failure and rejects its messages until it is
+// resumed, so that the retried message is published before the following ones.
+type orderedPublishResult struct {
+	*pubsub.PublishResult
+	topic       *pubsub.Topic
+	orderingKey string
+}
+
+func (r *orderedPublishResult) Get(ctx context.Context) (string, error) {
+	id, err := r.PublishResult.Get(ctx)
+	if err != nil && ctx.Err() == nil {
+		r.topic.ResumePublish(r.orderingKey)
+		log.Warn("Resumed publishing of ordering key after failure",
+			log.Ferror(err),
+			log.Fstring("ordering_key", r.orderingKey),
+		)
+	}
+	return id, err
 }
 
-// Close closes the publisher.
+// Close flushes the pending messages and closes the publisher.
 func (p *PubSubPublisher) Close() error {
+	p.topic.Stop()
 	return p.cli.Close()
 }
@@ -0,0 +1,47 @@
+package pubsub
+
+import (
+	"context"
+	"testing"
+
+	"cloud.google.com/go/pubsub"
+	"cloud.google.com/go/pubsub/pstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/ucpr/mongo-streamer/internal/config"
+)
+
+//nolint:paralleltest
+func TestPublisher_AsyncPublish_ResumePublish(t *testing.T) {
+	ctx := context.Background()
+
+	// every publish fails, so the ordering key is paused after the first message
+	srv := pstest.NewServer(pstest.WithErrorInjection("Publish", codes.PermissionDenied, "denied"))
+	t.Cleanup(func() { _ = srv.Close() })
+	t.Setenv("PUBSUB_EMULATOR_HOST", srv.Addr)
+
+	cli, err := pubsub.NewClient(ctx, "project")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = cli.Close() })
+	_, err = cli.CreateTopic(ctx, "topic")
+	require.NoError(t, err)
+
+	publisher, err := NewPublisher(ctx, &config.PubSub{
+		ProjectID:   "project",
+		TopicID:     "topic",
+		OrderingKey: config.OrderingKeyDocumentKey,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = publisher.Close() })
+
+	for i := 0; i < 2; i++ {
+		_, err := publisher.AsyncPublish(ctx, Message{Data: []byte("data"), OrderingKey: "key"}).Get(ctx)
+		// the ordering key is resumed, so the message reaches the server instead of
+		// being rejected as paused
+		assert.NotErrorIs(t, err, pubsub.ErrPublishingPaused{OrderingKey: "key"})
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	}
+}
@@ -0,0 +1,147 @@
+package schemaregistry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/ucpr/mongo-streamer/internal/config"
+)
+
+// ErrIncompatibleSchema is returned when the schema is not compatible with the
+// latest version registered under the subject.
+var ErrIncompatibleSchema = errors.New("schemaregistry: incompatible schema")
+
+const (
+	contentType = "application/vnd.schemaregistry.v1+json"
+	schemaType  = "AVRO"
+
+	// errorCodeSubjectNotFound and errorCodeVersionNotFound are returned by the
+	// compatibility check of a subject that has no schema yet.
+	errorCodeSubjectNotFound = 40401
+	errorCodeVersionNotFound = 40402
+)
+
+// Error is an error response of the registry.
+type Error struct {
+	StatusCode int    `json:"-"`
+	Code       int    `json:"error_code"`
+	Message    string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("schemaregistry: status %d, error code %d: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// Client is a client of the Confluent Schema Registry REST API.
+type Client struct {
+	baseURL  string
+	username string
+	password string
+	hc       *http.Client
+}
+
+// NewClient creates a new client of the registry.
+func NewClient(cfg *config.SchemaRegistry) *Client {
+	return &Client{
+		baseURL:  strings.TrimSuffix(cfg.URL, "/"),
+		username: cfg.Username,
+		password: cfg.Password,
+		hc:       &http.Client{Timeout: cfg.Timeout},
+	}
+}
+
+type schemaRequest struct {
+	Schema     string `json:"schema"`
+	SchemaType string `json:"schemaType"`
+}
+
+// Register registers the Avro schema under the subject and returns its id.
+// The schema is checked against the latest version of the subject first, so that
+// an incompatible schema fails with ErrIncompatibleSchema instead of being registered.
+func (c *Client) Register(ctx context.Context, subject, schema string) (int, error) {
+	messages, err := c.CheckCompatibility(ctx, subject, schema)
+	if err != nil {
+		return 0, err
+	}
+	if messages != nil {
+		return 0, fmt.Errorf("%w with subject %q: %s", ErrIncompatibleSchema, subject, strings.Join(messages, "; "))
+	}
+
+	var res struct {
+		ID int `json:"id"`
+	}
+	path := "/subjects/" + url.PathEscape(subject) + "/versions"
+	if err := c.do(ctx, path, schemaRequest{Schema: schema, SchemaType: schemaType}, &res); err != nil {
+		return 0, err
+	}
+	return res.ID, nil
+}
+
+// CheckCompatibility checks the Avro schema against the latest version of the subject.
+// It returns nil if the schema is compatible or the subject has no schema, and the
+// reasons of the incompatibility otherwise.
+func (c *Client) CheckCompatibility(ctx context.Context, subject, schema string) ([]string, error) {
+	var res struct {
+		IsCompatible bool     `json:"is_compatible"`
+		Messages     []string `json:"messages"`
+	}
+	path := "/compatibility/subjects/" + url.PathEscape(subject) + "/versions/latest?verbose=true"
+	err := c.do(ctx, path, schemaRequest{Schema: schema, SchemaType: schemaType}, &res)
+	var rerr *Error
+	if errors.As(err, &rerr) && (rerr.Code == errorCodeSubjectNotFound || rerr.Code == errorCodeVersionNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if res.IsCompatible {
+		return nil, nil
+	}
+	if len(res.Messages) == 0 {
+		return []string{"no details"}, nil
+	}
+	return res.Messages, nil
+}
+
+// do posts the request to the registry and decodes the response into res.
+func (c *Client) do(ctx context.Context, path string, body, res any) error {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Accept", contentType)
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	resp, err := c.hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	rb, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		rerr := &Error{StatusCode: resp.StatusCode}
+		if err := json.Unmarshal(rb, rerr); err != nil {
+			rerr.Message = string(rb)
+		}
+		return rerr
+	}
+	return json.Unmarshal(rb, res)
+}
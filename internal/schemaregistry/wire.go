@@ -0,0 +1,17 @@
+package schemaregistry
+
+import (
+	"encoding/binary"
+)
+
+// magicByte is the first byte of a message in the Confluent wire format.
+const magicByte = 0x0
+
+// Frame prefixes the payload with the magic byte and the schema id in big-endian,
+// following the Confluent wire format.
+func Frame(id int, payload []byte) []byte {
+	b := make([]byte, 5, 5+len(payload))
+	b[0] = magicByte
+	binary.BigEndian.PutUint32(b[1:], uint32(id))
+	return append(b, payload...)
+}
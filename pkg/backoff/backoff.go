@@ -0,0 +1,84 @@
+package backoff
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Backoff computes exponentially increasing delays between attempts.
+type Backoff struct {
+	// Initial is the delay after the first attempt.
+	Initial time.Duration
+	// Max is the upper bound of the delay.
+	Max time.Duration
+	// Multiplier is the factor by which the delay grows after each attempt.
+	Multiplier float64
+	// Jitter is the randomization factor in [0, 1]. A delay d is randomized
+	// to a value in [d*(1-Jitter), d*(1+Jitter)], capped by Max.
+	Jitter float64
+}
+
+// Duration returns the delay after the given attempt, starting from 1.
+func (b Backoff) Duration(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	mul := b.Multiplier
+	if mul < 1 {
+		mul = 1
+	}
+
+	d := float64(b.Initial) * math.Pow(mul, float64(attempt-1))
+	if b.Jitter > 0 {
+		//nolint:gosec
+		d *= 1 - b.Jitter + 2*b.Jitter*rand.Float64()
+	}
+	if b.Max > 0 && d > float64(b.Max) {
+		d = float64(b.Max)
+	}
+	return time.Duration(d)
+}
+
+// Sleep waits for d or until ctx is done, whichever comes first.
+// It returns the error of ctx if ctx is done before d elapses.
+func Sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// PermanentError is an error that must not be retried.
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// Permanent wraps err so that it is not retried. It returns nil if err is nil.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &PermanentError{Err: err}
+}
+
+// IsPermanent reports whether err or any error it wraps is permanent.
+func IsPermanent(err error) bool {
+	var perr *PermanentError
+	return errors.As(err, &perr)
+}
@@ -0,0 +1,107 @@
+package backoff
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackoff_Duration(t *testing.T) {
+	t.Parallel()
+
+	b := Backoff{
+		Initial:    100 * time.Millisecond,
+		Max:        time.Second,
+		Multiplier: 2,
+	}
+	patterns := []struct {
+		name    string
+		attempt int
+		want    time.Duration
+	}{
+		{name: "first attempt", attempt: 1, want: 100 * time.Millisecond},
+		{name: "second attempt", attempt: 2, want: 200 * time.Millisecond},
+		{name: "fourth attempt", attempt: 4, want: 800 * time.Millisecond},
+		{name: "capped by max", attempt: 10, want: time.Second},
+		{name: "invalid attempt", attempt: 0, want: 100 * time.Millisecond},
+	}
+
+	for _, tt := range patterns {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.want, b.Duration(tt.attempt))
+		})
+	}
+}
+
+func TestBackoff_Duration_Jitter(t *testing.T) {
+	t.Parallel()
+
+	b := Backoff{
+		Initial:    time.Second,
+		Max:        time.Minute,
+		Multiplier: 2,
+		Jitter:     0.5,
+	}
+	for i := 0; i < 100; i++ {
+		got := b.Duration(2)
+		assert.GreaterOrEqual(t, got, time.Second)
+		assert.LessOrEqual(t, got, 3*time.Second)
+	}
+}
+
+func TestSleep(t *testing.T) {
+	t.Parallel()
+
+	assert.NoError(t, Sleep(context.Background(), time.Millisecond))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, Sleep(ctx, time.Hour), context.Canceled)
+}
+
+func TestIsPermanent(t *testing.T) {
+	t.Parallel()
+
+	patterns := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{
+			name: "nil",
+			err:  nil,
+			want: false,
+		},
+		{
+			name: "temporary error",
+			err:  assert.AnError,
+			want: false,
+		},
+		{
+			name: "permanent error",
+			err:  Permanent(assert.AnError),
+			want: true,
+		},
+		{
+			name: "wrapped permanent error",
+			err:  fmt.Errorf("wrapped: %w", Permanent(assert.AnError)),
+			want: true,
+		},
+	}
+
+	for _, tt := range patterns {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, IsPermanent(tt.err))
+		})
+	}
+	assert.NoError(t, Permanent(nil))
+	assert.ErrorIs(t, Permanent(assert.AnError), assert.AnError)
+}